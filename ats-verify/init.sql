@@ -4,6 +4,10 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 -- 1. Enums for Role-Based Access Control and Statuses
 CREATE TYPE user_role AS ENUM ('admin', 'paid_user', 'ats_staff', 'customs_staff', 'marketplace_staff');
 CREATE TYPE risk_level AS ENUM ('green', 'yellow', 'red');
+CREATE TYPE tracking_status AS ENUM (
+    'accepted', 'in_transit', 'arrived_country', 'customs_hold', 'customs_released',
+    'ready_for_pickup', 'delivered', 'returned', 'unknown'
+);
 
 -- 2. Users Table
 -- Supports all roles. 'marketplace_prefix' is used for 'marketplace_staff' to determine the source (e.g., 'wb').
@@ -59,6 +63,9 @@ CREATE TABLE parcels (
     -- FALSE = Not Used (default on insert)
     -- TRUE = Used (marked by Customs/ATS)
     is_used BOOLEAN DEFAULT FALSE,
+
+    -- Normalized status of the latest tracking event (see tracking_status enum).
+    tracking_status tracking_status NOT NULL DEFAULT 'unknown',
     
     upload_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(), -- The date from the CSV
     uploaded_by UUID REFERENCES users(id),
@@ -77,6 +84,7 @@ CREATE TABLE tracking_events (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     parcel_id UUID REFERENCES parcels(id) ON DELETE CASCADE,
     status_code VARCHAR(50),
+    normalized_status tracking_status NOT NULL DEFAULT 'unknown',
     description TEXT,
     location VARCHAR(100),
     event_time TIMESTAMP WITH TIME ZONE,
@@ -84,6 +92,11 @@ CREATE TABLE tracking_events (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Deduplicates repeated provider responses for the same parcel.
+CREATE UNIQUE INDEX idx_tracking_events_dedup ON tracking_events(parcel_id, source, status_code, event_time);
+CREATE INDEX idx_tracking_events_parcel_time ON tracking_events(parcel_id, event_time);
+CREATE INDEX idx_parcels_tracking_status ON parcels(tracking_status);
+
 -- 6. Analysis Reports
 -- distinct from tracking, this stores the result of "IMEI vs PDF" or "Risk Analysis" jobs.
 CREATE TABLE analysis_reports (
@@ -93,9 +106,17 @@ CREATE TABLE analysis_reports (
     input_file_name VARCHAR(255),
     result_summary JSONB, -- Stores JSON summary like {"found": 10, "total": 12}
     raw_data_url TEXT, -- Path to result CSV/File if stored
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    csv_file_name VARCHAR(255) DEFAULT '',
+    csv_sha256 VARCHAR(64) DEFAULT '',
+    pdf_file_names TEXT[] NOT NULL DEFAULT '{}',
+    pdf_sha256 TEXT[] NOT NULL DEFAULT '{}', -- Same order as pdf_file_names
+    results JSONB -- Full verification report
 );
 
+CREATE INDEX idx_analysis_reports_type_created ON analysis_reports(report_type, created_at DESC);
+CREATE INDEX idx_analysis_reports_user_created ON analysis_reports(user_id, created_at DESC);
+
 -- ============================================================
 -- 7. Support Tickets (Kanban Board: ATS → Customs Workflow)
 -- ============================================================
@@ -139,3 +160,98 @@ CREATE TABLE support_tickets (
 CREATE INDEX idx_support_tickets_status ON support_tickets(status);
 CREATE INDEX idx_support_tickets_iin ON support_tickets(iin);
 CREATE INDEX idx_support_tickets_assigned_to ON support_tickets(assigned_to);
+
+-- ============================================================
+-- 8. Notifications & Tracking Alerts
+-- ============================================================
+
+-- In-app notification feed (customs detention alerts, etc.).
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,                         -- 'customs_hold', 'payment_required'
+    title VARCHAR(255) NOT NULL,
+    body TEXT DEFAULT '',
+    parcel_id UUID REFERENCES parcels(id) ON DELETE SET NULL,
+    track_number VARCHAR(100) DEFAULT '',
+    ticket_id UUID REFERENCES support_tickets(id) ON DELETE SET NULL,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- One row per raised alert; the unique key deduplicates alerts per parcel.
+CREATE TABLE tracking_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcel_id UUID NOT NULL REFERENCES parcels(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    status_code VARCHAR(50) DEFAULT '',
+    ticket_id UUID REFERENCES support_tickets(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (parcel_id, kind)
+);
+
+-- ============================================================
+-- 9. Tracking Watchlists
+-- ============================================================
+
+-- Personal watchlists refreshed in the background; subscribers are notified on changes.
+CREATE TABLE watchlist_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    track_number VARCHAR(100) NOT NULL,
+    label VARCHAR(255) DEFAULT '',
+    channels TEXT[] NOT NULL DEFAULT '{in_app}',   -- 'in_app', 'email', 'webhook'
+    email VARCHAR(255) DEFAULT '',
+    webhook_url TEXT DEFAULT '',
+    last_status tracking_status NOT NULL DEFAULT 'unknown',
+    last_event_key TEXT DEFAULT '',                -- source|status_code|event_time of the latest seen event
+    last_event_at TIMESTAMP WITH TIME ZONE,
+    last_checked_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, track_number)
+);
+
+CREATE INDEX idx_watchlist_items_last_checked ON watchlist_items(last_checked_at NULLS FIRST);
+
+-- ============================================================
+-- 10. TAC Database (Type Allocation Codes)
+-- ============================================================
+
+-- Imported from a CSV dump; resolves IMEIs to manufacturer and model.
+CREATE TABLE tac_codes (
+    tac CHAR(8) PRIMARY KEY,                 -- First 8 digits of an IMEI
+    manufacturer VARCHAR(255) NOT NULL,
+    model VARCHAR(255) DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Different TACs known to belong to one dual-SIM device (Imei1/Imei2). Stored with tac_a < tac_b.
+CREATE TABLE tac_pairs (
+    tac_a CHAR(8) NOT NULL,
+    tac_b CHAR(8) NOT NULL,
+    note VARCHAR(255) DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (tac_a, tac_b),
+    CHECK (tac_a < tac_b)
+);
+
+-- ============================================================
+-- 11. IMEI Registry
+-- ============================================================
+
+-- Every device found in a verified declaration; repeat declarations are flagged.
+CREATE TABLE imei_registry (
+    imei14 CHAR(14) NOT NULL,                -- IMEI without the Luhn check digit
+    report_id UUID NOT NULL REFERENCES analysis_reports(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    declaration VARCHAR(255) DEFAULT '',     -- PDF file names of the verification
+    csv_line INT NOT NULL DEFAULT 0,
+    verified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (imei14, report_id)
+);
+
+CREATE INDEX idx_imei_registry_imei_verified ON imei_registry(imei14, verified_at DESC);
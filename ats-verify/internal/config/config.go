@@ -1,6 +1,9 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -12,11 +15,19 @@ import (
 
 // Config holds all application configuration.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Kazpost  KazpostConfig
-	CDEK     CDEKConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Kazpost   KazpostConfig
+	CDEK      CDEKConfig
+	Tracking  TrackingConfig
+	Alerts    AlertsConfig
+	Share     ShareConfig
+	Watchlist WatchlistConfig
+	SMTP      SMTPConfig
+	PDF       PDFConfig
+	Report    ReportConfig
+	Cert      CertificateConfig
 }
 
 // ServerConfig holds HTTP server settings.
@@ -51,6 +62,66 @@ type CDEKConfig struct {
 	ClientSecret string
 }
 
+// TrackingConfig holds per-provider protection settings for external tracking APIs.
+type TrackingConfig struct {
+	RatePerSecond    float64
+	Burst            int
+	MaxRetries       int
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	MaxConcurrent    int    // Per-provider in-flight request cap
+	BulkWorkers      int    // Worker pool size for bulk tracking jobs
+	BulkMaxTracks    int    // Maximum tracks per bulk tracking job
+	ConfigPath       string // Optional JSON file with config-driven carrier trackers
+}
+
+// AlertsConfig holds settings for customs detention alerts.
+type AlertsConfig struct {
+	CreateTickets bool   // Open a support ticket for every new alert
+	TicketAuthor  string // Username recorded as the author of auto-opened tickets
+}
+
+// ShareConfig holds settings for public signed tracking links.
+type ShareConfig struct {
+	Secret        string // HMAC key; defaults to a key derived from the JWT secret
+	PublicBaseURL string // Public origin used to build links
+	DefaultTTL    time.Duration
+	MaxTTL        time.Duration
+}
+
+// WatchlistConfig holds settings for track watchlists.
+type WatchlistConfig struct {
+	RefreshInterval time.Duration
+	LimitPaidUser   int
+	LimitStaff      int // ATS staff and customs officers; admins are unlimited
+	WebhookSecret   string
+}
+
+// SMTPConfig holds the SMTP relay used for email notifications (disabled if Host is empty).
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// PDFConfig holds PDF text extraction settings.
+type PDFConfig struct {
+	SidecarURL     string // PyMuPDF sidecar fallback; disabled if empty
+	SidecarTimeout time.Duration
+}
+
+// ReportConfig holds settings for exported reports.
+type ReportConfig struct {
+	FontPath string // TTF with Cyrillic glyphs for PDF reports; Cyrillic is transliterated if empty
+}
+
+// CertificateConfig holds settings for signed verification certificates.
+type CertificateConfig struct {
+	KeyPath string // PEM Ed25519 key, created if missing; an in-memory key is used if empty
+}
+
 // DSN returns the PostgreSQL connection string.
 func (d DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
@@ -76,6 +147,86 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_EXPIRATION_HOURS: %w", err)
 	}
 
+	trackingRate, err := strconv.ParseFloat(getEnv("TRACKING_RATE_PER_SECOND", "5"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_RATE_PER_SECOND: %w", err)
+	}
+
+	trackingBurst, err := strconv.Atoi(getEnv("TRACKING_BURST", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_BURST: %w", err)
+	}
+
+	trackingRetries, err := strconv.Atoi(getEnv("TRACKING_MAX_RETRIES", "2"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_MAX_RETRIES: %w", err)
+	}
+
+	breakerThreshold, err := strconv.Atoi(getEnv("TRACKING_BREAKER_THRESHOLD", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_BREAKER_THRESHOLD: %w", err)
+	}
+
+	breakerSeconds, err := strconv.Atoi(getEnv("TRACKING_BREAKER_OPEN_SECONDS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_BREAKER_OPEN_SECONDS: %w", err)
+	}
+
+	trackingConcurrent, err := strconv.Atoi(getEnv("TRACKING_MAX_CONCURRENT", "4"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_MAX_CONCURRENT: %w", err)
+	}
+
+	bulkWorkers, err := strconv.Atoi(getEnv("TRACKING_BULK_WORKERS", "8"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_BULK_WORKERS: %w", err)
+	}
+
+	bulkMaxTracks, err := strconv.Atoi(getEnv("TRACKING_BULK_MAX_TRACKS", "5000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_BULK_MAX_TRACKS: %w", err)
+	}
+
+	alertTickets, err := strconv.ParseBool(getEnv("ALERTS_CREATE_TICKETS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALERTS_CREATE_TICKETS: %w", err)
+	}
+
+	shareTTLHours, err := strconv.Atoi(getEnv("SHARE_LINK_TTL_HOURS", "72"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHARE_LINK_TTL_HOURS: %w", err)
+	}
+
+	shareMaxTTLHours, err := strconv.Atoi(getEnv("SHARE_LINK_MAX_TTL_HOURS", "720"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHARE_LINK_MAX_TTL_HOURS: %w", err)
+	}
+
+	watchRefresh, err := strconv.Atoi(getEnv("WATCHLIST_REFRESH_MINUTES", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WATCHLIST_REFRESH_MINUTES: %w", err)
+	}
+
+	watchLimitPaid, err := strconv.Atoi(getEnv("WATCHLIST_LIMIT_PAID_USER", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WATCHLIST_LIMIT_PAID_USER: %w", err)
+	}
+
+	watchLimitStaff, err := strconv.Atoi(getEnv("WATCHLIST_LIMIT_STAFF", "500"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WATCHLIST_LIMIT_STAFF: %w", err)
+	}
+
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+
+	pdfSidecarTimeout, err := strconv.Atoi(getEnv("PDF_SIDECAR_TIMEOUT_SECONDS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF_SIDECAR_TIMEOUT_SECONDS: %w", err)
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("APP_PORT", "8080"),
@@ -99,9 +250,64 @@ func Load() (*Config, error) {
 			ClientID:     getEnv("CDEK_CLIENT_ID", ""),
 			ClientSecret: getEnv("CDEK_CLIENT_SECRET", ""),
 		},
+		Tracking: TrackingConfig{
+			RatePerSecond:    trackingRate,
+			Burst:            trackingBurst,
+			MaxRetries:       trackingRetries,
+			FailureThreshold: breakerThreshold,
+			OpenTimeout:      time.Duration(breakerSeconds) * time.Second,
+			MaxConcurrent:    trackingConcurrent,
+			BulkWorkers:      bulkWorkers,
+			BulkMaxTracks:    bulkMaxTracks,
+			ConfigPath:       getEnv("TRACKERS_CONFIG", ""),
+		},
+		Alerts: AlertsConfig{
+			CreateTickets: alertTickets,
+			TicketAuthor:  getEnv("ALERTS_TICKET_AUTHOR", os.Getenv("ADMIN_EMAIL")),
+		},
+		Share: ShareConfig{
+			Secret:        getEnv("SHARE_LINK_SECRET", deriveSecret(os.Getenv("JWT_SECRET"), "share-link")),
+			PublicBaseURL: getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+			DefaultTTL:    time.Duration(shareTTLHours) * time.Hour,
+			MaxTTL:        time.Duration(shareMaxTTLHours) * time.Hour,
+		},
+		Watchlist: WatchlistConfig{
+			RefreshInterval: time.Duration(watchRefresh) * time.Minute,
+			LimitPaidUser:   watchLimitPaid,
+			LimitStaff:      watchLimitStaff,
+			WebhookSecret:   getEnv("WATCHLIST_WEBHOOK_SECRET", ""),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     smtpPort,
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "noreply@ats-verify.local"),
+		},
+		PDF: PDFConfig{
+			SidecarURL:     getEnv("PDF_SIDECAR_URL", ""),
+			SidecarTimeout: time.Duration(pdfSidecarTimeout) * time.Second,
+		},
+		Report: ReportConfig{
+			FontPath: getEnv("REPORT_FONT_PATH", ""),
+		},
+		Cert: CertificateConfig{
+			KeyPath: getEnv("CERT_KEY_PATH", "data/cert_key.pem"),
+		},
 	}, nil
 }
 
+// deriveSecret derives a purpose-specific key from a master secret, so that one
+// secret never signs two kinds of tokens. Returns "" for an empty master secret.
+func deriveSecret(master, purpose string) string {
+	if master == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(master))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ats-verify/internal/middleware"
+	"ats-verify/internal/models"
+	"ats-verify/internal/service"
+)
+
+// DeclarationHandler handles standalone declaration PDF endpoints.
+type DeclarationHandler struct {
+	declarationService *service.DeclarationService
+}
+
+// NewDeclarationHandler creates a new DeclarationHandler.
+func NewDeclarationHandler(declarationService *service.DeclarationService) *DeclarationHandler {
+	return &DeclarationHandler{declarationService: declarationService}
+}
+
+// RegisterRoutes registers declaration routes.
+func (h *DeclarationHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
+	roleMw := middleware.RequireRole(models.RoleCustoms, models.RolePaidUser, models.RoleAdmin)
+	mux.Handle("POST /api/v1/declarations/extract", authMw(roleMw(http.HandlerFunc(h.Extract))))
+}
+
+// Extract handles POST /api/v1/declarations/extract?format=json|csv (multipart: pdf_file
+// and the optional pdf_password). Returns the page texts, IMEIs and header fields of a
+// declaration; format=csv downloads the IMEI list instead.
+func (h *DeclarationHandler) Extract(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		Error(w, http.StatusBadRequest, "invalid format, expected json or csv")
+		return
+	}
+
+	// The form carries a single PDF: a body over the PDF limit is rejected while parsing.
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxPDFBytes+1<<20)
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ErrorCode(w, http.StatusRequestEntityTooLarge, service.PDFErrorTooLarge,
+				fmt.Sprintf("pdf_file is too large (max %d MB)", service.MaxPDFBytes>>20))
+			return
+		}
+		Error(w, http.StatusBadRequest, "failed to parse form: "+err.Error())
+		return
+	}
+	file, header, err := r.FormFile("pdf_file")
+	if err != nil {
+		Error(w, http.StatusBadRequest, "pdf_file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "failed to read pdf_file")
+		return
+	}
+
+	ext, err := h.declarationService.Extract(r.Context(), header.Filename, data, r.FormValue("pdf_password"))
+	if err != nil {
+		pdfError(w, header.Filename, err)
+		return
+	}
+
+	if format != "csv" {
+		JSON(w, http.StatusOK, ext)
+		return
+	}
+
+	out, err := service.RenderDeclarationIMEIsCSV(ext)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	name := strings.TrimSuffix(header.Filename, ".pdf")
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-imeis.csv"`, strings.ReplaceAll(name, `"`, "")))
+	w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
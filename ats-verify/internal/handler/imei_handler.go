@@ -1,7 +1,20 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"ats-verify/internal/middleware"
 	"ats-verify/internal/models"
@@ -11,14 +24,22 @@ import (
 // IMEIHandler handles IMEI verification endpoints.
 type IMEIHandler struct {
 	imeiService  *service.IMEIService
-	pdfExtractor *service.PDFExtractor
+	pdfExtractor *service.MultiExtractor
+	tacService   *service.TACService
+	registry     *service.IMEIRegistryService
+	exporter     *service.IMEIReportExporter
+	certificates *service.IMEICertificateService
 }
 
 // NewIMEIHandler creates a new IMEIHandler.
-func NewIMEIHandler(imeiService *service.IMEIService, pdfExtractor *service.PDFExtractor) *IMEIHandler {
+func NewIMEIHandler(imeiService *service.IMEIService, pdfExtractor *service.MultiExtractor, tacService *service.TACService, registry *service.IMEIRegistryService, exporter *service.IMEIReportExporter, certificates *service.IMEICertificateService) *IMEIHandler {
 	return &IMEIHandler{
 		imeiService:  imeiService,
 		pdfExtractor: pdfExtractor,
+		tacService:   tacService,
+		registry:     registry,
+		exporter:     exporter,
+		certificates: certificates,
 	}
 }
 
@@ -26,9 +47,26 @@ func NewIMEIHandler(imeiService *service.IMEIService, pdfExtractor *service.PDFE
 func (h *IMEIHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
 	roleMw := middleware.RequireRole(models.RoleCustoms, models.RolePaidUser, models.RoleAdmin)
 	mux.Handle("POST /api/v1/imei/analyze", authMw(roleMw(http.HandlerFunc(h.Analyze))))
+	mux.Handle("GET /api/v1/imei/reports", authMw(roleMw(http.HandlerFunc(h.ListReports))))
+	mux.Handle("GET /api/v1/imei/reports/{id}", authMw(roleMw(http.HandlerFunc(h.GetReport))))
+	mux.Handle("GET /api/v1/imei/reports/{id}/export", authMw(roleMw(http.HandlerFunc(h.ExportReport))))
+	mux.Handle("GET /api/v1/imei/reports/{id}/certificate", authMw(roleMw(http.HandlerFunc(h.GetCertificate))))
+
+	// Public validation of certificates handed to third parties (no auth, content is signed).
+	mux.HandleFunc("POST /api/v1/verify-certificate", h.VerifyCertificate)
+
+	adminMw := middleware.RequireRole(models.RoleAdmin)
+	mux.Handle("POST /api/v1/imei/tac/import", authMw(adminMw(http.HandlerFunc(h.ImportTAC))))
+	mux.Handle("POST /api/v1/imei/tac/pairs/import", authMw(adminMw(http.HandlerFunc(h.ImportTACPairs))))
 }
 
-// Analyze handles POST /api/v1/imei/analyze (multipart: csv_file + pdf_file)
+// maxIMEIPDFFiles caps the number of declaration parts accepted by one analysis.
+const maxIMEIPDFFiles = 20
+
+// Analyze handles POST /api/v1/imei/analyze (multipart: csv_file + one or more pdf_files)
+// The legacy single pdf_file field is still accepted. With mode=serial the values of the
+// CSV columns listed in columns are verified as serial numbers under the match rule.
+// The optional pdf_password opens encrypted PDFs.
 func (h *IMEIHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form (max 50MB total)
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
@@ -37,33 +75,396 @@ func (h *IMEIHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get CSV file
-	csvFile, _, err := r.FormFile("csv_file")
+	csvFile, csvHeader, err := r.FormFile("csv_file")
 	if err != nil {
 		Error(w, http.StatusBadRequest, "csv_file is required")
 		return
 	}
 	defer csvFile.Close()
 
-	// Get PDF file
-	pdfFile, _, err := r.FormFile("pdf_file")
+	// Get PDF files
+	var pdfHeaders []*multipart.FileHeader
+	pdfHeaders = append(pdfHeaders, r.MultipartForm.File["pdf_files"]...)
+	pdfHeaders = append(pdfHeaders, r.MultipartForm.File["pdf_file"]...)
+	if len(pdfHeaders) == 0 {
+		Error(w, http.StatusBadRequest, "pdf_files is required")
+		return
+	}
+	if len(pdfHeaders) > maxIMEIPDFFiles {
+		Error(w, http.StatusBadRequest, fmt.Sprintf("too many PDF files (max %d)", maxIMEIPDFFiles))
+		return
+	}
+
+	input := service.IMEIVerificationInput{CSVFileName: csvHeader.Filename}
+	docs := make([]service.PDFDocument, 0, len(pdfHeaders))
+	password := r.FormValue("pdf_password")
+	for _, fh := range pdfHeaders {
+		doc, hash, err := h.extractPDF(r.Context(), fh, password)
+		if err != nil {
+			pdfError(w, fh.Filename, err)
+			return
+		}
+		docs = append(docs, doc)
+		input.PDFFileNames = append(input.PDFFileNames, fh.Filename)
+		input.PDFSHA256 = append(input.PDFSHA256, hash)
+	}
+
+	// The CSV is hashed while it is streamed into the analysis.
+	csvHash := sha256.New()
+	csvInput := io.TeeReader(csvFile, csvHash)
+	var report *models.IMEIVerificationReport
+	switch mode := r.FormValue("mode"); mode {
+	case "", service.ModeIMEI:
+		report, err = h.imeiService.AnalyzeDocuments(csvInput, docs)
+	case service.ModeSerial:
+		opts, optsErr := serialOptions(r)
+		if optsErr != nil {
+			Error(w, http.StatusBadRequest, optsErr.Error())
+			return
+		}
+		report, err = h.imeiService.AnalyzeSerials(csvInput, docs, opts)
+	default:
+		Error(w, http.StatusBadRequest, "mode must be imei or serial")
+		return
+	}
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.CSVSHA256 = hex.EncodeToString(csvHash.Sum(nil))
+
+	// TAC lookup is best-effort: the verification result is valid without it.
+	if report.Mode == service.ModeIMEI {
+		if err := h.tacService.Annotate(r.Context(), report); err != nil {
+			log.Printf("imei: TAC annotation failed: %v", err)
+		}
+	}
+
+	// Saving the verification and the device registry is best-effort too; report_id stays empty on failure.
+	if claims := middleware.GetClaims(r); claims != nil {
+		if userID, err := uuid.Parse(claims.UserID); err == nil {
+			if err := h.registry.Record(r.Context(), userID, claims.Role, input, report); err != nil {
+				log.Printf("imei: saving verification failed: %v", err)
+			}
+		}
+	}
+
+	JSON(w, http.StatusOK, report)
+}
+
+// serialOptions reads the serial mode form fields: columns (comma-separated CSV
+// headers), match (exact, prefix or alnum) and prefix_length.
+func serialOptions(r *http.Request) (service.SerialOptions, error) {
+	rule, err := service.ParseSerialMatchRule(r.FormValue("match"))
+	if err != nil {
+		return service.SerialOptions{}, err
+	}
+	opts := service.SerialOptions{Columns: strings.Split(r.FormValue("columns"), ","), Rule: rule}
+	if rule == service.SerialMatchPrefix {
+		if opts.PrefixLength, err = strconv.Atoi(r.FormValue("prefix_length")); err != nil {
+			return service.SerialOptions{}, fmt.Errorf("prefix_length must be a number")
+		}
+	}
+	return opts, nil
+}
+
+// extractPDF extracts per-page text and goods items from an uploaded PDF with the best
+// available extraction backend. Also returns the hex SHA-256 of the file.
+func (h *IMEIHandler) extractPDF(ctx context.Context, fh *multipart.FileHeader, password string) (service.PDFDocument, string, error) {
+	if fh.Size > service.MaxPDFBytes {
+		return service.PDFDocument{}, "", fmt.Errorf("%w: %d MB (max %d MB)", service.ErrPDFTooLarge, fh.Size>>20, service.MaxPDFBytes>>20)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return service.PDFDocument{}, "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return service.PDFDocument{}, "", err
+	}
+	doc, err := h.pdfExtractor.ExtractDocument(ctx, fh.Filename, data, password)
+	return doc, sha256Hex(data), err
+}
+
+// pdfError writes a PDF extraction error with its code: encrypted, no_text_layer,
+// corrupt or too_large.
+func pdfError(w http.ResponseWriter, fileName string, err error) {
+	code := service.PDFErrorCode(err)
+	status := http.StatusBadRequest
+	if code == service.PDFErrorTooLarge {
+		status = http.StatusRequestEntityTooLarge
+	}
+	ErrorCode(w, status, code, fmt.Sprintf("failed to extract PDF text from %s: %s", fileName, err.Error()))
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ListReports handles GET /api/v1/imei/reports?user_id=&from=2024-01-01&to=2024-01-31&page=1&limit=20
+// Both dates are inclusive. Admins and customs see all verifications, other users only their own.
+func (h *IMEIHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	filter := service.ListIMEIReportsFilter{Page: page, Limit: limit}
+
+	if v := q.Get("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		filter.UserID = &id
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid 'from' date, expected YYYY-MM-DD")
+			return
+		}
+		filter.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid 'to' date, expected YYYY-MM-DD")
+			return
+		}
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		Error(w, http.StatusBadRequest, "'from' must not be after 'to'")
+		return
+	}
+
+	resp, err := h.registry.ListReports(r.Context(), viewerID, claims.Role, filter)
 	if err != nil {
-		Error(w, http.StatusBadRequest, "pdf_file is required")
+		Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer pdfFile.Close()
 
-	// Extract text from PDF using real PDF parser (ledongthuc/pdf).
-	pdfText, err := h.pdfExtractor.ExtractTextFromReader(pdfFile)
+	JSON(w, http.StatusOK, resp)
+}
+
+// GetReport handles GET /api/v1/imei/reports/{id}
+// Returns the stored verification with its full results.
+func (h *IMEIHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+
+	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		Error(w, http.StatusBadRequest, "failed to extract PDF text: "+err.Error())
+		Error(w, http.StatusBadRequest, "invalid report id")
 		return
 	}
 
-	report, err := h.imeiService.Analyze(csvFile, pdfText)
+	report, err := h.registry.GetReport(r.Context(), viewerID, claims.Role, id)
 	if err != nil {
-		Error(w, http.StatusBadRequest, err.Error())
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if report == nil {
+		Error(w, http.StatusNotFound, "report not found")
 		return
 	}
 
 	JSON(w, http.StatusOK, report)
 }
+
+// ExportReport handles GET /api/v1/imei/reports/{id}/export?format=xlsx|csv|pdf
+func (h *IMEIHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid report id")
+		return
+	}
+	format, err := service.ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.registry.GetReport(r.Context(), viewerID, claims.Role, id)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if report == nil {
+		Error(w, http.StatusNotFound, "report not found")
+		return
+	}
+
+	file, err := h.exporter.Export(r.Context(), report, format)
+	if err != nil {
+		if errors.Is(err, service.ErrNoStoredResults) {
+			Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Data)
+}
+
+// GetCertificate handles GET /api/v1/imei/reports/{id}/certificate?format=json|pdf
+// Returns the signed certificate of a stored verification.
+func (h *IMEIHandler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid report id")
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "pdf" {
+		Error(w, http.StatusBadRequest, service.ErrInvalidCertificateFormat.Error())
+		return
+	}
+
+	report, err := h.registry.GetReport(r.Context(), viewerID, claims.Role, id)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if report == nil {
+		Error(w, http.StatusNotFound, "report not found")
+		return
+	}
+
+	signed, err := h.certificates.Issue(r.Context(), report)
+	if err != nil {
+		if errors.Is(err, service.ErrNoStoredResults) {
+			Error(w, http.StatusConflict, err.Error())
+			return
+		}
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	file, err := h.certificates.Render(signed, format)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Data)
+}
+
+// maxCertificateBytes caps the body of a certificate validation request.
+const maxCertificateBytes = 1 << 20
+
+// VerifyCertificate handles POST /api/v1/verify-certificate (body: certificate JSON)
+// Checks the signature and compares the certificate with the stored verification.
+func (h *IMEIHandler) VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCertificateBytes)
+	var cert models.SignedIMEICertificate
+	if err := Decode(r, &cert); err != nil {
+		Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	check, err := h.certificates.Verify(r.Context(), &cert)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	JSON(w, http.StatusOK, check)
+}
+
+// ImportTAC handles POST /api/v1/imei/tac/import (multipart: file)
+// Loads a TAC dump (tac, manufacturer, model columns) into the local TAC table.
+func (h *IMEIHandler) ImportTAC(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		Error(w, http.StatusBadRequest, "failed to parse form: "+err.Error())
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		Error(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	imported, err := h.tacService.Import(r.Context(), file)
+	if err != nil {
+		if strings.Contains(err.Error(), "missing required column") || strings.Contains(err.Error(), "no valid data") {
+			Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"imported": imported,
+		"message":  "TAC table updated",
+	})
+}
+
+// ImportTACPairs handles POST /api/v1/imei/tac/pairs/import (multipart: file)
+// Loads dual-SIM TAC pairs (tac_a, tac_b, optional note columns) used by the pairing check.
+func (h *IMEIHandler) ImportTACPairs(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		Error(w, http.StatusBadRequest, "failed to parse form: "+err.Error())
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		Error(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	imported, err := h.tacService.ImportPairs(r.Context(), file)
+	if err != nil {
+		if strings.Contains(err.Error(), "missing required column") || strings.Contains(err.Error(), "no valid data") {
+			Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"imported": imported,
+		"message":  "TAC pairs updated",
+	})
+}
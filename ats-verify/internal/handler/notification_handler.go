@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/middleware"
+	"ats-verify/internal/service"
+)
+
+// NotificationHandler handles the in-app notification feed.
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// RegisterRoutes registers notification routes on the mux.
+func (h *NotificationHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
+	// Every authenticated user reads only their own notifications.
+	mux.Handle("GET /api/v1/notifications", authMw(http.HandlerFunc(h.List)))
+	mux.Handle("POST /api/v1/notifications/{id}/read", authMw(http.HandlerFunc(h.MarkRead)))
+}
+
+// List handles GET /api/v1/notifications?unread=true&limit=50
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	unreadOnly, _ := strconv.ParseBool(q.Get("unread"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	list, err := h.notificationService.List(r.Context(), userID, unreadOnly, limit)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, list)
+}
+
+// MarkRead handles POST /api/v1/notifications/{id}/read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid notification id")
+		return
+	}
+
+	if err := h.notificationService.MarkRead(r.Context(), id, userID); err != nil {
+		Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]string{"message": "notification marked as read"})
+}
+
+// claimsUserID extracts the authenticated user ID, writing an error response on failure.
+func claimsUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		Error(w, http.StatusUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid user id in token")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -20,6 +20,7 @@ func JSON(w http.ResponseWriter, status int, data interface{}) {
 // ErrorResponse is the standard error format.
 type ErrorResponse struct {
 	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"` // Machine-readable reason, e.g. "encrypted"
 	Message string `json:"message,omitempty"`
 }
 
@@ -28,6 +29,11 @@ func Error(w http.ResponseWriter, status int, msg string) {
 	JSON(w, status, ErrorResponse{Error: http.StatusText(status), Message: msg})
 }
 
+// ErrorCode writes a JSON error response with a machine-readable code.
+func ErrorCode(w http.ResponseWriter, status int, code, msg string) {
+	JSON(w, status, ErrorResponse{Error: http.StatusText(status), Code: code, Message: msg})
+}
+
 // Decode decodes the request body into the given struct.
 func Decode(r *http.Request, v interface{}) error {
 	defer r.Body.Close()
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"ats-verify/internal/middleware"
+	"ats-verify/internal/models"
+	"ats-verify/internal/service"
+)
+
+// StatsHandler handles analytics endpoints.
+type StatsHandler struct {
+	transitStatsService *service.TransitStatsService
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(transitStatsService *service.TransitStatsService) *StatsHandler {
+	return &StatsHandler{transitStatsService: transitStatsService}
+}
+
+// RegisterRoutes registers analytics routes.
+func (h *StatsHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
+	roleMw := middleware.RequireRole(models.RoleAdmin, models.RoleATSStaff, models.RoleCustoms)
+	mux.Handle("GET /api/v1/stats/transit", authMw(roleMw(http.HandlerFunc(h.Transit))))
+}
+
+// Transit handles GET /api/v1/stats/transit?from=2024-01-01&to=2024-01-31
+// Both dates are inclusive; the default period is the last 30 days.
+func (h *StatsHandler) Transit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid 'to' date, expected YYYY-MM-DD")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid 'from' date, expected YYYY-MM-DD")
+			return
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		Error(w, http.StatusBadRequest, "'from' must not be after 'to'")
+		return
+	}
+
+	stats, err := h.transitStatsService.Transit(r.Context(), from, to.AddDate(0, 0, 1))
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, stats)
+}
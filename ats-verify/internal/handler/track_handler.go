@@ -1,8 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"ats-verify/internal/middleware"
 	"ats-verify/internal/models"
@@ -11,24 +18,45 @@ import (
 
 // TrackHandler handles track search and tracking endpoints.
 type TrackHandler struct {
-	parcelService   *service.ParcelService
-	trackingService *service.TrackingService
+	parcelService       *service.ParcelService
+	trackingService     *service.TrackingService
+	bulkTrackingService *service.BulkTrackingService
+	shareLinkService    *service.ShareLinkService
 }
 
 // NewTrackHandler creates a new TrackHandler.
-func NewTrackHandler(parcelService *service.ParcelService, trackingService *service.TrackingService) *TrackHandler {
+func NewTrackHandler(
+	parcelService *service.ParcelService,
+	trackingService *service.TrackingService,
+	bulkTrackingService *service.BulkTrackingService,
+	shareLinkService *service.ShareLinkService,
+) *TrackHandler {
 	return &TrackHandler{
-		parcelService:   parcelService,
-		trackingService: trackingService,
+		parcelService:       parcelService,
+		trackingService:     trackingService,
+		bulkTrackingService: bulkTrackingService,
+		shareLinkService:    shareLinkService,
 	}
 }
 
 // RegisterRoutes registers track routes.
 func (h *TrackHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
-	mux.Handle("POST /api/v1/track/bulk", authMw(
-		middleware.RequireRole(models.RoleATSStaff, models.RoleAdmin, models.RoleCustoms)(http.HandlerFunc(h.BulkSearch)),
+	bulkMw := middleware.RequireRole(models.RoleATSStaff, models.RoleAdmin, models.RoleCustoms)
+
+	mux.Handle("POST /api/v1/track/bulk", authMw(bulkMw(http.HandlerFunc(h.BulkSearch))))
+	mux.Handle("POST /api/v1/tracking/bulk", authMw(bulkMw(http.HandlerFunc(h.StartBulkTracking))))
+	mux.Handle("GET /api/v1/tracking/bulk/{id}", authMw(bulkMw(http.HandlerFunc(h.GetBulkTracking))))
+	mux.Handle("GET /api/v1/tracking/bulk/{id}/csv", authMw(bulkMw(http.HandlerFunc(h.ExportBulkTracking))))
+	mux.Handle("GET /api/v1/tracking/health", authMw(
+		middleware.RequireRole(models.RoleATSStaff, models.RoleAdmin)(http.HandlerFunc(h.TrackingHealth)),
 	))
 	mux.Handle("GET /api/v1/tracking/{track}", authMw(http.HandlerFunc(h.GetTracking)))
+	mux.Handle("POST /api/v1/tracking/{track}/share", authMw(
+		middleware.RequireRole(models.RoleATSStaff, models.RoleAdmin)(http.HandlerFunc(h.CreateShareLink)),
+	))
+
+	// Public read-only view for share links (no auth, token is HMAC-signed).
+	mux.HandleFunc("GET /api/v1/public/tracking/{token}", h.GetPublicTracking)
 }
 
 // bulkSearchRequest is the payload for bulk track search.
@@ -95,16 +123,182 @@ func (h *TrackHandler) GetTracking(w http.ResponseWriter, r *http.Request) {
 
 	// Optionally check if parcel exists in our DB for extra info.
 	var parcelInfo interface{}
-	results, dbErr := h.parcelService.BulkTrackLookup(r.Context(), []string{track})
-	if dbErr == nil && len(results) > 0 && results[0].Found {
-		parcelInfo = results[0].Parcel
+	if parcel := h.recordKnownParcel(r.Context(), track, trackingResult); parcel != nil {
+		parcelInfo = parcel
 	}
 
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"track_number": track,
 		"parcel":       parcelInfo,
 		"events":       trackingResult.Events,
+		"status":       trackingResult.Status,
 		"provider":     trackingResult.Provider,
 		"external_url": trackingResult.ExternalURL,
 	})
 }
+
+// recordKnownParcel looks the track up in our DB. Events of known parcels are stored
+// so the parcel keeps its current status. Returns nil for unknown tracks.
+func (h *TrackHandler) recordKnownParcel(ctx context.Context, track string, result *service.TrackingResult) *models.Parcel {
+	results, err := h.parcelService.BulkTrackLookup(ctx, []string{track})
+	if err != nil || len(results) == 0 || !results[0].Found {
+		return nil
+	}
+
+	parcel := results[0].Parcel
+	if result != nil {
+		if _, err := h.trackingService.RecordEvents(ctx, parcel, result.Events); err != nil {
+			log.Printf("tracking: %v", err)
+		}
+	}
+	return parcel
+}
+
+// shareLinkRequest is the payload for creating a public tracking link.
+type shareLinkRequest struct {
+	TTLHours int `json:"ttl_hours"` // Optional; defaults to SHARE_LINK_TTL_HOURS
+}
+
+// CreateShareLink handles POST /api/v1/tracking/{track}/share
+// Issues a signed, expiring link to the public tracking view.
+func (h *TrackHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	var req shareLinkRequest
+	if r.ContentLength != 0 {
+		if err := Decode(r, &req); err != nil {
+			Error(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	link, err := h.shareLinkService.Create(r.PathValue("track"), time.Duration(req.TTLHours)*time.Hour)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusCreated, link)
+}
+
+// GetPublicTracking handles GET /api/v1/public/tracking/{token}
+// Serves a redacted tracking view without uploader or internal IDs.
+func (h *TrackHandler) GetPublicTracking(w http.ResponseWriter, r *http.Request) {
+	track, expiresAt, err := h.shareLinkService.Verify(r.PathValue("token"))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, service.ErrShareLinkExpired) {
+			status = http.StatusGone
+		}
+		Error(w, status, err.Error())
+		return
+	}
+
+	// Provider failures still show the registration and used/unused status.
+	trackingResult, err := h.trackingService.Track(r.Context(), track)
+	if err != nil {
+		trackingResult = nil
+	}
+	parcel := h.recordKnownParcel(r.Context(), track, trackingResult)
+	if trackingResult == nil && parcel == nil {
+		Error(w, http.StatusNotFound, "tracking data not found")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	JSON(w, http.StatusOK, service.RedactTracking(track, trackingResult, parcel, expiresAt))
+}
+
+// TrackingHealth handles GET /api/v1/tracking/health
+// Reports circuit breaker state, error rate and latency percentiles per provider.
+func (h *TrackHandler) TrackingHealth(w http.ResponseWriter, r *http.Request) {
+	providers := h.trackingService.Health()
+
+	status := "ok"
+	for _, p := range providers {
+		if p.State != service.BreakerClosed && p.State != "unmonitored" {
+			status = "degraded"
+			break
+		}
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"status":    status,
+		"providers": providers,
+	})
+}
+
+// StartBulkTracking handles POST /api/v1/tracking/bulk
+// Starts an async job that queries external providers for every track.
+func (h *TrackHandler) StartBulkTracking(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		Error(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req bulkSearchRequest
+	if err := Decode(r, &req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "invalid user ID in token")
+		return
+	}
+
+	job, err := h.bulkTrackingService.Start(userID, req.Tracks)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusAccepted, job)
+}
+
+// GetBulkTracking handles GET /api/v1/tracking/bulk/{id}
+// Returns job progress together with the results processed so far.
+func (h *TrackHandler) GetBulkTracking(w http.ResponseWriter, r *http.Request) {
+	job := h.bulkJobForRequest(w, r)
+	if job == nil {
+		return
+	}
+	JSON(w, http.StatusOK, job)
+}
+
+// ExportBulkTracking handles GET /api/v1/tracking/bulk/{id}/csv
+func (h *TrackHandler) ExportBulkTracking(w http.ResponseWriter, r *http.Request) {
+	job := h.bulkJobForRequest(w, r)
+	if job == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tracking-%s.csv"`, job.ID))
+	if err := service.WriteBulkTrackingCSV(w, job); err != nil {
+		log.Printf("bulk tracking: CSV export failed: %v", err)
+	}
+}
+
+// bulkJobForRequest loads the job from the path and checks that the caller may see it.
+// Writes the error response and returns nil on failure.
+func (h *TrackHandler) bulkJobForRequest(w http.ResponseWriter, r *http.Request) *service.BulkTrackingJob {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		Error(w, http.StatusUnauthorized, "unauthorized")
+		return nil
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid job id")
+		return nil
+	}
+
+	job := h.bulkTrackingService.Get(id)
+	if job == nil || (claims.Role != models.RoleAdmin && job.CreatedBy.String() != claims.UserID) {
+		Error(w, http.StatusNotFound, "job not found")
+		return nil
+	}
+	return job
+}
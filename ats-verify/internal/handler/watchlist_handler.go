@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/middleware"
+	"ats-verify/internal/models"
+	"ats-verify/internal/service"
+)
+
+// WatchlistHandler handles personal track watchlists.
+type WatchlistHandler struct {
+	watchlistService *service.WatchlistService
+}
+
+// NewWatchlistHandler creates a new WatchlistHandler.
+func NewWatchlistHandler(watchlistService *service.WatchlistService) *WatchlistHandler {
+	return &WatchlistHandler{watchlistService: watchlistService}
+}
+
+// RegisterRoutes registers watchlist routes.
+func (h *WatchlistHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
+	// Limits per role are enforced by the service.
+	roleMw := middleware.RequireRole(models.RolePaidUser, models.RoleATSStaff, models.RoleCustoms, models.RoleAdmin)
+
+	mux.Handle("GET /api/v1/watchlist", authMw(roleMw(http.HandlerFunc(h.List))))
+	mux.Handle("POST /api/v1/watchlist", authMw(roleMw(http.HandlerFunc(h.Add))))
+	mux.Handle("DELETE /api/v1/watchlist/{id}", authMw(roleMw(http.HandlerFunc(h.Remove))))
+}
+
+// List handles GET /api/v1/watchlist
+func (h *WatchlistHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.watchlistService.List(r.Context(), userID, middleware.GetClaims(r).Role)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, list)
+}
+
+// Add handles POST /api/v1/watchlist
+func (h *WatchlistHandler) Add(w http.ResponseWriter, r *http.Request) {
+	userID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+
+	var input service.AddWatchInput
+	if err := Decode(r, &input); err != nil {
+		Error(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	item, err := h.watchlistService.Add(r.Context(), userID, claims.Role, claims.Username, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWatchlistLimit):
+			Error(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrInvalidWatch):
+			Error(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrAlreadyWatched):
+			Error(w, http.StatusConflict, err.Error())
+		default:
+			Error(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	JSON(w, http.StatusCreated, item)
+}
+
+// Remove handles DELETE /api/v1/watchlist/{id}
+func (h *WatchlistHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	userID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "invalid watchlist item id")
+		return
+	}
+
+	if err := h.watchlistService.Remove(r.Context(), id, userID); err != nil {
+		Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]string{"message": "track removed from watchlist"})
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -49,6 +50,41 @@ const (
 	PriorityHigh   TicketPriority = "high"
 )
 
+// TrackingStatus is the carrier-independent status of a parcel.
+// Provider-specific codes (Kazpost "DetainedByCustom", CDEK "DELIVERED", ...)
+// are mapped onto this set by each Tracker.
+type TrackingStatus string
+
+const (
+	TrackingStatusAccepted        TrackingStatus = "accepted"
+	TrackingStatusInTransit       TrackingStatus = "in_transit"
+	TrackingStatusArrivedCountry  TrackingStatus = "arrived_country"
+	TrackingStatusCustomsHold     TrackingStatus = "customs_hold"
+	TrackingStatusCustomsReleased TrackingStatus = "customs_released"
+	TrackingStatusReadyForPickup  TrackingStatus = "ready_for_pickup"
+	TrackingStatusDelivered       TrackingStatus = "delivered"
+	TrackingStatusReturned        TrackingStatus = "returned"
+	TrackingStatusUnknown         TrackingStatus = "unknown"
+)
+
+// NotificationKind identifies what triggered an in-app notification.
+type NotificationKind string
+
+const (
+	NotificationCustomsHold     NotificationKind = "customs_hold"
+	NotificationPaymentRequired NotificationKind = "payment_required"
+	NotificationTrackingUpdate  NotificationKind = "tracking_update"
+)
+
+// WatchChannel is a delivery channel for watchlist notifications.
+type WatchChannel string
+
+const (
+	WatchChannelInApp   WatchChannel = "in_app"
+	WatchChannelEmail   WatchChannel = "email"
+	WatchChannelWebhook WatchChannel = "webhook"
+)
+
 // -------------------------------------------------------
 // Domain Models
 // -------------------------------------------------------
@@ -67,18 +103,19 @@ type User struct {
 
 // Parcel represents a tracked parcel in the system.
 type Parcel struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	TrackNumber string    `json:"track_number" db:"track_number"`
-	Marketplace string    `json:"marketplace" db:"marketplace"`
-	Country     string    `json:"country,omitempty" db:"country"`
-	Brand       string    `json:"brand,omitempty" db:"brand"`
-	ProductName string    `json:"product_name,omitempty" db:"product_name"`
-	SNT         string    `json:"snt,omitempty" db:"snt"`
-	IsUsed      bool      `json:"is_used" db:"is_used"`
-	UploadDate  time.Time `json:"upload_date" db:"upload_date"`
-	UploadedBy  uuid.UUID `json:"uploaded_by" db:"uploaded_by"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID      `json:"id" db:"id"`
+	TrackNumber    string         `json:"track_number" db:"track_number"`
+	Marketplace    string         `json:"marketplace" db:"marketplace"`
+	Country        string         `json:"country,omitempty" db:"country"`
+	Brand          string         `json:"brand,omitempty" db:"brand"`
+	ProductName    string         `json:"product_name,omitempty" db:"product_name"`
+	SNT            string         `json:"snt,omitempty" db:"snt"`
+	IsUsed         bool           `json:"is_used" db:"is_used"`
+	TrackingStatus TrackingStatus `json:"tracking_status" db:"tracking_status"` // Normalized status of the latest tracking event
+	UploadDate     time.Time      `json:"upload_date" db:"upload_date"`
+	UploadedBy     uuid.UUID      `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
 }
 
 // RiskRawData holds raw CSV risk analysis rows.
@@ -109,14 +146,15 @@ type IINBINRisk struct {
 
 // TrackingEvent represents a single tracking event from Kazpost/CDEK.
 type TrackingEvent struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	ParcelID    uuid.UUID `json:"parcel_id" db:"parcel_id"`
-	StatusCode  string    `json:"status_code" db:"status_code"`
-	Description string    `json:"description" db:"description"`
-	Location    string    `json:"location" db:"location"`
-	EventTime   time.Time `json:"event_time" db:"event_time"`
-	Source      string    `json:"source" db:"source"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID               uuid.UUID      `json:"id" db:"id"`
+	ParcelID         uuid.UUID      `json:"parcel_id" db:"parcel_id"`
+	StatusCode       string         `json:"status_code" db:"status_code"`
+	NormalizedStatus TrackingStatus `json:"normalized_status" db:"normalized_status"`
+	Description      string         `json:"description" db:"description"`
+	Location         string         `json:"location" db:"location"`
+	EventTime        time.Time      `json:"event_time" db:"event_time"`
+	Source           string         `json:"source" db:"source"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
 }
 
 // AnalysisReport stores results of IMEI verification or risk analysis.
@@ -128,6 +166,15 @@ type AnalysisReport struct {
 	ResultSummary map[string]interface{} `json:"result_summary" db:"result_summary"`
 	RawDataURL    string                 `json:"raw_data_url,omitempty" db:"raw_data_url"`
 	CreatedAt     time.Time              `json:"created_at" db:"created_at"`
+
+	// Input files and their SHA-256 hashes (hex), proving what was checked
+	CSVFileName  string         `json:"csv_file_name" db:"csv_file_name"`
+	CSVSHA256    string         `json:"csv_sha256" db:"csv_sha256"`
+	PDFFileNames pq.StringArray `json:"pdf_file_names" db:"pdf_file_names"`
+	PDFSHA256    pq.StringArray `json:"pdf_sha256" db:"pdf_sha256"` // Same order as PDFFileNames
+
+	// Full report JSON; only loaded for a single report
+	Results json.RawMessage `json:"results,omitempty" db:"results"`
 }
 
 // SupportTicket represents a Kanban board ticket for the ATS → Customs workflow.
@@ -155,6 +202,56 @@ type SupportTicket struct {
 	RiskComment *string    `json:"risk_comment,omitempty" db:"risk_comment"`
 }
 
+// Notification is an in-app message addressed to a single user.
+type Notification struct {
+	ID          uuid.UUID        `json:"id" db:"id"`
+	UserID      uuid.UUID        `json:"user_id" db:"user_id"`
+	Kind        NotificationKind `json:"kind" db:"kind"`
+	Title       string           `json:"title" db:"title"`
+	Body        string           `json:"body" db:"body"`
+	ParcelID    *uuid.UUID       `json:"parcel_id,omitempty" db:"parcel_id"`
+	TrackNumber string           `json:"track_number,omitempty" db:"track_number"`
+	TicketID    *uuid.UUID       `json:"ticket_id,omitempty" db:"ticket_id"`
+	ReadAt      *time.Time       `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+}
+
+// WatchlistItem is a track on a user's personal watchlist, refreshed in the background.
+type WatchlistItem struct {
+	ID            uuid.UUID      `json:"id" db:"id"`
+	UserID        uuid.UUID      `json:"user_id" db:"user_id"`
+	TrackNumber   string         `json:"track_number" db:"track_number"`
+	Label         string         `json:"label,omitempty" db:"label"`
+	Channels      pq.StringArray `json:"channels" db:"channels"` // WatchChannel values
+	Email         string         `json:"email,omitempty" db:"email"`
+	WebhookURL    string         `json:"webhook_url,omitempty" db:"webhook_url"`
+	LastStatus    TrackingStatus `json:"last_status" db:"last_status"`
+	LastEventKey  string         `json:"-" db:"last_event_key"` // Identifies the latest seen event
+	LastEventAt   *time.Time     `json:"last_event_at,omitempty" db:"last_event_at"`
+	LastCheckedAt *time.Time     `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	LastError     string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
+// TACEntry maps a Type Allocation Code (first 8 IMEI digits) to a device.
+type TACEntry struct {
+	TAC          string `json:"tac" db:"tac"`
+	Manufacturer string `json:"manufacturer" db:"manufacturer"`
+	Model        string `json:"model" db:"model"`
+}
+
+// ParcelTransit holds the tracking milestones of a parcel used for transit-time analytics.
+type ParcelTransit struct {
+	ParcelID     uuid.UUID  `json:"parcel_id"`
+	Marketplace  string     `json:"marketplace"`
+	Country      string     `json:"country"`
+	Carrier      string     `json:"carrier"`                  // Source of the first scan
+	FirstScanAt  time.Time  `json:"first_scan_at"`            // Earliest tracking event
+	CustomsInAt  *time.Time `json:"customs_in_at,omitempty"`  // First customs_hold event
+	CustomsOutAt *time.Time `json:"customs_out_at,omitempty"` // First release/pickup/delivery after customs_in
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`   // First delivered event
+}
+
 // MarketplacePrefixMap maps user role suffixes to marketplace names.
 var MarketplacePrefixMap = map[string]string{
 	"wb":    "Wildberries",
@@ -168,37 +265,313 @@ var MarketplacePrefixMap = map[string]string{
 // IMEI Verification Report (output format)
 // -------------------------------------------------------
 
+// IMEIValidity classifies the structure of a CSV IMEI value.
+type IMEIValidity string
+
+const (
+	IMEIValid15     IMEIValidity = "valid_15"     // 15 digits with a correct Luhn check digit
+	IMEIValid14     IMEIValidity = "valid_14"     // 14 digits; check digit is computed
+	IMEIBadLuhn     IMEIValidity = "bad_luhn"     // 15 digits with a wrong check digit (still matched by prefix)
+	IMEINonNumeric  IMEIValidity = "non_numeric"  // Contains non-digit characters
+	IMEIWrongLength IMEIValidity = "wrong_length" // Digits only, but not 14 or 15 of them
+
+	// SerialValid marks a serial number that can be matched under the report's rule.
+	// In serial mode IMEIWrongLength means too short for the rule.
+	SerialValid IMEIValidity = "serial"
+)
+
 // IMEIMatchResult represents the verification result for a single IMEI value.
 type IMEIMatchResult struct {
-	CSVLine     int    `json:"csv_line"`               // 1-based row number in the source CSV
-	Column      string `json:"column"`                 // Column name, e.g. "Imei1", "Imei2"
-	IMEI14      string `json:"imei_14"`                // 14-digit IMEI from CSV (without Luhn check digit)
-	MatchedIMEI string `json:"matched_imei,omitempty"` // 15-digit sequence found in PDF (if matched)
-	Found       bool   `json:"found"`                  // Whether the 14-digit prefix was found inside PDF
+	CSVLine         int          `json:"csv_line"`                     // 1-based row number in the source CSV
+	Column          string       `json:"column"`                       // Column name, e.g. "Imei1", "Imei2"
+	RawValue        string       `json:"raw_value"`                    // Value as it appears in the CSV
+	Validity        IMEIValidity `json:"validity"`                     // Structure check of RawValue
+	IMEI14          string       `json:"imei_14"`                      // 14-digit IMEI from CSV (without Luhn check digit)
+	Identifier      string       `json:"identifier,omitempty"`         // Serial mode: match key of RawValue under the report's rule
+	ExpectedIMEI    string       `json:"expected_imei,omitempty"`      // IMEI14 + computed Luhn check digit
+	MatchedIMEI     string       `json:"matched_imei,omitempty"`       // 15-digit sequence (serial mode: token) found in PDF (if matched)
+	PDFCheckDigitOK *bool        `json:"pdf_check_digit_ok,omitempty"` // Whether MatchedIMEI ends with the expected check digit
+	Found           bool         `json:"found"`                        // Whether the 14-digit prefix was found inside PDF
+	Normalized      bool         `json:"normalized,omitempty"`         // Found only after joining digits split by spaces, hyphens or line breaks
+	NormalizedFrom  string       `json:"normalized_from,omitempty"`    // Original PDF text of a normalized match
+	SourceFile      string       `json:"source_file,omitempty"`        // PDF file the IMEI was found in
+	SourcePage      int          `json:"source_page,omitempty"`        // 1-based page of SourceFile
+	GoodsItem       int          `json:"goods_item,omitempty"`         // Declaration goods item (graph 32) containing the IMEI
+
+	// Same IMEI14 earlier in this CSV (first occurrence)
+	DuplicateOfLine   int    `json:"duplicate_of_line,omitempty"`
+	DuplicateOfColumn string `json:"duplicate_of_column,omitempty"`
+
+	// Latest earlier verification that already registered this device
+	PreviousDeclaration *IMEIPreviousDeclaration `json:"previous_declaration,omitempty"`
+
+	// Declared device data from optional CSV columns (brand, model, track number).
+	DeclaredBrand string `json:"declared_brand,omitempty"`
+	DeclaredModel string `json:"declared_model,omitempty"`
+	TrackNumber   string `json:"track_number,omitempty"`
+
+	// Dual-SIM pairing: the IMEI columns of the row belong to different devices.
+	PairMismatch bool   `json:"pair_mismatch,omitempty"`
+	PairReason   string `json:"pair_reason,omitempty"`
+
+	// TAC lookup (first 8 digits) and brand cross-check.
+	DeviceManufacturer string `json:"device_manufacturer,omitempty"`
+	DeviceModel        string `json:"device_model,omitempty"`
+	BrandMismatch      bool   `json:"brand_mismatch,omitempty"`
+	MismatchReason     string `json:"mismatch_reason,omitempty"`
 }
 
 // IMEIColumnStats holds per-column statistics (e.g. stats for "Imei1", "Imei2", etc.).
+// Non-numeric and wrong-length values are counted as missing; bad-Luhn values are still matched.
 type IMEIColumnStats struct {
 	Column  string `json:"column"`
 	Total   int    `json:"total"`
 	Found   int    `json:"found"`
 	Missing int    `json:"missing"`
+	Invalid int    `json:"invalid"` // Non-numeric, wrong-length or bad-Luhn values
+
+	PairMismatches int `json:"pair_mismatches"` // Values in rows failing the dual-SIM pairing check
+}
+
+// IMEIDetectedColumn explains why a CSV column was treated as an IMEI column.
+type IMEIDetectedColumn struct {
+	Column    string  `json:"column"`
+	Position  int     `json:"position"`   // 1-based column number in the CSV
+	Method    string  `json:"method"`     // "header" or "content"
+	IMEIShare float64 `json:"imei_share"` // Share of 14-15 digit values among sampled non-empty values
+	Sampled   int     `json:"sampled"`    // Non-empty values sampled
+	Reason    string  `json:"reason"`
+}
+
+// IMEIFileStats holds per-PDF statistics for declarations split across several files.
+type IMEIFileStats struct {
+	FileName      string `json:"file_name"`
+	Pages         int    `json:"pages"`
+	IMEISequences int    `json:"imei_sequences"` // 15-digit sequences in the file text
+	Matched       int    `json:"matched"`        // CSV IMEIs matched in this file
+	Unexpected    int    `json:"unexpected"`     // Luhn-valid IMEIs in this file without a CSV counterpart
+
+	// Text extraction backend that produced the text and its garbage-character ratio
+	Backend      string  `json:"backend,omitempty"`
+	GarbageRatio float64 `json:"garbage_ratio"`
+	Repaired     bool    `json:"repaired,omitempty"` // Cross-reference table was rebuilt before extraction
+}
+
+// DeclarationItem is a goods item of a customs declaration: the graph 32 item number
+// and the graph 31 description block with the IMEIs and quantity found in it.
+type DeclarationItem struct {
+	Number           int      `json:"number"`
+	Page             int      `json:"page"` // Page where the item starts
+	Description      string   `json:"description"`
+	DeclaredQuantity int      `json:"declared_quantity"` // 0 when no quantity was recognized
+	IMEIs            []string `json:"imeis"`             // Distinct 15-digit sequences in the description
+}
+
+// DeclarationExtraction is what a declaration PDF contains, read without a CSV.
+type DeclarationExtraction struct {
+	FileName string              `json:"file_name"`
+	Backend  string              `json:"backend"`            // TextExtractor that produced Pages
+	Repaired bool                `json:"repaired,omitempty"` // Cross-reference table was rebuilt before extraction
+	Metadata DeclarationMetadata `json:"metadata"`
+	Pages    []DeclarationPage   `json:"pages"`
+	Items    []DeclarationItem   `json:"items,omitempty"` // Only when the layout was parsed
+
+	// Distinct Luhn-valid 15-digit sequences, in document order
+	IMEIs      []DeclarationIMEI `json:"imeis"`
+	TotalIMEIs int               `json:"total_imeis"`
+
+	// 15-digit sequences with a wrong check digit (not IMEIs, or misprinted)
+	TotalBadLuhn int `json:"total_bad_luhn"`
+}
+
+// DeclarationMetadata holds declaration header fields; empty when not detected.
+type DeclarationMetadata struct {
+	Number       string `json:"number,omitempty"`        // Registration number, e.g. 50508010/150324/0001234
+	Date         string `json:"date,omitempty"`          // YYYY-MM-DD
+	DeclarantBIN string `json:"declarant_bin,omitempty"` // 12-digit BIN of the declarant (graph 14)
+}
+
+// DeclarationPage is the extracted text of one page.
+type DeclarationPage struct {
+	Number int    `json:"number"` // 1-based
+	Text   string `json:"text"`
+}
+
+// DeclarationIMEI is an IMEI found in a declaration, at its first occurrence.
+type DeclarationIMEI struct {
+	IMEI        string `json:"imei"`
+	Page        int    `json:"page"`
+	Line        int    `json:"line"`                 // 1-based line within the page text
+	GoodsItem   int    `json:"goods_item,omitempty"` // Goods item (graph 32) listing the IMEI
+	Occurrences int    `json:"occurrences"`
+	Context     string `json:"context"` // Text around the IMEI
+	// NormalizedFrom is the original text of an IMEI split by spaces, hyphens or line breaks.
+	NormalizedFrom string `json:"normalized_from,omitempty"`
+}
+
+// IMEIGoodsItemStats compares the IMEIs of a goods item with its declared quantity.
+type IMEIGoodsItemStats struct {
+	SourceFile       string `json:"source_file"`
+	ItemNumber       int    `json:"item_number"`
+	Page             int    `json:"page"`
+	DeclaredQuantity int    `json:"declared_quantity"`
+	IMEIsInPDF       int    `json:"imeis_in_pdf"`    // Distinct IMEIs listed in graph 31
+	MatchedDevices   int    `json:"matched_devices"` // CSV rows with at least one IMEI in this item
+	QuantityMatches  *bool  `json:"quantity_matches,omitempty"`
+}
+
+// IMEIPreviousDeclaration points to an earlier verification of the same device. The
+// report and its file names are only set when the viewer may open that report.
+type IMEIPreviousDeclaration struct {
+	ReportID    *uuid.UUID `json:"report_id,omitempty"`
+	Declaration string     `json:"declaration,omitempty"` // PDF file names of the earlier verification
+	VerifiedAt  time.Time  `json:"verified_at"`
+}
+
+// TACPair is a pair of different TACs known to belong to one dual-SIM device.
+// TACA < TACB.
+type TACPair struct {
+	TACA string `json:"tac_a" db:"tac_a"`
+	TACB string `json:"tac_b" db:"tac_b"`
+	Note string `json:"note,omitempty" db:"note"`
+}
+
+// IMEIRegistryEntry records a device verified in a declaration.
+type IMEIRegistryEntry struct {
+	IMEI14      string    `json:"imei_14" db:"imei14"`
+	ReportID    uuid.UUID `json:"report_id" db:"report_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Declaration string    `json:"declaration" db:"declaration"`
+	CSVLine     int       `json:"csv_line" db:"csv_line"`
+	VerifiedAt  time.Time `json:"verified_at" db:"verified_at"`
+	PDFSHA256   []string  `json:"-" db:"-"` // Hashes of the PDFs of the registering verification
+}
+
+// IMEIUnexpected is a Luhn-valid IMEI declared in a PDF without a CSV counterpart.
+type IMEIUnexpected struct {
+	IMEI       string `json:"imei"`
+	SourceFile string `json:"source_file,omitempty"`
+	SourcePage int    `json:"source_page"`
+	Line       int    `json:"line"`    // 1-based line of the page text
+	Context    string `json:"context"` // PDF text around the IMEI
+	GoodsItem  int    `json:"goods_item,omitempty"`
 }
 
 // IMEIVerificationReport is the full output of an IMEI-vs-PDF verification job.
 // Designed per GOALS.md spec: top stats → per-column breakdown → line-by-line results.
 type IMEIVerificationReport struct {
+	// "imei", or "serial" for serial numbers matched under MatchRule
+	Mode      string `json:"mode"`
+	MatchRule string `json:"match_rule,omitempty"`
+
 	// Aggregate totals
 	TotalIMEIs   int `json:"total_imeis"`
 	TotalFound   int `json:"total_found"`
 	TotalMissing int `json:"total_missing"`
+	TotalInvalid int `json:"total_invalid"`
+
+	// Reverse direction: IMEIs declared in the PDF but absent from the CSV
+	TotalUnexpected int `json:"total_unexpected"`
+
+	// Duplicates within the CSV and devices already registered by earlier verifications
+	TotalDuplicates         int `json:"total_duplicates"`
+	TotalPreviouslyDeclared int `json:"total_previously_declared"`
+
+	// Stored verification this report belongs to (empty when it could not be saved)
+	ReportID string `json:"report_id,omitempty"`
+
+	// CSV rows whose IMEI columns do not share a TAC or a known TAC pair
+	TotalPairMismatches int `json:"total_pair_mismatches"`
+
+	// Matches found only in numbers split by spaces, hyphens or line breaks
+	TotalNormalized int `json:"total_normalized"`
+
+	// TAC annotation totals (zero when no TAC data is available)
+	TotalTACResolved     int `json:"total_tac_resolved"`
+	TotalBrandMismatches int `json:"total_brand_mismatches"`
+
+	// CSV columns treated as IMEI columns and why
+	DetectedColumns []IMEIDetectedColumn `json:"detected_columns"`
 
 	// Per-column breakdown (Imei1, Imei2, Imei3, Imei4)
 	ColumnStats []IMEIColumnStats `json:"column_stats"`
 
-	// Line-by-line verification results
+	// Per-PDF breakdown, in upload order
+	FileStats []IMEIFileStats `json:"file_stats"`
+
+	// Per goods item breakdown (only when declaration layout was parsed)
+	GoodsItems []IMEIGoodsItemStats `json:"goods_items,omitempty"`
+
+	// Line-by-line verification results (matched and missing from PDF)
 	Results []IMEIMatchResult `json:"results"`
 
+	// Unique PDF IMEIs with no CSV counterpart, in document order
+	UnexpectedInPDF []IMEIUnexpected `json:"unexpected_in_pdf"`
+
 	// Formatted Text Report
 	TextReport string `json:"text_report"`
 }
+
+// -------------------------------------------------------
+// IMEI Verification Certificates
+// -------------------------------------------------------
+
+// IMEICertificate is the signed content of a verification certificate. Every field is
+// derived from the stored verification, so a presented certificate can be rebuilt and
+// compared.
+type IMEICertificate struct {
+	Version       int                    `json:"version"`
+	ReportID      uuid.UUID              `json:"report_id"`
+	VerifiedAt    time.Time              `json:"verified_at"`
+	Verifier      IMEICertificateUser    `json:"verifier"`
+	Inputs        []IMEICertificateInput `json:"inputs"` // CSV first, then PDFs in upload order
+	Summary       IMEICertificateSummary `json:"summary"`
+	ResultsSHA256 string                 `json:"results_sha256"` // Hash of the stored full results
+	KeyID         string                 `json:"key_id"`         // Signing key fingerprint
+}
+
+// IMEICertificateUser identifies the user who ran a verification.
+type IMEICertificateUser struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// IMEICertificateInput is an uploaded file with its SHA-256 hash (hex).
+type IMEICertificateInput struct {
+	Kind     string `json:"kind"` // "csv" or "pdf"
+	FileName string `json:"file_name"`
+	SHA256   string `json:"sha256"`
+}
+
+// IMEICertificateSummary holds the totals of a verification.
+type IMEICertificateSummary struct {
+	Mode               string `json:"mode"`
+	Total              int    `json:"total"`
+	Found              int    `json:"found"`
+	Missing            int    `json:"missing"`
+	Invalid            int    `json:"invalid"`
+	Unexpected         int    `json:"unexpected"`
+	Duplicates         int    `json:"duplicates"`
+	PreviouslyDeclared int    `json:"previously_declared"`
+	PairMismatches     int    `json:"pair_mismatches"`
+}
+
+// SignedIMEICertificate is a certificate with its Ed25519 signature over the JSON
+// encoding of Certificate. Signature and PublicKey are base64 (standard encoding).
+type SignedIMEICertificate struct {
+	Certificate IMEICertificate `json:"certificate"`
+	Algorithm   string          `json:"algorithm"`
+	Signature   string          `json:"signature"`
+	PublicKey   string          `json:"public_key"`
+}
+
+// IMEICertificateCheck is the outcome of validating a presented certificate.
+type IMEICertificateCheck struct {
+	Valid          bool       `json:"valid"`
+	SignatureValid bool       `json:"signature_valid"`
+	RecordFound    bool       `json:"record_found"`
+	Mismatches     []string   `json:"mismatches,omitempty"` // Certificate fields that differ from the stored record
+	Reason         string     `json:"reason,omitempty"`
+	ReportID       *uuid.UUID `json:"report_id,omitempty"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty"`
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// AnalysisReportRepository handles stored IMEI verification and risk analysis results.
+type AnalysisReportRepository struct {
+	db *sql.DB
+}
+
+// NewAnalysisReportRepository creates a new AnalysisReportRepository.
+func NewAnalysisReportRepository(db *sql.DB) *AnalysisReportRepository {
+	return &AnalysisReportRepository{db: db}
+}
+
+// Create inserts a report and returns its ID. A preset report.ID is kept.
+func (r *AnalysisReportRepository) Create(ctx context.Context, report *models.AnalysisReport) (uuid.UUID, error) {
+	summary, err := json.Marshal(report.ResultSummary)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("encoding report summary: %w", err)
+	}
+	var results []byte
+	if len(report.Results) > 0 {
+		results = report.Results
+	}
+
+	newID := report.ID
+	if newID == uuid.Nil {
+		newID = uuid.New()
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO analysis_reports (id, user_id, report_type, input_file_name, result_summary, raw_data_url,
+		                               csv_file_name, csv_sha256, pdf_file_names, pdf_sha256, results, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())`,
+		newID, report.UserID, report.ReportType, report.InputFileName, summary, report.RawDataURL,
+		report.CSVFileName, report.CSVSHA256, report.PDFFileNames, report.PDFSHA256, results,
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("creating analysis report: %w", err)
+	}
+	return newID, nil
+}
+
+// AnalysisReportFilter holds filter parameters for listing reports.
+type AnalysisReportFilter struct {
+	ReportType string
+	UserID     *uuid.UUID
+	From       *time.Time // Inclusive
+	To         *time.Time // Exclusive
+	Page       int
+	Limit      int
+}
+
+// analysisReportColumns are the columns of a report without the full results.
+const analysisReportColumns = `id, COALESCE(user_id, '00000000-0000-0000-0000-000000000000'), COALESCE(report_type, ''),
+	COALESCE(input_file_name, ''), COALESCE(result_summary, '{}'), COALESCE(raw_data_url, ''), created_at,
+	COALESCE(csv_file_name, ''), COALESCE(csv_sha256, ''), pdf_file_names, pdf_sha256`
+
+// List returns reports matching the filter, newest first, and the total count.
+func (r *AnalysisReportRepository) List(ctx context.Context, f AnalysisReportFilter) ([]models.AnalysisReport, int, error) {
+	where := []string{}
+	args := []interface{}{}
+	argIdx := 1
+
+	if f.ReportType != "" {
+		where = append(where, fmt.Sprintf("report_type = $%d", argIdx))
+		args = append(args, f.ReportType)
+		argIdx++
+	}
+	if f.UserID != nil {
+		where = append(where, fmt.Sprintf("user_id = $%d", argIdx))
+		args = append(args, *f.UserID)
+		argIdx++
+	}
+	if f.From != nil {
+		where = append(where, fmt.Sprintf("created_at >= $%d", argIdx))
+		args = append(args, *f.From)
+		argIdx++
+	}
+	if f.To != nil {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, *f.To)
+		argIdx++
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// Count total
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM analysis_reports"+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting analysis reports: %w", err)
+	}
+
+	// Fetch page
+	offset := (f.Page - 1) * f.Limit
+	dataQuery := "SELECT " + analysisReportColumns + " FROM analysis_reports" + whereClause +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, f.Limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, dataQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing analysis reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.AnalysisReport{}
+	for rows.Next() {
+		report, err := scanAnalysisReport(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, total, rows.Err()
+}
+
+// GetByID returns a report with its full results, or nil if it does not exist.
+func (r *AnalysisReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AnalysisReport, error) {
+	row := r.db.QueryRowContext(ctx,
+		"SELECT "+analysisReportColumns+", results FROM analysis_reports WHERE id = $1", id,
+	)
+	var results []byte
+	report, err := scanAnalysisReport(row, &results)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.Results = results
+	return report, nil
+}
+
+// scanAnalysisReport scans analysisReportColumns followed by extra destinations.
+func scanAnalysisReport(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.AnalysisReport, error) {
+	var report models.AnalysisReport
+	var summary []byte
+	dest := append([]interface{}{
+		&report.ID, &report.UserID, &report.ReportType, &report.InputFileName, &summary, &report.RawDataURL, &report.CreatedAt,
+		&report.CSVFileName, &report.CSVSHA256, &report.PDFFileNames, &report.PDFSHA256,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scanning analysis report: %w", err)
+	}
+	if err := json.Unmarshal(summary, &report.ResultSummary); err != nil {
+		return nil, fmt.Errorf("decoding report summary: %w", err)
+	}
+	return &report, nil
+}
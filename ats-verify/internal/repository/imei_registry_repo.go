@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"ats-verify/internal/models"
+)
+
+// IMEIRegistryRepository handles the registry of devices verified in declarations.
+type IMEIRegistryRepository struct {
+	db *sql.DB
+}
+
+// NewIMEIRegistryRepository creates a new IMEIRegistryRepository.
+func NewIMEIRegistryRepository(db *sql.DB) *IMEIRegistryRepository {
+	return &IMEIRegistryRepository{db: db}
+}
+
+// HistoryByIMEIs returns the registry entries of each known IMEI14, newest first, with
+// the PDF hashes of the verification that registered them.
+func (r *IMEIRegistryRepository) HistoryByIMEIs(ctx context.Context, imeis []string) (map[string][]models.IMEIRegistryEntry, error) {
+	result := make(map[string][]models.IMEIRegistryEntry)
+	if len(imeis) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT g.imei14, g.report_id, g.user_id, g.declaration, g.csv_line, g.verified_at, a.pdf_sha256
+		 FROM imei_registry g JOIN analysis_reports a ON a.id = g.report_id
+		 WHERE g.imei14 = ANY($1)
+		 ORDER BY g.imei14, g.verified_at DESC`,
+		pq.Array(imeis),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("looking up IMEI registry: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.IMEIRegistryEntry
+		if err := rows.Scan(&e.IMEI14, &e.ReportID, &e.UserID, &e.Declaration, &e.CSVLine, &e.VerifiedAt, pq.Array(&e.PDFSHA256)); err != nil {
+			return nil, fmt.Errorf("scanning IMEI registry row: %w", err)
+		}
+		result[e.IMEI14] = append(result[e.IMEI14], e)
+	}
+	return result, rows.Err()
+}
+
+// Register inserts registry entries in a single transaction. An IMEI is stored once per report.
+func (r *IMEIRegistryRepository) Register(ctx context.Context, entries []models.IMEIRegistryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO imei_registry (imei14, report_id, user_id, declaration, csv_line, verified_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 ON CONFLICT (imei14, report_id) DO NOTHING`,
+	)
+	if err != nil {
+		return fmt.Errorf("preparing IMEI registry insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.ExecContext(ctx, e.IMEI14, e.ReportID, e.UserID, e.Declaration, e.CSVLine); err != nil {
+			return fmt.Errorf("registering IMEI %s: %w", e.IMEI14, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// NotificationRepository handles in-app notification database operations.
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository creates a new NotificationRepository.
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create inserts a notification and returns its ID.
+func (r *NotificationRepository) Create(ctx context.Context, n *models.Notification) (uuid.UUID, error) {
+	newID := uuid.New()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO notifications (id, user_id, kind, title, body, parcel_id, track_number, ticket_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())`,
+		newID, n.UserID, n.Kind, n.Title, n.Body, n.ParcelID, n.TrackNumber, n.TicketID,
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("creating notification: %w", err)
+	}
+	return newID, nil
+}
+
+// ListByUser returns the latest notifications of a user, optionally only unread ones.
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := `SELECT id, user_id, kind, title, body, parcel_id, track_number, ticket_id, read_at, created_at
+	          FROM notifications WHERE user_id = $1`
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC LIMIT $2"
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Body, &n.ParcelID, &n.TrackNumber, &n.TicketID, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning notification row: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// CountUnread returns the number of unread notifications of a user.
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification of the given user as read.
+func (r *NotificationRepository) MarkRead(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("marking notification read: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("notification not found")
+	}
+	return nil
+}
@@ -94,10 +94,10 @@ func (r *ParcelRepository) UpsertParcel(ctx context.Context, p *models.Parcel) (
 func (r *ParcelRepository) GetByTrackNumber(ctx context.Context, trackNumber string) (*models.Parcel, error) {
 	var p models.Parcel
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, upload_date, uploaded_by, created_at, updated_at
+		`SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, tracking_status, upload_date, uploaded_by, created_at, updated_at
 		 FROM parcels WHERE track_number = $1`,
 		trackNumber,
-	).Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt)
+	).Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.TrackingStatus, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -127,7 +127,7 @@ func (r *ParcelRepository) MarkUsed(ctx context.Context, trackNumber string) err
 
 // ListAll returns all parcels, optionally filtered by period.
 func (r *ParcelRepository) ListAll(ctx context.Context, from, to *time.Time) ([]models.Parcel, error) {
-	query := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, upload_date, uploaded_by, created_at, updated_at FROM parcels"
+	query := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, tracking_status, upload_date, uploaded_by, created_at, updated_at FROM parcels"
 	args := []interface{}{}
 
 	if from != nil && to != nil {
@@ -145,7 +145,7 @@ func (r *ParcelRepository) ListAll(ctx context.Context, from, to *time.Time) ([]
 	var parcels []models.Parcel
 	for rows.Next() {
 		var p models.Parcel
-		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.TrackingStatus, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("scanning parcel row: %w", err)
 		}
 		parcels = append(parcels, p)
@@ -189,7 +189,7 @@ func (r *ParcelRepository) ListWithFilters(ctx context.Context, status, search s
 
 	// Fetch page
 	offset := (page - 1) * limit
-	dataQuery := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, upload_date, uploaded_by, created_at, updated_at FROM parcels" +
+	dataQuery := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, tracking_status, upload_date, uploaded_by, created_at, updated_at FROM parcels" +
 		whereClause + fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
 	args = append(args, limit, offset)
 
@@ -202,7 +202,7 @@ func (r *ParcelRepository) ListWithFilters(ctx context.Context, status, search s
 	var parcels []models.Parcel
 	for rows.Next() {
 		var p models.Parcel
-		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.TrackingStatus, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("scanning parcel row: %w", err)
 		}
 		parcels = append(parcels, p)
@@ -224,7 +224,7 @@ func (r *ParcelRepository) BulkLookup(ctx context.Context, tracks []string) ([]m
 		args[i] = strings.TrimSpace(t)
 	}
 
-	query := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, upload_date, uploaded_by, created_at, updated_at FROM parcels WHERE track_number IN (" +
+	query := "SELECT id, track_number, marketplace, country, brand, product_name, snt, is_used, tracking_status, upload_date, uploaded_by, created_at, updated_at FROM parcels WHERE track_number IN (" +
 		strings.Join(placeholders, ",") + ")"
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -236,7 +236,7 @@ func (r *ParcelRepository) BulkLookup(ctx context.Context, tracks []string) ([]m
 	var parcels []models.Parcel
 	for rows.Next() {
 		var p models.Parcel
-		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.TrackNumber, &p.Marketplace, &p.Country, &p.Brand, &p.ProductName, &p.SNT, &p.IsUsed, &p.TrackingStatus, &p.UploadDate, &p.UploadedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("scanning bulk parcel: %w", err)
 		}
 		parcels = append(parcels, p)
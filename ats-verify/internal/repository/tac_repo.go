@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"ats-verify/internal/models"
+)
+
+// TACRepository handles the local Type Allocation Code table.
+type TACRepository struct {
+	db *sql.DB
+}
+
+// NewTACRepository creates a new TACRepository.
+func NewTACRepository(db *sql.DB) *TACRepository {
+	return &TACRepository{db: db}
+}
+
+// Import upserts TAC entries in a single transaction and returns the number of rows written.
+func (r *TACRepository) Import(ctx context.Context, entries []models.TACEntry) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO tac_codes (tac, manufacturer, model, updated_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (tac) DO UPDATE SET manufacturer = EXCLUDED.manufacturer, model = EXCLUDED.model, updated_at = NOW()`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("preparing TAC upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.ExecContext(ctx, e.TAC, e.Manufacturer, e.Model); err != nil {
+			return 0, fmt.Errorf("upserting TAC %s: %w", e.TAC, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return len(entries), nil
+}
+
+// LookupMany resolves TACs to devices. Unknown TACs are absent from the map.
+func (r *TACRepository) LookupMany(ctx context.Context, tacs []string) (map[string]models.TACEntry, error) {
+	result := make(map[string]models.TACEntry)
+	if len(tacs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT tac, manufacturer, model FROM tac_codes WHERE tac = ANY($1)", pq.Array(tacs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("looking up TACs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.TACEntry
+		if err := rows.Scan(&e.TAC, &e.Manufacturer, &e.Model); err != nil {
+			return nil, fmt.Errorf("scanning TAC row: %w", err)
+		}
+		result[e.TAC] = e
+	}
+	return result, nil
+}
+
+// ImportPairs upserts known dual-SIM TAC pairs in a single transaction and returns the
+// number of pairs written. Pairs must be ordered (TACA < TACB).
+func (r *TACRepository) ImportPairs(ctx context.Context, pairs []models.TACPair) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO tac_pairs (tac_a, tac_b, note)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (tac_a, tac_b) DO UPDATE SET note = EXCLUDED.note`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("preparing TAC pair upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range pairs {
+		if _, err := stmt.ExecContext(ctx, p.TACA, p.TACB, p.Note); err != nil {
+			return 0, fmt.Errorf("upserting TAC pair %s/%s: %w", p.TACA, p.TACB, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return len(pairs), nil
+}
+
+// PairsAmong returns the known pairs in which both TACs are in tacs.
+func (r *TACRepository) PairsAmong(ctx context.Context, tacs []string) ([]models.TACPair, error) {
+	if len(tacs) < 2 {
+		return nil, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT tac_a, tac_b, note FROM tac_pairs WHERE tac_a = ANY($1) AND tac_b = ANY($1)", pq.Array(tacs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("looking up TAC pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []models.TACPair
+	for rows.Next() {
+		var p models.TACPair
+		if err := rows.Scan(&p.TACA, &p.TACB, &p.Note); err != nil {
+			return nil, fmt.Errorf("scanning TAC pair row: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// Count returns the number of known TACs.
+func (r *TACRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tac_codes").Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting TACs: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// TrackingAlertRepository records which tracking alerts were already raised per parcel.
+type TrackingAlertRepository struct {
+	db *sql.DB
+}
+
+// NewTrackingAlertRepository creates a new TrackingAlertRepository.
+func NewTrackingAlertRepository(db *sql.DB) *TrackingAlertRepository {
+	return &TrackingAlertRepository{db: db}
+}
+
+// Claim registers an alert of the given kind for a parcel.
+// Returns false if the alert was already raised before (deduplication per parcel).
+func (r *TrackingAlertRepository) Claim(ctx context.Context, parcelID uuid.UUID, kind models.NotificationKind, statusCode string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO tracking_alerts (id, parcel_id, kind, status_code, created_at)
+		 VALUES ($1, $2, $3, $4, NOW())
+		 ON CONFLICT (parcel_id, kind) DO NOTHING`,
+		uuid.New(), parcelID, kind, statusCode,
+	)
+	if err != nil {
+		return false, fmt.Errorf("claiming tracking alert: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// AttachTicket links the support ticket opened for an alert.
+func (r *TrackingAlertRepository) AttachTicket(ctx context.Context, parcelID uuid.UUID, kind models.NotificationKind, ticketID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE tracking_alerts SET ticket_id = $1 WHERE parcel_id = $2 AND kind = $3",
+		ticketID, parcelID, kind,
+	)
+	if err != nil {
+		return fmt.Errorf("attaching ticket to tracking alert: %w", err)
+	}
+	return nil
+}
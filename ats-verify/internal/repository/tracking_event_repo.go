@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// TrackingEventRepository handles tracking event persistence.
+type TrackingEventRepository struct {
+	db *sql.DB
+}
+
+// NewTrackingEventRepository creates a new TrackingEventRepository.
+func NewTrackingEventRepository(db *sql.DB) *TrackingEventRepository {
+	return &TrackingEventRepository{db: db}
+}
+
+// SaveEvents stores provider events for a parcel, skipping events that were already
+// stored (same source, status code and event time), and refreshes the parcel's
+// current normalized status from its latest known event.
+// Returns the newly inserted events and the resulting parcel status.
+func (r *TrackingEventRepository) SaveEvents(ctx context.Context, parcelID uuid.UUID, events []models.TrackingEvent) ([]models.TrackingEvent, models.TrackingStatus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inserted []models.TrackingEvent
+	for _, e := range events {
+		if e.ID == uuid.Nil {
+			e.ID = uuid.New()
+		}
+		if e.NormalizedStatus == "" {
+			e.NormalizedStatus = models.TrackingStatusUnknown
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO tracking_events (id, parcel_id, status_code, normalized_status, description, location, event_time, source, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+			 ON CONFLICT (parcel_id, source, status_code, event_time) DO NOTHING`,
+			e.ID, parcelID, e.StatusCode, e.NormalizedStatus, e.Description, e.Location, e.EventTime, e.Source,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("inserting tracking event: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			e.ParcelID = parcelID
+			inserted = append(inserted, e)
+		}
+	}
+
+	var status models.TrackingStatus
+	err = tx.QueryRowContext(ctx,
+		`UPDATE parcels SET tracking_status = COALESCE((
+		     SELECT normalized_status FROM tracking_events
+		     WHERE parcel_id = $1 AND normalized_status <> 'unknown'
+		     ORDER BY event_time DESC LIMIT 1
+		 ), 'unknown'), updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING tracking_status`,
+		parcelID,
+	).Scan(&status)
+	if err != nil {
+		return nil, "", fmt.Errorf("updating parcel tracking status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit tx: %w", err)
+	}
+	return inserted, status, nil
+}
+
+// ListByParcel returns all stored events for a parcel, oldest first.
+func (r *TrackingEventRepository) ListByParcel(ctx context.Context, parcelID uuid.UUID) ([]models.TrackingEvent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, parcel_id, status_code, normalized_status, description, location, event_time, source, created_at
+		 FROM tracking_events WHERE parcel_id = $1 ORDER BY event_time ASC`,
+		parcelID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tracking events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.TrackingEvent
+	for rows.Next() {
+		var e models.TrackingEvent
+		if err := rows.Scan(&e.ID, &e.ParcelID, &e.StatusCode, &e.NormalizedStatus, &e.Description, &e.Location, &e.EventTime, &e.Source, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning tracking event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+// TransitStatsRepository reads tracking milestones for transit-time analytics.
+type TransitStatsRepository struct {
+	db *sql.DB
+}
+
+// NewTransitStatsRepository creates a new TransitStatsRepository.
+func NewTransitStatsRepository(db *sql.DB) *TransitStatsRepository {
+	return &TransitStatsRepository{db: db}
+}
+
+// ListParcelTransits returns milestones of parcels whose first scan falls in [from, to).
+func (r *TransitStatsRepository) ListParcelTransits(ctx context.Context, from, to time.Time) ([]models.ParcelTransit, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`WITH m AS (
+		     SELECT parcel_id,
+		            MIN(event_time) AS first_scan_at,
+		            MIN(event_time) FILTER (WHERE normalized_status = 'customs_hold') AS customs_in_at,
+		            MIN(event_time) FILTER (WHERE normalized_status = 'delivered') AS delivered_at
+		     FROM tracking_events
+		     WHERE event_time IS NOT NULL
+		     GROUP BY parcel_id
+		 )
+		 SELECT p.id, p.marketplace, COALESCE(p.country, ''),
+		        COALESCE((SELECT e.source FROM tracking_events e
+		                  WHERE e.parcel_id = m.parcel_id AND e.event_time = m.first_scan_at LIMIT 1), ''),
+		        m.first_scan_at, m.customs_in_at,
+		        (SELECT MIN(e.event_time) FROM tracking_events e
+		         WHERE e.parcel_id = m.parcel_id AND e.event_time > m.customs_in_at
+		           AND e.normalized_status IN ('customs_released', 'ready_for_pickup', 'delivered')),
+		        m.delivered_at
+		 FROM m
+		 JOIN parcels p ON p.id = m.parcel_id
+		 WHERE m.first_scan_at >= $1 AND m.first_scan_at < $2`,
+		from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing parcel transits: %w", err)
+	}
+	defer rows.Close()
+
+	var transits []models.ParcelTransit
+	for rows.Next() {
+		var t models.ParcelTransit
+		if err := rows.Scan(&t.ParcelID, &t.Marketplace, &t.Country, &t.Carrier,
+			&t.FirstScanAt, &t.CustomsInAt, &t.CustomsOutAt, &t.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("scanning parcel transit row: %w", err)
+		}
+		transits = append(transits, t)
+	}
+	return transits, rows.Err()
+}
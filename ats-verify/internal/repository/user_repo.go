@@ -81,3 +81,26 @@ func (r *UserRepository) ApproveUser(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+// ListApprovedByRole returns all approved users with the given role.
+func (r *UserRepository) ListApprovedByRole(ctx context.Context, role models.UserRole) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, username, password_hash, role, marketplace_prefix, is_approved, created_at, updated_at
+		 FROM users WHERE role = $1 AND is_approved = true ORDER BY created_at`,
+		role,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing users by role: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.MarketplacePrefix, &u.IsApproved, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning user row: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+var (
+	// ErrWatchlistDuplicate is returned by Create when the user already watches the track.
+	ErrWatchlistDuplicate = errors.New("track is already on the watchlist")
+	// ErrWatchlistFull is returned by Create when the user's watchlist has reached the limit.
+	ErrWatchlistFull = errors.New("watchlist is full")
+)
+
+// WatchlistRepository handles watchlist database operations.
+type WatchlistRepository struct {
+	db *sql.DB
+}
+
+// NewWatchlistRepository creates a new WatchlistRepository.
+func NewWatchlistRepository(db *sql.DB) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+const watchlistColumns = `id, user_id, track_number, label, channels, email, webhook_url,
+	last_status, last_event_key, last_event_at, last_checked_at, last_error, created_at`
+
+func scanWatchlistItem(s interface{ Scan(...interface{}) error }, item *models.WatchlistItem) error {
+	return s.Scan(&item.ID, &item.UserID, &item.TrackNumber, &item.Label, &item.Channels, &item.Email, &item.WebhookURL,
+		&item.LastStatus, &item.LastEventKey, &item.LastEventAt, &item.LastCheckedAt, &item.LastError, &item.CreatedAt)
+}
+
+// Create inserts a watchlist item unless the user already has limit items (a negative
+// limit means unlimited). The user's row is locked for the count and the insert, so
+// concurrent adds cannot both pass the limit. Returns ErrWatchlistFull or
+// ErrWatchlistDuplicate if the user already watches the track.
+func (r *WatchlistRepository) Create(ctx context.Context, item *models.WatchlistItem, limit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if limit >= 0 {
+		if _, err := tx.ExecContext(ctx, "SELECT 1 FROM users WHERE id = $1 FOR UPDATE", item.UserID); err != nil {
+			return fmt.Errorf("locking user: %w", err)
+		}
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM watchlist_items WHERE user_id = $1", item.UserID).Scan(&count); err != nil {
+			return fmt.Errorf("counting watchlist: %w", err)
+		}
+		if count >= limit {
+			return ErrWatchlistFull
+		}
+	}
+
+	item.ID = uuid.New()
+	item.LastStatus = models.TrackingStatusUnknown
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO watchlist_items (id, user_id, track_number, label, channels, email, webhook_url, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		 ON CONFLICT (user_id, track_number) DO NOTHING
+		 RETURNING created_at`,
+		item.ID, item.UserID, item.TrackNumber, item.Label, item.Channels, item.Email, item.WebhookURL,
+	).Scan(&item.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrWatchlistDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("creating watchlist item: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListByUser returns the watchlist of a user.
+func (r *WatchlistRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.WatchlistItem, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+watchlistColumns+" FROM watchlist_items WHERE user_id = $1 ORDER BY created_at DESC", userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing watchlist: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WatchlistItem
+	for rows.Next() {
+		var item models.WatchlistItem
+		if err := scanWatchlistItem(rows, &item); err != nil {
+			return nil, fmt.Errorf("scanning watchlist row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Delete removes a watchlist item of the given user.
+func (r *WatchlistRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM watchlist_items WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("deleting watchlist item: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("watchlist item not found")
+	}
+	return nil
+}
+
+// ListDue returns items not checked since the given time, least recently checked first.
+func (r *WatchlistRepository) ListDue(ctx context.Context, checkedBefore time.Time, limit int) ([]models.WatchlistItem, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+watchlistColumns+` FROM watchlist_items
+		 WHERE last_checked_at IS NULL OR last_checked_at < $1
+		 ORDER BY last_checked_at NULLS FIRST LIMIT $2`,
+		checkedBefore, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing due watchlist items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WatchlistItem
+	for rows.Next() {
+		var item models.WatchlistItem
+		if err := scanWatchlistItem(rows, &item); err != nil {
+			return nil, fmt.Errorf("scanning watchlist row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// UpdateState stores the result of a background refresh.
+func (r *WatchlistRepository) UpdateState(ctx context.Context, item *models.WatchlistItem) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE watchlist_items
+		 SET last_status = $1, last_event_key = $2, last_event_at = $3, last_checked_at = $4, last_error = $5
+		 WHERE id = $6`,
+		item.LastStatus, item.LastEventKey, item.LastEventAt, item.LastCheckedAt, item.LastError, item.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating watchlist item: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// Bulk tracking job states.
+const (
+	BulkJobQueued    = "queued"
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+)
+
+// Bulk tracking item states.
+const (
+	BulkItemPending = "pending"
+	BulkItemDone    = "done"
+	BulkItemFailed  = "failed"
+)
+
+// bulkJobRetention is how long finished jobs are kept in memory for download.
+const bulkJobRetention = 24 * time.Hour
+
+// bulkJobTimeout bounds the total run time of a single job.
+const bulkJobTimeout = 2 * time.Hour
+
+// BulkTrackingItem is the latest external status of one track in a bulk job.
+type BulkTrackingItem struct {
+	TrackNumber   string                `json:"track_number"`
+	State         string                `json:"state"` // pending, done, failed
+	InDB          bool                  `json:"in_db"`
+	IsUsed        *bool                 `json:"is_used,omitempty"`
+	Provider      string                `json:"provider,omitempty"`
+	Status        models.TrackingStatus `json:"status,omitempty"`
+	LastEventTime *time.Time            `json:"last_event_time,omitempty"`
+	LastEvent     string                `json:"last_event,omitempty"`
+	LastLocation  string                `json:"last_location,omitempty"`
+	ExternalURL   string                `json:"external_url,omitempty"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// BulkTrackingJob is an asynchronous external tracking job over many tracks.
+type BulkTrackingJob struct {
+	ID         uuid.UUID          `json:"id"`
+	Status     string             `json:"status"` // queued, running, completed
+	CreatedBy  uuid.UUID          `json:"created_by"`
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Results    []BulkTrackingItem `json:"results"`
+}
+
+// BulkTrackingService runs bulk external tracking jobs on a bounded worker pool.
+// Jobs live in memory only; finished jobs are dropped after bulkJobRetention.
+// Per-provider limits are enforced by the ResilientTracker wrapping each provider.
+type BulkTrackingService struct {
+	trackingService *TrackingService
+	parcelRepo      *repository.ParcelRepository
+	workers         int
+	maxTracks       int
+
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*BulkTrackingJob
+}
+
+// NewBulkTrackingService creates a new BulkTrackingService.
+func NewBulkTrackingService(trackingService *TrackingService, parcelRepo *repository.ParcelRepository, workers, maxTracks int) *BulkTrackingService {
+	if workers < 1 {
+		workers = 1
+	}
+	return &BulkTrackingService{
+		trackingService: trackingService,
+		parcelRepo:      parcelRepo,
+		workers:         workers,
+		maxTracks:       maxTracks,
+		jobs:            make(map[uuid.UUID]*BulkTrackingJob),
+	}
+}
+
+// Start validates the tracks, registers a job and runs it in the background.
+// Duplicate and empty track numbers are dropped.
+func (s *BulkTrackingService) Start(createdBy uuid.UUID, tracks []string) (*BulkTrackingJob, error) {
+	seen := make(map[string]bool, len(tracks))
+	var unique []string
+	for _, t := range tracks {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		unique = append(unique, t)
+	}
+
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("tracks array is required")
+	}
+	if s.maxTracks > 0 && len(unique) > s.maxTracks {
+		return nil, fmt.Errorf("too many tracks: %d (max %d per job)", len(unique), s.maxTracks)
+	}
+
+	job := &BulkTrackingJob{
+		ID:        uuid.New(),
+		Status:    BulkJobQueued,
+		CreatedBy: createdBy,
+		Total:     len(unique),
+		CreatedAt: time.Now(),
+		Results:   make([]BulkTrackingItem, len(unique)),
+	}
+	for i, t := range unique {
+		job.Results[i] = BulkTrackingItem{TrackNumber: t, State: BulkItemPending}
+	}
+
+	s.mu.Lock()
+	s.evictExpiredLocked()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go s.run(job)
+
+	return s.Get(job.ID), nil
+}
+
+// Get returns a snapshot of a job (including partial results), or nil if unknown.
+func (s *BulkTrackingService) Get(id uuid.UUID) *BulkTrackingJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	snapshot := *job
+	snapshot.Results = make([]BulkTrackingItem, len(job.Results))
+	copy(snapshot.Results, job.Results)
+	return &snapshot
+}
+
+// run processes all tracks of a job with a bounded worker pool.
+func (s *BulkTrackingService) run(job *BulkTrackingJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), bulkJobTimeout)
+	defer cancel()
+
+	s.mu.Lock()
+	started := time.Now()
+	job.Status = BulkJobRunning
+	job.StartedAt = &started
+	tracks := make([]string, len(job.Results))
+	for i, item := range job.Results {
+		tracks[i] = item.TrackNumber
+	}
+	s.mu.Unlock()
+
+	parcels := s.lookupParcels(ctx, tracks)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				item := s.trackOne(ctx, tracks[i], parcels[tracks[i]])
+
+				s.mu.Lock()
+				job.Results[i] = item
+				job.Processed++
+				if item.State == BulkItemDone {
+					job.Succeeded++
+				} else {
+					job.Failed++
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range tracks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	s.mu.Lock()
+	finished := time.Now()
+	job.Status = BulkJobCompleted
+	job.FinishedAt = &finished
+	s.mu.Unlock()
+
+	log.Printf("bulk tracking: job %s finished (%d tracks, %d failed) in %s", job.ID, job.Total, job.Failed, finished.Sub(started).Round(time.Second))
+}
+
+// lookupParcels returns our DB parcels for the given tracks, keyed by track number.
+func (s *BulkTrackingService) lookupParcels(ctx context.Context, tracks []string) map[string]*models.Parcel {
+	parcels := make(map[string]*models.Parcel)
+	if s.parcelRepo == nil {
+		return parcels
+	}
+
+	const chunkSize = 1000
+	for start := 0; start < len(tracks); start += chunkSize {
+		end := start + chunkSize
+		if end > len(tracks) {
+			end = len(tracks)
+		}
+		found, err := s.parcelRepo.BulkLookup(ctx, tracks[start:end])
+		if err != nil {
+			log.Printf("bulk tracking: parcel lookup failed: %v", err)
+			continue
+		}
+		for i := range found {
+			parcels[found[i].TrackNumber] = &found[i]
+		}
+	}
+	return parcels
+}
+
+// trackOne queries external providers for a single track and records events for known parcels.
+func (s *BulkTrackingService) trackOne(ctx context.Context, track string, parcel *models.Parcel) BulkTrackingItem {
+	item := BulkTrackingItem{TrackNumber: track, State: BulkItemDone}
+	if parcel != nil {
+		used := parcel.IsUsed
+		item.InDB = true
+		item.IsUsed = &used
+	}
+
+	result, err := s.trackingService.Track(ctx, track)
+	if err != nil {
+		item.State = BulkItemFailed
+		item.Error = err.Error()
+		return item
+	}
+
+	item.Provider = result.Provider
+	item.Status = result.Status
+	item.ExternalURL = result.ExternalURL
+	if last := latestEvent(result.Events); last != nil {
+		eventTime := last.EventTime
+		item.LastEventTime = &eventTime
+		item.LastEvent = last.Description
+		item.LastLocation = last.Location
+	}
+
+	if parcel != nil {
+		if _, err := s.trackingService.RecordEvents(ctx, parcel, result.Events); err != nil {
+			log.Printf("bulk tracking: %v", err)
+		}
+	}
+	return item
+}
+
+// evictExpiredLocked drops finished jobs older than bulkJobRetention. Caller holds s.mu.
+func (s *BulkTrackingService) evictExpiredLocked() {
+	cutoff := time.Now().Add(-bulkJobRetention)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// latestEvent returns the event with the latest event time, or nil.
+func latestEvent(events []models.TrackingEvent) *models.TrackingEvent {
+	var latest *models.TrackingEvent
+	for i := range events {
+		if latest == nil || events[i].EventTime.After(latest.EventTime) {
+			latest = &events[i]
+		}
+	}
+	return latest
+}
+
+// WriteBulkTrackingCSV writes job results as CSV. Pending items are included so a
+// partial export still lists every submitted track.
+func WriteBulkTrackingCSV(w io.Writer, job *BulkTrackingJob) error {
+	cw := csv.NewWriter(w)
+	header := []string{"track_number", "state", "in_db", "is_used", "provider", "status", "last_event_time", "last_event", "last_location", "external_url", "error"}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
+	}
+
+	for _, item := range job.Results {
+		isUsed := ""
+		if item.IsUsed != nil {
+			isUsed = fmt.Sprintf("%t", *item.IsUsed)
+		}
+		eventTime := ""
+		if item.LastEventTime != nil && !item.LastEventTime.IsZero() {
+			eventTime = item.LastEventTime.Format(time.RFC3339)
+		}
+		record := []string{
+			item.TrackNumber,
+			item.State,
+			fmt.Sprintf("%t", item.InDB),
+			isUsed,
+			item.Provider,
+			string(item.Status),
+			eventTime,
+			item.LastEvent,
+			item.LastLocation,
+			item.ExternalURL,
+			item.Error,
+		}
+		if err := cw.Write(csvSafeRecord(record)); err != nil {
+			return fmt.Errorf("writing CSV row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
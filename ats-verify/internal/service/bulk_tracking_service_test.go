@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// mapTracker returns a fixed event per known track and an error for the rest.
+type mapTracker struct {
+	events map[string]models.TrackingEvent
+}
+
+func (m *mapTracker) Provider() string { return "Kazpost" }
+
+func (m *mapTracker) Track(ctx context.Context, trackNumber string) ([]models.TrackingEvent, error) {
+	e, ok := m.events[trackNumber]
+	if !ok {
+		return nil, fmt.Errorf("kazpost: not found")
+	}
+	return []models.TrackingEvent{e}, nil
+}
+
+func waitForBulkJob(t *testing.T, svc *BulkTrackingService, id uuid.UUID) *BulkTrackingJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job := svc.Get(id); job.Status == BulkJobCompleted {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("bulk job did not complete in time")
+	return nil
+}
+
+func TestBulkTrackingService_RunsJobWithPartialFailures(t *testing.T) {
+	tracker := &mapTracker{events: map[string]models.TrackingEvent{
+		"CN001KZ": {StatusCode: "DetainedByCustom", NormalizedStatus: models.TrackingStatusCustomsHold, Description: "Задержано таможней", Location: "Алматы"},
+		"CN002KZ": {StatusCode: "HAND", NormalizedStatus: models.TrackingStatusDelivered, Description: "Вручено"},
+	}}
+	svc := NewBulkTrackingService(NewTrackingService(nil, tracker), nil, 3, 100)
+
+	job, err := svc.Start(uuid.New(), []string{"CN001KZ", " CN002KZ ", "CN001KZ", "", "UNKNOWN"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if job.Total != 3 {
+		t.Fatalf("expected duplicates and blanks to be dropped (3 tracks), got %d", job.Total)
+	}
+
+	done := waitForBulkJob(t, svc, job.ID)
+	if done.Processed != 3 || done.Succeeded != 2 || done.Failed != 1 {
+		t.Errorf("expected 3 processed / 2 ok / 1 failed, got %d / %d / %d", done.Processed, done.Succeeded, done.Failed)
+	}
+	if done.Results[0].Status != models.TrackingStatusCustomsHold || done.Results[0].LastLocation != "Алматы" {
+		t.Errorf("unexpected first result: %+v", done.Results[0])
+	}
+	if done.Results[2].State != BulkItemFailed || done.Results[2].Error == "" {
+		t.Errorf("expected UNKNOWN to fail with an error, got %+v", done.Results[2])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBulkTrackingCSV(&buf, done); err != nil {
+		t.Fatalf("expected no CSV error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header + 3 rows, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[1], "CN001KZ,done,false,,Kazpost,customs_hold,") {
+		t.Errorf("unexpected CSV row: %s", lines[1])
+	}
+}
+
+func TestWriteBulkTrackingCSV_EscapesFormulas(t *testing.T) {
+	job := &BulkTrackingJob{Results: []BulkTrackingItem{
+		{TrackNumber: "=HYPERLINK(\"http://x\")", State: BulkItemFailed, Error: "@SUM(A1)"},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteBulkTrackingCSV(&buf, job); err != nil {
+		t.Fatalf("expected no CSV error, got %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row := records[1]; row[0] != `'=HYPERLINK("http://x")` || row[10] != "'@SUM(A1)" {
+		t.Errorf("expected formula-like cells to be prefixed with ', got %q", row)
+	}
+}
+
+func TestBulkTrackingService_RejectsOversizedJobs(t *testing.T) {
+	svc := NewBulkTrackingService(NewTrackingService(nil), nil, 1, 2)
+
+	if _, err := svc.Start(uuid.New(), []string{"A", "B", "C"}); err == nil {
+		t.Fatal("expected an error for more tracks than the job limit")
+	}
+	if _, err := svc.Start(uuid.New(), nil); err == nil {
+		t.Fatal("expected an error for an empty track list")
+	}
+}
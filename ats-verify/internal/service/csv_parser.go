@@ -1,29 +1,35 @@
 package service
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"io"
 )
 
+// csvPeekSize is how much of the input is buffered to detect the separator.
+const csvPeekSize = 64 << 10
+
 // NewRobustCSVReader creates a CSV reader that handles BOM, detects ';' vs ',',
-// and sets LazyQuotes to handle malformed data.
+// and sets LazyQuotes to handle malformed data. The input is streamed; only the
+// first line is inspected up front.
 func NewRobustCSVReader(reader io.Reader) (*csv.Reader, error) {
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
+	br := bufio.NewReaderSize(reader, csvPeekSize)
 
 	// 1. Remove UTF-8 BOM if present
-	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
 
 	// 2. Detect separator: look at the first line
-	firstLineEnd := bytes.IndexByte(data, '\n')
-	var firstLine []byte
-	if firstLineEnd == -1 {
-		firstLine = data
-	} else {
-		firstLine = data[:firstLineEnd]
+	head, err := br.Peek(csvPeekSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	firstLine := head
+	if firstLineEnd := bytes.IndexByte(head, '\n'); firstLineEnd != -1 {
+		firstLine = head[:firstLineEnd]
 	}
 
 	comma := ','
@@ -37,7 +43,7 @@ func NewRobustCSVReader(reader io.Reader) (*csv.Reader, error) {
 	}
 
 	// 3. Create reader with robust settings
-	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader := csv.NewReader(br)
 	csvReader.Comma = comma
 	csvReader.TrimLeadingSpace = true // Trims leading space of field
 	csvReader.LazyQuotes = true       // Allow unescaped quotes
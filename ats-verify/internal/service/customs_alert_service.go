@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// CustomsAlertService raises alerts when a parcel from our database is held by customs
+// or requires a customs payment. Each alert kind fires at most once per parcel.
+// It is registered as a TrackingEventHook on the TrackingService.
+type CustomsAlertService struct {
+	alertRepo     *repository.TrackingAlertRepository
+	ticketRepo    *repository.TicketRepository
+	userRepo      *repository.UserRepository
+	notifications *NotificationService
+
+	createTickets bool
+	ticketAuthor  string // Username recorded as created_by on auto-opened tickets
+}
+
+// NewCustomsAlertService creates a new CustomsAlertService.
+// When createTickets is true, a support ticket prefilled with the parcel data is opened
+// on behalf of ticketAuthor for every new alert.
+func NewCustomsAlertService(
+	alertRepo *repository.TrackingAlertRepository,
+	ticketRepo *repository.TicketRepository,
+	userRepo *repository.UserRepository,
+	notifications *NotificationService,
+	createTickets bool,
+	ticketAuthor string,
+) *CustomsAlertService {
+	return &CustomsAlertService{
+		alertRepo:     alertRepo,
+		ticketRepo:    ticketRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+		createTickets: createTickets,
+		ticketAuthor:  ticketAuthor,
+	}
+}
+
+// OnTrackingEvents implements TrackingEventHook.
+func (s *CustomsAlertService) OnTrackingEvents(ctx context.Context, parcel *models.Parcel, newEvents []models.TrackingEvent) {
+	// Only alert while the parcel is still held; replaying an old history of an
+	// already released parcel must not page anybody.
+	if parcel.TrackingStatus != models.TrackingStatusCustomsHold {
+		return
+	}
+
+	for _, alert := range customsAlerts(newEvents) {
+		claimed, err := s.alertRepo.Claim(ctx, parcel.ID, alert.kind, alert.event.StatusCode)
+		if err != nil {
+			log.Printf("customs alert: %v", err)
+			continue
+		}
+		if !claimed {
+			continue // already raised for this parcel
+		}
+
+		n := buildCustomsAlertNotification(parcel, alert.kind, alert.event)
+		if s.createTickets {
+			if ticketID, err := s.openTicket(ctx, parcel, alert.kind, alert.event); err != nil {
+				log.Printf("customs alert: opening ticket for %s: %v", parcel.TrackNumber, err)
+			} else {
+				n.TicketID = &ticketID
+			}
+		}
+
+		if _, err := s.notifications.NotifyRole(ctx, models.RoleCustoms, n); err != nil {
+			log.Printf("customs alert: notifying customs staff: %v", err)
+		}
+		if _, err := s.notifications.NotifyMarketplace(ctx, parcel.Marketplace, n); err != nil {
+			log.Printf("customs alert: notifying marketplace %s: %v", parcel.Marketplace, err)
+		}
+	}
+}
+
+// openTicket opens a support ticket prefilled with the track and parcel data.
+func (s *CustomsAlertService) openTicket(ctx context.Context, parcel *models.Parcel, kind models.NotificationKind, event models.TrackingEvent) (uuid.UUID, error) {
+	author, err := s.userRepo.GetByUsername(ctx, s.ticketAuthor)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if author == nil {
+		return uuid.Nil, fmt.Errorf("ticket author %q not found", s.ticketAuthor)
+	}
+
+	documentNumber := parcel.SNT
+	if documentNumber == "" {
+		documentNumber = "-"
+	}
+
+	ticket := &models.SupportTicket{
+		FullName:          parcel.Marketplace,
+		SupportTicketID:   fmt.Sprintf("ALERT-%s-%s", strings.ToUpper(string(kind)), parcel.TrackNumber),
+		ApplicationNumber: parcel.TrackNumber,
+		DocumentNumber:    documentNumber,
+		RejectionReason:   customsAlertTitle(kind) + ": " + event.Description,
+		SupportComment:    customsAlertBody(parcel, event),
+		Status:            models.TicketStatusToDo,
+		Priority:          models.PriorityHigh,
+		CreatedBy:         author.ID,
+	}
+
+	ticketID, err := s.ticketRepo.Create(ctx, ticket)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.alertRepo.AttachTicket(ctx, parcel.ID, kind, ticketID); err != nil {
+		log.Printf("customs alert: %v", err)
+	}
+	return ticketID, nil
+}
+
+type customsAlert struct {
+	kind  models.NotificationKind
+	event models.TrackingEvent
+}
+
+// customsAlerts returns one alert per kind found in the events, keeping the first
+// triggering event of each kind.
+func customsAlerts(events []models.TrackingEvent) []customsAlert {
+	var alerts []customsAlert
+	seen := make(map[models.NotificationKind]bool)
+	for _, e := range events {
+		kind, ok := customsAlertKind(e)
+		if !ok || seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		alerts = append(alerts, customsAlert{kind: kind, event: e})
+	}
+	return alerts
+}
+
+// customsAlertKind classifies an event as a customs hold or a payment request.
+func customsAlertKind(e models.TrackingEvent) (models.NotificationKind, bool) {
+	for _, code := range strings.Split(e.StatusCode, ",") {
+		if strings.TrimSpace(code) == "PaymentRequired" {
+			return models.NotificationPaymentRequired, true
+		}
+	}
+	if e.NormalizedStatus == models.TrackingStatusCustomsHold {
+		return models.NotificationCustomsHold, true
+	}
+	return "", false
+}
+
+func customsAlertTitle(kind models.NotificationKind) string {
+	if kind == models.NotificationPaymentRequired {
+		return "Требуется оплата таможенных платежей"
+	}
+	return "Посылка задержана таможней"
+}
+
+func customsAlertBody(parcel *models.Parcel, event models.TrackingEvent) string {
+	var parts []string
+	parts = append(parts, "Трек: "+parcel.TrackNumber)
+	if parcel.Marketplace != "" {
+		parts = append(parts, "Маркетплейс: "+parcel.Marketplace)
+	}
+	if parcel.Brand != "" || parcel.ProductName != "" {
+		parts = append(parts, "Товар: "+strings.TrimSpace(parcel.Brand+" "+parcel.ProductName))
+	}
+	if parcel.Country != "" {
+		parts = append(parts, "Страна: "+parcel.Country)
+	}
+	if parcel.SNT != "" {
+		parts = append(parts, "СНТ: "+parcel.SNT)
+	}
+	status := event.Description
+	if event.StatusCode != "" {
+		status += " (" + event.StatusCode + ")"
+	}
+	parts = append(parts, "Статус: "+strings.TrimSpace(status))
+	if event.Location != "" {
+		parts = append(parts, "Место: "+event.Location)
+	}
+	if !event.EventTime.IsZero() {
+		parts = append(parts, "Время: "+event.EventTime.Format("02.01.2006 15:04"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func buildCustomsAlertNotification(parcel *models.Parcel, kind models.NotificationKind, event models.TrackingEvent) models.Notification {
+	parcelID := parcel.ID
+	return models.Notification{
+		Kind:        kind,
+		Title:       customsAlertTitle(kind) + ": " + parcel.TrackNumber,
+		Body:        customsAlertBody(parcel, event),
+		ParcelID:    &parcelID,
+		TrackNumber: parcel.TrackNumber,
+	}
+}
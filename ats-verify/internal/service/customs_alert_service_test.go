@@ -0,0 +1,53 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+func TestCustomsAlerts_OnePerKind(t *testing.T) {
+	events := []models.TrackingEvent{
+		{StatusCode: "RECEIVED", NormalizedStatus: models.TrackingStatusArrivedCountry},
+		{StatusCode: "DetainedByCustom", NormalizedStatus: models.TrackingStatusCustomsHold, Description: "Задержано таможней"},
+		{StatusCode: "CUSTOMS_HOLD", NormalizedStatus: models.TrackingStatusCustomsHold},
+		{StatusCode: "DetainedByCustom,PaymentRequired", NormalizedStatus: models.TrackingStatusCustomsHold},
+	}
+
+	alerts := customsAlerts(events)
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+	if alerts[0].kind != models.NotificationCustomsHold || alerts[0].event.StatusCode != "DetainedByCustom" {
+		t.Errorf("expected first customs hold event to win, got %+v", alerts[0])
+	}
+	if alerts[1].kind != models.NotificationPaymentRequired {
+		t.Errorf("expected payment_required alert, got %s", alerts[1].kind)
+	}
+}
+
+func TestCustomsAlerts_IgnoresOtherStatuses(t *testing.T) {
+	events := []models.TrackingEvent{
+		{StatusCode: "HAND", NormalizedStatus: models.TrackingStatusDelivered},
+		{StatusCode: "NotPaymentRequired", NormalizedStatus: models.TrackingStatusInTransit},
+	}
+	if alerts := customsAlerts(events); len(alerts) != 0 {
+		t.Errorf("expected no alerts, got %+v", alerts)
+	}
+}
+
+func TestBuildCustomsAlertNotification(t *testing.T) {
+	parcel := &models.Parcel{TrackNumber: "CN001KZ", Marketplace: "Wildberries", Brand: "Apple", ProductName: "iPhone 15", SNT: "SNT-1"}
+	event := models.TrackingEvent{StatusCode: "DetainedByCustom", Description: "Задержано таможней", Location: "Алматы"}
+
+	n := buildCustomsAlertNotification(parcel, models.NotificationCustomsHold, event)
+	if n.ParcelID == nil || n.TrackNumber != "CN001KZ" {
+		t.Fatalf("expected parcel reference, got %+v", n)
+	}
+	for _, want := range []string{"Wildberries", "Apple iPhone 15", "SNT-1", "Алматы", "DetainedByCustom"} {
+		if !strings.Contains(n.Body, want) {
+			t.Errorf("expected body to contain %q, got %q", want, n.Body)
+		}
+	}
+}
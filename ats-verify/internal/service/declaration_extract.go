@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+var (
+	// reDeclarationNumber matches a declaration registration number:
+	// customs office code / date DDMMYY / serial number, e.g. "50508010/150324/0001234".
+	reDeclarationNumber = regexp.MustCompile(`\b(\d{8})\s?/\s?(\d{6})\s?/\s?([A-ZА-Я]?\d{7})\b`)
+	// reLabeledDate matches a date after a "Дата" caption, e.g. "Дата: 15.03.2024".
+	reLabeledDate = regexp.MustCompile(`(?i)дата[^\d\n]{0,40}(\d{2})\.(\d{2})\.(\d{4})`)
+	// reDeclarantCaption matches the caption of graph 14.
+	reDeclarantCaption = regexp.MustCompile(`(?i)декларант`)
+	// reBIN matches a 12-digit BIN after a "БИН" / "ИИН/БИН" / "BIN" caption.
+	reBIN = regexp.MustCompile(`(?i)(?:бин|bin)[^\d\n]{0,20}(\d{12})\b`)
+)
+
+// DeclarationService reads declaration PDFs on their own, before any CSV exists.
+type DeclarationService struct {
+	pdfExtractor *MultiExtractor
+}
+
+// NewDeclarationService creates a new DeclarationService.
+func NewDeclarationService(pdfExtractor *MultiExtractor) *DeclarationService {
+	return &DeclarationService{pdfExtractor: pdfExtractor}
+}
+
+// Extract extracts the text of a declaration PDF and lists its IMEIs and header fields.
+// password opens encrypted PDFs.
+func (s *DeclarationService) Extract(ctx context.Context, fileName string, data []byte, password string) (*models.DeclarationExtraction, error) {
+	doc, err := s.pdfExtractor.ExtractDocument(ctx, fileName, data, password)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDeclaration(doc), nil
+}
+
+// summarizeDeclaration builds the extraction result of an extracted document.
+func summarizeDeclaration(doc PDFDocument) *models.DeclarationExtraction {
+	ext := &models.DeclarationExtraction{
+		FileName: doc.FileName,
+		Backend:  doc.Backend,
+		Repaired: doc.Repaired,
+		Metadata: declarationMetadata(doc.Pages),
+		Pages:    make([]models.DeclarationPage, 0, len(doc.Pages)),
+		Items:    doc.Items,
+		IMEIs:    []models.DeclarationIMEI{},
+	}
+
+	itemOf := make(map[string]int)
+	addItemIMEI := func(imei string, item int) {
+		if _, ok := itemOf[imei]; !ok {
+			itemOf[imei] = item
+		}
+	}
+	for _, item := range doc.Items {
+		for _, imei := range item.IMEIs {
+			addItemIMEI(imei, item.Number)
+		}
+		for _, run := range findSplitDigitRuns(item.Description) {
+			if len(run.digits) == 15 {
+				addItemIMEI(run.digits, item.Number)
+			}
+		}
+	}
+
+	first := make(map[string]int) // IMEI → index in ext.IMEIs
+	badLuhn := make(map[string]bool)
+	for n, text := range doc.Pages {
+		ext.Pages = append(ext.Pages, models.DeclarationPage{Number: n + 1, Text: text})
+		for _, hit := range pageIMEISequences(text) {
+			if luhnCheckDigit(hit.digits[:14]) != hit.digits[14] {
+				if hit.from == "" {
+					badLuhn[hit.digits] = true
+				}
+				continue
+			}
+			if i, ok := first[hit.digits]; ok {
+				ext.IMEIs[i].Occurrences++
+				continue
+			}
+			first[hit.digits] = len(ext.IMEIs)
+			ext.IMEIs = append(ext.IMEIs, models.DeclarationIMEI{
+				IMEI:           hit.digits,
+				Page:           n + 1,
+				Line:           strings.Count(text[:hit.start], "\n") + 1,
+				GoodsItem:      itemOf[hit.digits],
+				Occurrences:    1,
+				Context:        textContext(text, hit.start, hit.end),
+				NormalizedFrom: hit.from,
+			})
+		}
+	}
+	ext.TotalIMEIs = len(ext.IMEIs)
+	ext.TotalBadLuhn = len(badLuhn)
+	return ext
+}
+
+// imeiSequence is a 15-digit sequence of a page text; from is the original text of a
+// number rebuilt from split pieces.
+type imeiSequence struct {
+	digits     string
+	start, end int
+	from       string
+}
+
+// pageIMEISequences returns the unbroken 15-digit sequences of text and the 15-digit
+// numbers split by spaces, hyphens or line breaks, in text order. Split numbers with a
+// wrong check digit are usually unrelated digits and are left to the caller to drop.
+func pageIMEISequences(text string) []imeiSequence {
+	var out []imeiSequence
+	for _, loc := range regex15Digits.FindAllStringIndex(text, -1) {
+		out = append(out, imeiSequence{digits: text[loc[0]:loc[1]], start: loc[0], end: loc[1]})
+	}
+	for _, run := range findSplitDigitRuns(text) {
+		if len(run.digits) != 15 {
+			continue
+		}
+		start, end := run.offsets[0], run.offsets[len(run.offsets)-1]+1
+		out = append(out, imeiSequence{digits: run.digits, start: start, end: end, from: run.source(text)})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].start < out[j].start })
+	return out
+}
+
+// declarationMetadata detects the registration number, date and declarant BIN. The date
+// comes from the registration number, else from the first "Дата" caption. The BIN after
+// the graph 14 caption wins over any other BIN.
+func declarationMetadata(pages []string) models.DeclarationMetadata {
+	var meta models.DeclarationMetadata
+	text := strings.Join(pages, "\n")
+
+	if m := reDeclarationNumber.FindStringSubmatch(text); m != nil {
+		meta.Number = m[1] + "/" + m[2] + "/" + m[3]
+		if d, err := time.Parse("020106", m[2]); err == nil {
+			meta.Date = d.Format("2006-01-02")
+		}
+	}
+	if meta.Date == "" {
+		if m := reLabeledDate.FindStringSubmatch(text); m != nil {
+			if d, err := time.Parse("02.01.2006", m[1]+"."+m[2]+"."+m[3]); err == nil {
+				meta.Date = d.Format("2006-01-02")
+			}
+		}
+	}
+
+	if loc := reDeclarantCaption.FindStringIndex(text); loc != nil {
+		if m := reBIN.FindStringSubmatch(text[loc[0]:]); m != nil {
+			meta.DeclarantBIN = m[1]
+		}
+	}
+	if meta.DeclarantBIN == "" {
+		if m := reBIN.FindStringSubmatch(text); m != nil {
+			meta.DeclarantBIN = m[1]
+		}
+	}
+	return meta
+}
+
+// RenderDeclarationIMEIsCSV writes the extracted IMEI list. A UTF-8 BOM lets Excel open
+// Cyrillic context.
+func RenderDeclarationIMEIsCSV(ext *models.DeclarationExtraction) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write([]string{"imei", "tac", "page", "line", "goods_item", "occurrences", "context", "normalized_from"}); err != nil {
+		return nil, fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, d := range ext.IMEIs {
+		item := ""
+		if d.GoodsItem > 0 {
+			item = strconv.Itoa(d.GoodsItem)
+		}
+		record := []string{d.IMEI, d.IMEI[:8], strconv.Itoa(d.Page), strconv.Itoa(d.Line), item, strconv.Itoa(d.Occurrences), d.Context, d.NormalizedFrom}
+		if err := cw.Write(csvSafeRecord(record)); err != nil {
+			return nil, fmt.Errorf("writing CSV row: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
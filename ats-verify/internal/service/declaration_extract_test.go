@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+func TestSummarizeDeclaration(t *testing.T) {
+	second := "35332811000000"
+	second += string(luhnCheckDigit(second))
+
+	doc := PDFDocument{
+		FileName: "decl.pdf",
+		Pages: []string{
+			"7 Справочный номер 50508010/150324/0001234\n" +
+				"9 Ответственное лицо ИИН/БИН 111111111111\n" +
+				"14 Декларант ТОО Ромашка БИН: 040340001234\n",
+			"31 Телефон IMEI 490154203237518, " + second + "\n" +
+				"повтор 490154203237518, опечатка 490154203237519\n",
+		},
+		Items: []models.DeclarationItem{{Number: 1, Page: 2, IMEIs: []string{second}}},
+	}
+
+	ext := summarizeDeclaration(doc)
+	want := models.DeclarationMetadata{Number: "50508010/150324/0001234", Date: "2024-03-15", DeclarantBIN: "040340001234"}
+	if ext.Metadata != want {
+		t.Errorf("expected metadata %+v, got %+v", want, ext.Metadata)
+	}
+	if len(ext.Pages) != 2 || ext.Pages[1].Number != 2 {
+		t.Fatalf("expected 2 numbered pages, got %+v", ext.Pages)
+	}
+	if ext.TotalIMEIs != 2 || ext.TotalBadLuhn != 1 {
+		t.Fatalf("expected 2 IMEIs and 1 bad check digit, got %d / %d", ext.TotalIMEIs, ext.TotalBadLuhn)
+	}
+	first := ext.IMEIs[0]
+	if first.IMEI != "490154203237518" || first.Page != 2 || first.Line != 1 || first.Occurrences != 2 || !strings.Contains(first.Context, "Телефон IMEI") {
+		t.Errorf("unexpected first IMEI %+v", first)
+	}
+	if ext.IMEIs[1].GoodsItem != 1 {
+		t.Errorf("expected second IMEI in goods item 1, got %+v", ext.IMEIs[1])
+	}
+
+	out, err := RenderDeclarationIMEIsCSV(ext)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("\ufeffimei,tac,page")) || !bytes.Contains(out, []byte("490154203237518,49015420,2,1,,2,")) {
+		t.Errorf("unexpected CSV:\n%s", out)
+	}
+}
+
+func TestSummarizeDeclaration_SplitIMEIs(t *testing.T) {
+	doc := PDFDocument{
+		FileName: "decl.pdf",
+		Pages: []string{
+			"31 Телефон IMEI 35 332811 000000 5\n" +
+				"IMEI 4901542032-37518, повтор 490154203237518\n" +
+				"Вес 12 345678 901234 5 кг\n",
+		},
+		Items: []models.DeclarationItem{{Number: 3, Page: 1, Description: "Телефон IMEI 35 332811 000000 5"}},
+	}
+
+	ext := summarizeDeclaration(doc)
+	if ext.TotalIMEIs != 2 {
+		t.Fatalf("expected 2 IMEIs, got %+v", ext.IMEIs)
+	}
+	first := ext.IMEIs[0]
+	if first.IMEI != "353328110000005" || first.NormalizedFrom != "35 332811 000000 5" || first.GoodsItem != 3 || first.Line != 1 {
+		t.Errorf("unexpected split IMEI %+v", first)
+	}
+	second := ext.IMEIs[1]
+	if second.IMEI != "490154203237518" || second.NormalizedFrom != "4901542032-37518" || second.Occurrences != 2 {
+		t.Errorf("unexpected hyphenated IMEI %+v", second)
+	}
+
+	out, err := RenderDeclarationIMEIsCSV(ext)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Contains(out, []byte(",35 332811 000000 5\n")) {
+		t.Errorf("expected normalized_from in CSV:\n%s", out)
+	}
+}
+
+func TestDeclarationMetadata_LabeledDate(t *testing.T) {
+	meta := declarationMetadata([]string{"Дата регистрации: 01.02.2025"})
+	if meta.Date != "2025-02-01" || meta.Number != "" || meta.DeclarantBIN != "" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ats-verify/internal/models"
+)
+
+// TextFragment is a piece of PDF text with its position on the page
+// (points, origin at the bottom-left corner as in PDF user space).
+type TextFragment struct {
+	Page int
+	X    float64
+	Y    float64
+	S    string
+}
+
+// lineTolerance is the maximum Y distance (points) between fragments of one visual line.
+const lineTolerance = 2.0
+
+var (
+	// reGoodsItemMarker matches the graph 32 item number, e.g. "32 Товар № 3" or "Товар 3".
+	reGoodsItemMarker = regexp.MustCompile(`(?i)товар\s*№?\s*(\d{1,3})(?:[^\d]|$)`)
+	// reGraph31Label matches the printed captions of graph 31 that are not part of the description.
+	reGraph31Label = regexp.MustCompile(`(?i)^\s*31\s*$|грузовые\s+места\s+и\s+описание|маркировка\s+и\s+количество`)
+	// reGraph44Start matches the start of graph 44, which closes the graph 31 block below it.
+	reGraph44Start = regexp.MustCompile(`(?i)^\s*44(?:\s|$)|дополнит\S*\s+информац|предоставл\S*\s+документ`)
+	// reQuantityLabeled matches an explicit quantity, e.g. "Кол-во: 10" or "количество 10".
+	reQuantityLabeled = regexp.MustCompile(`(?i)кол(?:-во|ичество)\.?\s*[:\-]?\s*(\d+)`)
+	// reQuantityUnits matches a quantity with units, e.g. "10 шт" or "(796) 10 ШТ".
+	reQuantityUnits = regexp.MustCompile(`(?i)(\d+)\s*(?:шт|штук|pcs)(?:[^\p{L}]|$)`)
+)
+
+// layoutLine is a visual line of fragments sorted left to right.
+type layoutLine struct {
+	page      int
+	y         float64
+	fragments []TextFragment
+}
+
+// ParseDeclarationItems splits positioned declaration text into goods items.
+// A goods item starts at a graph 32 marker ("Товар № N"); its graph 31 description is the
+// text left of that marker's column, down to the next marker or the start of graph 44.
+// Descriptions continue across pages until the next marker.
+func ParseDeclarationItems(fragments []TextFragment) []models.DeclarationItem {
+	var items []models.DeclarationItem
+	var cur *models.DeclarationItem
+	var boundary float64
+	var description []string
+	open := false
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		cur.Description = strings.Join(description, "\n")
+		cur.DeclaredQuantity = declaredQuantity(cur.Description)
+		cur.IMEIs = uniqueIMEISequences(cur.Description)
+		items = append(items, *cur)
+	}
+
+	for _, line := range groupLayoutLines(fragments) {
+		if number, x, ok := findGoodsItemMarker(line); ok {
+			flush()
+			cur = &models.DeclarationItem{Number: number, Page: line.page}
+			boundary = x
+			description = nil
+			open = true
+			if text := descriptionText(line, boundary); text != "" {
+				description = append(description, text)
+			}
+			continue
+		}
+		if cur == nil || !open {
+			continue
+		}
+
+		text := descriptionText(line, boundary)
+		if text == "" {
+			continue
+		}
+		if reGraph44Start.MatchString(text) {
+			open = false
+			continue
+		}
+		description = append(description, text)
+	}
+	flush()
+
+	return items
+}
+
+// groupLayoutLines orders fragments by page, top to bottom, and merges fragments whose
+// baselines are within lineTolerance into one line.
+func groupLayoutLines(fragments []TextFragment) []layoutLine {
+	sorted := make([]TextFragment, len(fragments))
+	copy(sorted, fragments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Page != sorted[j].Page {
+			return sorted[i].Page < sorted[j].Page
+		}
+		return sorted[i].Y > sorted[j].Y
+	})
+
+	var lines []layoutLine
+	for _, f := range sorted {
+		if strings.TrimSpace(f.S) == "" {
+			continue
+		}
+		n := len(lines)
+		if n > 0 && lines[n-1].page == f.Page && lines[n-1].y-f.Y <= lineTolerance {
+			lines[n-1].fragments = append(lines[n-1].fragments, f)
+			continue
+		}
+		lines = append(lines, layoutLine{page: f.Page, y: f.Y, fragments: []TextFragment{f}})
+	}
+
+	for i := range lines {
+		sort.SliceStable(lines[i].fragments, func(a, b int) bool {
+			return lines[i].fragments[a].X < lines[i].fragments[b].X
+		})
+	}
+	return lines
+}
+
+// findGoodsItemMarker returns the item number and the left X of the graph 32 column.
+// A standalone "32" caption directly before the marker moves the column edge to it.
+func findGoodsItemMarker(line layoutLine) (int, float64, bool) {
+	for i, f := range line.fragments {
+		m := reGoodsItemMarker.FindStringSubmatch(f.S)
+		if m == nil {
+			continue
+		}
+		number, err := strconv.Atoi(m[1])
+		if err != nil || number == 0 {
+			continue
+		}
+		x := f.X
+		if i > 0 && strings.TrimSpace(line.fragments[i-1].S) == "32" {
+			x = line.fragments[i-1].X
+		}
+		return number, x, true
+	}
+	return 0, 0, false
+}
+
+// descriptionText joins the graph 31 fragments of a line (left of boundary),
+// skipping printed captions.
+func descriptionText(line layoutLine, boundary float64) string {
+	var parts []string
+	for _, f := range line.fragments {
+		if f.X >= boundary {
+			break
+		}
+		s := strings.TrimSpace(f.S)
+		if s == "" || reGraph31Label.MatchString(s) {
+			continue
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// declaredQuantity extracts the item quantity from a graph 31 description.
+// An explicit "Кол-во" wins over a number with units; 0 means not found.
+func declaredQuantity(description string) int {
+	for _, re := range []*regexp.Regexp{reQuantityLabeled, reQuantityUnits} {
+		if m := re.FindStringSubmatch(description); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// uniqueIMEISequences returns distinct 15-digit sequences in order of appearance.
+func uniqueIMEISequences(text string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, seq := range regex15Digits.FindAllString(text, -1) {
+		if !seen[seq] {
+			seen[seq] = true
+			out = append(out, seq)
+		}
+	}
+	return out
+}
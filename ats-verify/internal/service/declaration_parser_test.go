@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDeclarationItems(t *testing.T) {
+	// Two goods items: graph 31 on the left (x < 300), graph 32..46 on the right.
+	fragments := []TextFragment{
+		{Page: 1, X: 20, Y: 700, S: "31 Грузовые места и описание товаров"},
+		{Page: 1, X: 300, Y: 700, S: "32"},
+		{Page: 1, X: 320, Y: 700.5, S: "Товар № 1"},
+		{Page: 1, X: 400, Y: 680, S: "33 Код товара 8517130000"},
+		{Page: 1, X: 20, Y: 680, S: "Смартфоны Apple iPhone 15, кол-во: 2"},
+		{Page: 1, X: 20, Y: 665, S: "IMEI 490154203237518"},
+		{Page: 1, X: 20, Y: 650, S: "IMEI 353328110000007"},
+		{Page: 1, X: 420, Y: 650, S: "35 Вес брутто 123456789012345"},
+		{Page: 1, X: 20, Y: 600, S: "44 Дополнит. информация/ Предоставл. документы"},
+		{Page: 1, X: 20, Y: 580, S: "10000000000000000 номер документа 111111111111111"},
+
+		{Page: 2, X: 20, Y: 700, S: "31"},
+		{Page: 2, X: 305, Y: 700, S: "Товар 2"},
+		{Page: 2, X: 20, Y: 680, S: "Телефоны Xiaomi (796) 3 ШТ"},
+		{Page: 2, X: 20, Y: 665, S: "860123450000001"},
+	}
+
+	items := ParseDeclarationItems(fragments)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 goods items, got %d: %+v", len(items), items)
+	}
+
+	first := items[0]
+	if first.Number != 1 || first.Page != 1 || first.DeclaredQuantity != 2 {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if len(first.IMEIs) != 2 || first.IMEIs[0] != "490154203237518" || first.IMEIs[1] != "353328110000007" {
+		t.Errorf("expected 2 IMEIs from graph 31 only, got %v", first.IMEIs)
+	}
+	if strings.Contains(first.Description, "Грузовые места") || strings.Contains(first.Description, "111111111111111") {
+		t.Errorf("expected caption and graph 44 text to be excluded, got %q", first.Description)
+	}
+
+	second := items[1]
+	if second.Number != 2 || second.Page != 2 || second.DeclaredQuantity != 3 || len(second.IMEIs) != 1 {
+		t.Errorf("unexpected second item: %+v", second)
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_GoodsItems(t *testing.T) {
+	svc := NewIMEIService()
+
+	fragments := []TextFragment{
+		{Page: 1, X: 300, Y: 700, S: "Товар № 1"},
+		{Page: 1, X: 20, Y: 680, S: "Смартфоны, кол-во: 2"},
+		{Page: 1, X: 20, Y: 665, S: "490154203237518 353328110000007"},
+		{Page: 1, X: 300, Y: 600, S: "Товар № 2"},
+		{Page: 1, X: 20, Y: 580, S: "Планшеты 5 шт 860123450000001"},
+	}
+	doc := PDFDocument{
+		FileName: "dt.pdf",
+		Pages:    []string{"490154203237518 353328110000007 860123450000001"},
+		Items:    ParseDeclarationItems(fragments),
+	}
+
+	csvContent := "imei1,imei2\n49015420323751,35332811000000\n86012345000000,\n"
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), []PDFDocument{doc})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.Results[0].GoodsItem != 1 || report.Results[2].GoodsItem != 2 {
+		t.Errorf("expected IMEIs attributed to items 1 and 2, got %+v", report.Results)
+	}
+	if len(report.GoodsItems) != 2 {
+		t.Fatalf("expected 2 goods item stats, got %d", len(report.GoodsItems))
+	}
+	if st := report.GoodsItems[0]; st.MatchedDevices != 1 || st.IMEIsInPDF != 2 || st.QuantityMatches == nil || !*st.QuantityMatches {
+		t.Errorf("expected item 1 quantity to match, got %+v", st)
+	}
+	if st := report.GoodsItems[1]; st.QuantityMatches == nil || *st.QuantityMatches {
+		t.Errorf("expected item 2 quantity mismatch, got %+v", st)
+	}
+	if !strings.Contains(report.TextReport, "QUANTITY MISMATCH") || !strings.Contains(report.TextReport, "(dt.pdf, p. 1, item 1)") {
+		t.Errorf("expected goods items in text report, got:\n%s", report.TextReport)
+	}
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+// GenericTrackerConfig describes an HTTP/JSON carrier API declaratively.
+//
+// URL and Body are templates: "{track}" is replaced with the track number, escaped for
+// the URL path, the query string or a JSON string (in Body "{track}" must sit inside
+// quotes). URL and header values may reference environment variables as ${NAME} so API
+// keys stay out of the config file; they are expanded once, when the config is loaded.
+type GenericTrackerConfig struct {
+	Name           string            `json:"name"`
+	Method         string            `json:"method,omitempty"` // GET (default) or POST
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	TrackPattern   string            `json:"track_pattern,omitempty"` // Only tracks matching this regexp are queried
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+
+	// EventsPath selects the event list in the response, e.g. "data.traces" or
+	// "result[0].events". A "[*]" segment flattens nested lists.
+	EventsPath   string             `json:"events_path"`
+	Fields       GenericEventFields `json:"fields"`
+	TimeFormat   []string           `json:"time_formats,omitempty"` // Go layouts, "unix", "unix_ms" or "rfc3339"
+	TimeZone     string             `json:"time_zone,omitempty"`    // IANA zone for layouts without offset (default UTC)
+	StatusMap    map[string]string  `json:"status_map,omitempty"`   // Provider status code → normalized status
+	Descriptions map[string]string  `json:"descriptions,omitempty"` // Provider status code → human-readable text
+}
+
+// GenericEventFields maps event attributes to paths relative to a single event object.
+type GenericEventFields struct {
+	Time        string `json:"time"`
+	Date        string `json:"date,omitempty"` // Optional separate date, joined with time by a space
+	Location    string `json:"location,omitempty"`
+	StatusCode  string `json:"status_code"`
+	Description string `json:"description,omitempty"`
+}
+
+// genericTrackersFile is the top-level layout of the TRACKERS_CONFIG file.
+type genericTrackersFile struct {
+	Trackers []GenericTrackerConfig `json:"trackers"`
+}
+
+// GenericTracker implements Tracker for a carrier described by GenericTrackerConfig.
+type GenericTracker struct {
+	cfg          GenericTrackerConfig
+	client       *http.Client
+	trackPattern *regexp.Regexp
+	location     *time.Location
+	statusMap    map[string]models.TrackingStatus
+}
+
+// NewGenericTracker validates the config and creates a GenericTracker.
+func NewGenericTracker(cfg GenericTrackerConfig) (*GenericTracker, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("tracker name is required")
+	}
+	if !strings.Contains(cfg.URL, "{track}") && !strings.Contains(cfg.Body, "{track}") {
+		return nil, fmt.Errorf("%s: url or body must contain {track}", cfg.Name)
+	}
+	if cfg.EventsPath == "" || cfg.Fields.StatusCode == "" {
+		return nil, fmt.Errorf("%s: events_path and fields.status_code are required", cfg.Name)
+	}
+
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if cfg.Method != http.MethodGet && cfg.Method != http.MethodPost {
+		return nil, fmt.Errorf("%s: unsupported method %s", cfg.Name, cfg.Method)
+	}
+
+	// Expanding before any track number is substituted keeps "$NAME" in a track number
+	// from reading the server's environment.
+	cfg.URL = os.ExpandEnv(cfg.URL)
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	cfg.Headers = headers
+
+	t := &GenericTracker{cfg: cfg, location: time.UTC, statusMap: make(map[string]models.TrackingStatus)}
+
+	if cfg.TrackPattern != "" {
+		re, err := regexp.Compile(cfg.TrackPattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid track_pattern: %w", cfg.Name, err)
+		}
+		t.trackPattern = re
+	}
+
+	if cfg.TimeZone != "" {
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid time_zone: %w", cfg.Name, err)
+		}
+		t.location = loc
+	}
+
+	for code, status := range cfg.StatusMap {
+		ts := models.TrackingStatus(status)
+		if !isKnownTrackingStatus(ts) {
+			return nil, fmt.Errorf("%s: status_map %q: unknown status %q", cfg.Name, code, status)
+		}
+		t.statusMap[strings.ToUpper(code)] = ts
+	}
+
+	timeout := 15 * time.Second
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	t.client = &http.Client{Timeout: timeout}
+
+	return t, nil
+}
+
+// LoadGenericTrackers reads tracker definitions from a JSON config file.
+func LoadGenericTrackers(path string) ([]*GenericTracker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading trackers config: %w", err)
+	}
+
+	var file genericTrackersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing trackers config: %w", err)
+	}
+
+	trackers := make([]*GenericTracker, 0, len(file.Trackers))
+	for _, cfg := range file.Trackers {
+		t, err := NewGenericTracker(cfg)
+		if err != nil {
+			return nil, err
+		}
+		trackers = append(trackers, t)
+	}
+	return trackers, nil
+}
+
+func (g *GenericTracker) Provider() string { return g.cfg.Name }
+
+func (g *GenericTracker) Track(ctx context.Context, trackNumber string) ([]models.TrackingEvent, error) {
+	if g.trackPattern != nil && !g.trackPattern.MatchString(trackNumber) {
+		return nil, nil // not this carrier's track format
+	}
+
+	var body io.Reader
+	if g.cfg.Body != "" {
+		body = bytes.NewBufferString(g.trackBody(trackNumber))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, g.cfg.Method, g.trackURL(trackNumber), body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: creating request: %w", g.cfg.Name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range g.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: request failed: %w", g.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &providerStatusError{Provider: strings.ToLower(g.cfg.Name), StatusCode: resp.StatusCode}
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("%s: parsing json: %w", g.cfg.Name, err)
+	}
+
+	return g.parseEvents(data)
+}
+
+// trackURL substitutes the track number into the URL: path-escaped before the "?",
+// query-escaped after it, so "&" or "=" in a track cannot add query parameters.
+func (g *GenericTracker) trackURL(trackNumber string) string {
+	path, query, hasQuery := strings.Cut(g.cfg.URL, "?")
+	path = strings.ReplaceAll(path, "{track}", url.PathEscape(trackNumber))
+	if !hasQuery {
+		return path
+	}
+	return path + "?" + strings.ReplaceAll(query, "{track}", url.QueryEscape(trackNumber))
+}
+
+// trackBody substitutes the track number into the body as JSON string content.
+func (g *GenericTracker) trackBody(trackNumber string) string {
+	quoted, _ := json.Marshal(trackNumber)
+	return strings.ReplaceAll(g.cfg.Body, "{track}", string(quoted[1:len(quoted)-1]))
+}
+
+// parseEvents maps the decoded response to tracking events.
+func (g *GenericTracker) parseEvents(data interface{}) ([]models.TrackingEvent, error) {
+	nodes, err := jsonPathLookup(data, g.cfg.EventsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: events_path: %w", g.cfg.Name, err)
+	}
+	// A path ending on a list selects its elements.
+	if len(nodes) == 1 {
+		if list, ok := nodes[0].([]interface{}); ok {
+			nodes = list
+		}
+	}
+
+	var events []models.TrackingEvent
+	for _, node := range nodes {
+		code := g.field(node, g.cfg.Fields.StatusCode)
+		if code == "" {
+			continue
+		}
+
+		timeValue := g.field(node, g.cfg.Fields.Time)
+		if date := g.field(node, g.cfg.Fields.Date); date != "" {
+			timeValue = strings.TrimSpace(date + " " + timeValue)
+		}
+
+		description := g.field(node, g.cfg.Fields.Description)
+		if description == "" {
+			description = g.describe(code)
+		}
+
+		events = append(events, models.TrackingEvent{
+			ID:               uuid.New(),
+			StatusCode:       code,
+			NormalizedStatus: g.normalize(code),
+			Description:      description,
+			Location:         g.field(node, g.cfg.Fields.Location),
+			EventTime:        g.parseTime(timeValue),
+			Source:           g.cfg.Name,
+		})
+	}
+	return events, nil
+}
+
+// field resolves a path relative to an event and renders it as a string.
+func (g *GenericTracker) field(node interface{}, path string) string {
+	if path == "" {
+		return ""
+	}
+	values, err := jsonPathLookup(node, path)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	return jsonValueString(values[0])
+}
+
+// normalize maps a provider status code via status_map; comma-separated codes
+// resolve to the first mapped one.
+func (g *GenericTracker) normalize(code string) models.TrackingStatus {
+	if s, ok := g.statusMap[strings.ToUpper(code)]; ok {
+		return s
+	}
+	for _, part := range strings.Split(code, ",") {
+		if s, ok := g.statusMap[strings.ToUpper(strings.TrimSpace(part))]; ok {
+			return s
+		}
+	}
+	return models.TrackingStatusUnknown
+}
+
+func (g *GenericTracker) describe(code string) string {
+	if d, ok := g.cfg.Descriptions[code]; ok {
+		return d
+	}
+	return code
+}
+
+// parseTime tries the configured formats in order; unparseable times stay zero.
+func (g *GenericTracker) parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	formats := g.cfg.TimeFormat
+	if len(formats) == 0 {
+		formats = []string{"rfc3339"}
+	}
+
+	for _, layout := range formats {
+		switch layout {
+		case "unix", "unix_ms":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			if layout == "unix_ms" {
+				return time.UnixMilli(n).UTC()
+			}
+			return time.Unix(n, 0).UTC()
+		case "rfc3339":
+			layout = time.RFC3339
+		}
+		if t, err := time.ParseInLocation(layout, value, g.location); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// isKnownTrackingStatus reports whether s is part of the normalized taxonomy.
+func isKnownTrackingStatus(s models.TrackingStatus) bool {
+	switch s {
+	case models.TrackingStatusAccepted, models.TrackingStatusInTransit, models.TrackingStatusArrivedCountry,
+		models.TrackingStatusCustomsHold, models.TrackingStatusCustomsReleased, models.TrackingStatusReadyForPickup,
+		models.TrackingStatusDelivered, models.TrackingStatusReturned, models.TrackingStatusUnknown:
+		return true
+	}
+	return false
+}
+
+// jsonPathLookup resolves a dot-separated path such as "data.items[0].events[*]"
+// against a decoded JSON value. "$" or an empty path selects the value itself.
+// "[*]" fans out over list elements, so the result may contain several values.
+func jsonPathLookup(value interface{}, path string) ([]interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := []interface{}{value}
+	if path == "" {
+		return current, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		key := segment
+		var indexes []string
+		if i := strings.Index(segment, "["); i >= 0 {
+			key = segment[:i]
+			rest := segment[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if !strings.HasPrefix(rest, "[") || end < 0 {
+					return nil, fmt.Errorf("malformed segment %q", segment)
+				}
+				indexes = append(indexes, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		var next []interface{}
+		for _, v := range current {
+			if key != "" {
+				obj, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if v, ok = obj[key]; !ok {
+					continue
+				}
+				next = append(next, v)
+			} else {
+				next = append(next, v)
+			}
+		}
+
+		for _, idx := range indexes {
+			var indexed []interface{}
+			for _, v := range next {
+				list, ok := v.([]interface{})
+				if !ok {
+					continue
+				}
+				if idx == "*" {
+					indexed = append(indexed, list...)
+					continue
+				}
+				n, err := strconv.Atoi(idx)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in %q", idx, segment)
+				}
+				if n < 0 {
+					n += len(list)
+				}
+				if n >= 0 && n < len(list) {
+					indexed = append(indexed, list[n])
+				}
+			}
+			next = indexed
+		}
+
+		current = next
+	}
+	return current, nil
+}
+
+// jsonValueString renders a scalar JSON value; lists of scalars are joined with commas.
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(val)
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			if s := jsonValueString(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ",")
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
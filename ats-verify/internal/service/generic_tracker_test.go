@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+const genericStubResponse = `{
+  "data": [{
+    "waybill": "LP00123456789",
+    "traces": [
+      {"ts": 1709280000000, "code": "ACCEPT", "place": {"city": "Shenzhen"}, "text": "Accepted by carrier"},
+      {"ts": 1709452800000, "code": "CUSTOMS_HOLD", "place": {"city": "Almaty"}},
+      {"ts": 1709539200000, "code": "SIGNED", "place": {"city": "Almaty"}, "text": "Delivered"},
+      {"ts": 1709539200000, "code": ""}
+    ]
+  }]
+}`
+
+func TestGenericTracker_MapsStubResponse(t *testing.T) {
+	var gotPath, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte(genericStubResponse))
+	}))
+	defer srv.Close()
+
+	t.Setenv("STUB_API_KEY", "secret")
+	tracker, err := NewGenericTracker(GenericTrackerConfig{
+		Name:         "StubCarrier",
+		URL:          srv.URL + "/track/{track}",
+		Headers:      map[string]string{"X-Api-Key": "${STUB_API_KEY}"},
+		TrackPattern: `^LP\d{11}$`,
+		EventsPath:   "data[0].traces",
+		Fields: GenericEventFields{
+			Time:        "ts",
+			Location:    "place.city",
+			StatusCode:  "code",
+			Description: "text",
+		},
+		TimeFormat:   []string{"unix_ms"},
+		StatusMap:    map[string]string{"accept": "accepted", "CUSTOMS_HOLD": "customs_hold", "SIGNED": "delivered"},
+		Descriptions: map[string]string{"CUSTOMS_HOLD": "Held by customs"},
+	})
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	events, err := tracker.Track(context.Background(), "LP00123456789")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotPath != "/track/LP00123456789" || gotKey != "secret" {
+		t.Errorf("unexpected request: path=%q key=%q", gotPath, gotKey)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events (empty status skipped), got %d", len(events))
+	}
+	if events[0].NormalizedStatus != models.TrackingStatusAccepted || events[0].Location != "Shenzhen" || events[0].Source != "StubCarrier" {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Description != "Held by customs" || events[1].NormalizedStatus != models.TrackingStatusCustomsHold {
+		t.Errorf("expected description fallback and customs status, got %+v", events[1])
+	}
+	if got := events[2].EventTime.Format("2006-01-02"); got != "2024-03-04" {
+		t.Errorf("expected unix_ms time to parse, got %s", got)
+	}
+
+	// Tracks of other carriers are skipped without a request.
+	gotPath = ""
+	if events, err := tracker.Track(context.Background(), "1234567890"); err != nil || events != nil || gotPath != "" {
+		t.Errorf("expected foreign track to be skipped, got %v / %v / %q", events, err, gotPath)
+	}
+}
+
+func TestGenericTracker_ReportsProviderStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tracker, err := NewGenericTracker(GenericTrackerConfig{
+		Name:       "StubCarrier",
+		URL:        srv.URL + "?id={track}",
+		EventsPath: "events",
+		Fields:     GenericEventFields{StatusCode: "code"},
+	})
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	_, err = tracker.Track(context.Background(), "X1")
+	if !isProviderFailure(err) {
+		t.Errorf("expected 503 to count as provider failure, got %v", err)
+	}
+}
+
+func TestGenericTracker_EscapesTrackNumber(t *testing.T) {
+	var gotPath, gotQuery, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotQuery, gotBody = r.URL.Path, r.URL.RawQuery, string(body)
+		w.Write([]byte(`{"events":[]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("JWT_SECRET", "s3cr3t")
+	tracker, err := NewGenericTracker(GenericTrackerConfig{
+		Name:       "StubCarrier",
+		Method:     http.MethodPost,
+		URL:        srv.URL + "/track/{track}?id={track}&lang=ru",
+		Body:       `{"number":"{track}"}`,
+		EventsPath: "events",
+		Fields:     GenericEventFields{StatusCode: "code"},
+	})
+	if err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	track := `A"1&id=2/$JWT_SECRET`
+	if _, err := tracker.Track(context.Background(), track); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(gotPath+gotQuery+gotBody, "s3cr3t") {
+		t.Errorf("track number expanded an environment variable: %s?%s %s", gotPath, gotQuery, gotBody)
+	}
+	query, _ := url.ParseQuery(gotQuery)
+	if gotPath != "/track/"+track || len(query) != 2 || query.Get("id") != track || query.Get("lang") != "ru" {
+		t.Errorf("unexpected URL: path=%q query=%q", gotPath, gotQuery)
+	}
+	var body struct{ Number string }
+	if err := json.Unmarshal([]byte(gotBody), &body); err != nil || body.Number != track {
+		t.Errorf("expected JSON body with the track number, got %q (%v)", gotBody, err)
+	}
+}
+
+func TestJSONPathLookup(t *testing.T) {
+	data := map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{"items": []interface{}{"a", "b"}},
+			map[string]interface{}{"items": []interface{}{"c"}},
+		},
+	}
+
+	got, err := jsonPathLookup(data, "groups[*].items[*]")
+	if err != nil || len(got) != 3 {
+		t.Fatalf("expected 3 flattened values, got %v (%v)", got, err)
+	}
+	if got, _ := jsonPathLookup(data, "groups[-1].items[0]"); len(got) != 1 || got[0] != "c" {
+		t.Errorf("expected negative index to select last group, got %v", got)
+	}
+	if _, err := jsonPathLookup(data, "groups[x"); err == nil {
+		t.Error("expected malformed path to fail")
+	}
+}
+
+func TestLoadGenericTrackers_ExampleConfig(t *testing.T) {
+	trackers, err := LoadGenericTrackers(filepath.Join("..", "..", "configs", "trackers.example.json"))
+	if err != nil {
+		t.Fatalf("expected example config to load, got %v", err)
+	}
+	if len(trackers) != 3 {
+		t.Errorf("expected 3 example trackers, got %d", len(trackers))
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(bad, []byte(`{"trackers":[{"name":"X","url":"http://x/{track}","events_path":"e","fields":{"status_code":"c"},"status_map":{"A":"lost"}}]}`), 0o600)
+	if _, err := LoadGenericTrackers(bad); err == nil || !strings.Contains(err.Error(), "unknown status") {
+		t.Errorf("expected unknown normalized status to be rejected, got %v", err)
+	}
+}
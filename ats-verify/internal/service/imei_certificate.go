@@ -0,0 +1,329 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// Certificate format settings.
+const (
+	certificateVersion   = 1
+	certificateAlgorithm = "Ed25519"
+)
+
+// ErrInvalidCertificateFormat is returned for an unknown certificate format.
+var ErrInvalidCertificateFormat = errors.New("invalid certificate format, expected json or pdf")
+
+// LoadCertificateKey reads the Ed25519 signing key from a PEM (PKCS #8) file. A missing
+// file is created with a new key. The key must persist across restarts, or issued
+// certificates stop validating, so there is no in-memory fallback.
+func LoadCertificateKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, errors.New("certificate key path is not set (CERT_KEY_PATH)")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("creating certificate key directory: %w", err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+			return nil, fmt.Errorf("writing certificate key: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate key %s is not PEM encoded", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("certificate key %s is not an Ed25519 key", path)
+	}
+	return key, nil
+}
+
+// IMEICertificateService issues signed certificates for stored IMEI verifications and
+// validates presented ones. Certificates are not stored: they are rebuilt from the
+// verification record, which makes any edit of the certificate or the record visible.
+type IMEICertificateService struct {
+	reportRepo *repository.AnalysisReportRepository
+	userRepo   *repository.UserRepository
+	key        ed25519.PrivateKey
+	keyID      string
+	fontPath   string
+}
+
+// NewIMEICertificateService creates a new IMEICertificateService.
+func NewIMEICertificateService(reportRepo *repository.AnalysisReportRepository, userRepo *repository.UserRepository, key ed25519.PrivateKey, fontPath string) *IMEICertificateService {
+	pub := key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &IMEICertificateService{
+		reportRepo: reportRepo,
+		userRepo:   userRepo,
+		key:        key,
+		keyID:      hex.EncodeToString(sum[:8]),
+		fontPath:   fontPath,
+	}
+}
+
+// Issue builds and signs the certificate of a stored verification.
+func (s *IMEICertificateService) Issue(ctx context.Context, stored *models.AnalysisReport) (*models.SignedIMEICertificate, error) {
+	cert, err := s.certificateFor(stored, usernameOf(ctx, s.userRepo, stored.UserID))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(cert)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SignedIMEICertificate{
+		Certificate: *cert,
+		Algorithm:   certificateAlgorithm,
+		Signature:   base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)),
+		PublicKey:   base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+	}, nil
+}
+
+// certificateFor derives the certificate content from a stored verification.
+func (s *IMEICertificateService) certificateFor(stored *models.AnalysisReport, username string) (*models.IMEICertificate, error) {
+	if len(stored.Results) == 0 {
+		return nil, ErrNoStoredResults
+	}
+	var report models.IMEIVerificationReport
+	if err := json.Unmarshal(stored.Results, &report); err != nil {
+		return nil, fmt.Errorf("decoding stored results: %w", err)
+	}
+	mode := report.Mode
+	if mode == "" {
+		mode = ModeIMEI // Verifications stored before serial mode
+	}
+
+	inputs := []models.IMEICertificateInput{{Kind: "csv", FileName: stored.CSVFileName, SHA256: stored.CSVSHA256}}
+	for i, name := range stored.PDFFileNames {
+		hash := ""
+		if i < len(stored.PDFSHA256) {
+			hash = stored.PDFSHA256[i]
+		}
+		inputs = append(inputs, models.IMEICertificateInput{Kind: "pdf", FileName: name, SHA256: hash})
+	}
+
+	results := sha256.Sum256(stored.Results)
+	return &models.IMEICertificate{
+		Version:    certificateVersion,
+		ReportID:   stored.ID,
+		VerifiedAt: stored.CreatedAt.UTC().Truncate(time.Second),
+		Verifier:   models.IMEICertificateUser{UserID: stored.UserID, Username: username},
+		Inputs:     inputs,
+		Summary: models.IMEICertificateSummary{
+			Mode:               mode,
+			Total:              report.TotalIMEIs,
+			Found:              report.TotalFound,
+			Missing:            report.TotalMissing,
+			Invalid:            report.TotalInvalid,
+			Unexpected:         report.TotalUnexpected,
+			Duplicates:         report.TotalDuplicates,
+			PreviouslyDeclared: report.TotalPreviouslyDeclared,
+			PairMismatches:     report.TotalPairMismatches,
+		},
+		ResultsSHA256: hex.EncodeToString(results[:]),
+		KeyID:         s.keyID,
+	}, nil
+}
+
+// Verify checks the signature of a presented certificate and compares it with the stored
+// verification. The record is only looked up for correctly signed certificates.
+func (s *IMEICertificateService) Verify(ctx context.Context, presented *models.SignedIMEICertificate) (*models.IMEICertificateCheck, error) {
+	check := &models.IMEICertificateCheck{}
+	cert := presented.Certificate
+
+	if reason := s.checkSignature(presented); reason != "" {
+		check.Reason = reason
+		return check, nil
+	}
+	check.SignatureValid = true
+	check.ReportID = &cert.ReportID
+	check.VerifiedAt = &cert.VerifiedAt
+
+	stored, err := s.reportRepo.GetByID(ctx, cert.ReportID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.ReportType != ReportTypeIMEIVerification {
+		check.Reason = "verification record not found"
+		return check, nil
+	}
+	check.RecordFound = true
+
+	// The username may change after issue; the user ID is compared instead.
+	expected, err := s.certificateFor(stored, cert.Verifier.Username)
+	if errors.Is(err, ErrNoStoredResults) {
+		check.Reason = "verification record has no stored results"
+		return check, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	check.Mismatches = certificateMismatches(&cert, expected)
+	if len(check.Mismatches) > 0 {
+		check.Reason = "certificate does not match the stored verification"
+		return check, nil
+	}
+	check.Valid = true
+	return check, nil
+}
+
+// checkSignature returns why a certificate's signature is not valid, or "".
+func (s *IMEICertificateService) checkSignature(presented *models.SignedIMEICertificate) string {
+	if presented.Algorithm != certificateAlgorithm {
+		return fmt.Sprintf("unsupported signature algorithm %q", presented.Algorithm)
+	}
+	if presented.Certificate.KeyID != s.keyID {
+		return "certificate was not signed with this server's key"
+	}
+	sig, err := base64.StdEncoding.DecodeString(presented.Signature)
+	if err != nil {
+		return "signature is not valid base64"
+	}
+	payload, err := json.Marshal(presented.Certificate)
+	if err != nil || !ed25519.Verify(s.key.Public().(ed25519.PublicKey), payload, sig) {
+		return "signature does not match the certificate content"
+	}
+	return ""
+}
+
+// certificateMismatches lists the fields of a presented certificate that differ from
+// the one rebuilt from the stored verification.
+func certificateMismatches(presented, expected *models.IMEICertificate) []string {
+	var out []string
+	if presented.Version != expected.Version {
+		out = append(out, "version")
+	}
+	if !presented.VerifiedAt.Equal(expected.VerifiedAt) {
+		out = append(out, "verified_at")
+	}
+	if presented.Verifier.UserID != expected.Verifier.UserID {
+		out = append(out, "verifier")
+	}
+	if !slices.Equal(presented.Inputs, expected.Inputs) {
+		out = append(out, "inputs")
+	}
+	if presented.Summary != expected.Summary {
+		out = append(out, "summary")
+	}
+	if presented.ResultsSHA256 != expected.ResultsSHA256 {
+		out = append(out, "results_sha256")
+	}
+	return out
+}
+
+// Render returns the certificate as JSON or as a printable PDF with the JSON attached.
+func (s *IMEICertificateService) Render(signed *models.SignedIMEICertificate, format string) (*ExportedFile, error) {
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("imei-certificate-%s", signed.Certificate.ReportID)
+
+	switch format {
+	case "", "json":
+		return &ExportedFile{FileName: name + ".json", ContentType: "application/json", Data: data}, nil
+	case "pdf":
+		pdf, err := renderCertificatePDF(signed, data, name+".json", s.fontPath)
+		if err != nil {
+			return nil, fmt.Errorf("rendering pdf certificate: %w", err)
+		}
+		return &ExportedFile{FileName: name + ".pdf", ContentType: "application/pdf", Data: pdf}, nil
+	}
+	return nil, ErrInvalidCertificateFormat
+}
+
+// renderCertificatePDF prints the certificate fields and the signed JSON, which is also
+// embedded as a file attachment for submission to the validation endpoint.
+func renderCertificatePDF(signed *models.SignedIMEICertificate, signedJSON []byte, jsonName, fontPath string) ([]byte, error) {
+	cert := signed.Certificate
+	pdf, family, text, err := newReportPDF("IMEI verification certificate "+cert.ReportID.String(), fontPath)
+	if err != nil {
+		return nil, err
+	}
+	pdf.SetAttachments([]fpdf.Attachment{{Content: signedJSON, Filename: jsonName, Description: "Signed certificate"}})
+	pdf.AddPage()
+
+	pdf.SetFont(family, "B", 16)
+	pdf.CellFormat(0, 10, text("IMEI VERIFICATION CERTIFICATE"), "", 1, "C", false, 0, "")
+	pdf.Ln(3)
+
+	row := func(label, value string) {
+		pdf.SetFont(family, "B", 9)
+		pdf.CellFormat(50, 5, text(label), "", 0, "L", false, 0, "")
+		pdf.SetFont(family, "", 9)
+		pdf.MultiCell(0, 5, text(value), "", "L", false)
+	}
+	sum := cert.Summary
+	row("Report ID", cert.ReportID.String())
+	row("Verified at (UTC)", cert.VerifiedAt.Format("2006-01-02 15:04:05"))
+	row("Verifier", fmt.Sprintf("%s (%s)", cert.Verifier.Username, cert.Verifier.UserID))
+	row("Mode", sum.Mode)
+	row("Results", fmt.Sprintf("%d processed, %d found, %d missing, %d invalid, %d unexpected in PDF, %d duplicates, %d previously declared, %d dual-SIM pair mismatches",
+		sum.Total, sum.Found, sum.Missing, sum.Invalid, sum.Unexpected, sum.Duplicates, sum.PreviouslyDeclared, sum.PairMismatches))
+	for _, in := range cert.Inputs {
+		row("Input "+in.Kind, in.FileName+"\nSHA-256 "+in.SHA256)
+	}
+	row("Results SHA-256", cert.ResultsSHA256)
+	row("Signature", fmt.Sprintf("%s, key %s\n%s", signed.Algorithm, cert.KeyID, signed.Signature))
+
+	pdf.Ln(4)
+	pdf.SetFont(family, "", 8)
+	pdf.MultiCell(0, 4, text("To check this certificate, submit the attached "+jsonName+
+		" (or the JSON below) to POST /api/v1/verify-certificate."), "", "L", false)
+	pdf.Ln(2)
+	// The built-in Courier has no Cyrillic; file names are transliterated in print only.
+	if family == pdfFontFamily {
+		pdf.SetFont(family, "", 6)
+	} else {
+		pdf.SetFont("Courier", "", 6)
+	}
+	pdf.MultiCell(0, 3, text(string(signedJSON)), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
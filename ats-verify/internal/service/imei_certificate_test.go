@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+func testCertificateKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testStoredVerification(t *testing.T) *models.AnalysisReport {
+	t.Helper()
+	results, err := json.Marshal(&models.IMEIVerificationReport{Mode: ModeIMEI, TotalIMEIs: 3, TotalFound: 2, TotalMissing: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.AnalysisReport{
+		ID:           uuid.New(),
+		UserID:       uuid.New(),
+		ReportType:   ReportTypeIMEIVerification,
+		CreatedAt:    time.Date(2026, 3, 1, 9, 30, 15, 123456000, time.UTC),
+		CSVFileName:  "devices.csv",
+		CSVSHA256:    "aa",
+		PDFFileNames: []string{"decl.pdf"},
+		PDFSHA256:    []string{"bb"},
+		Results:      results,
+	}
+}
+
+func TestIMEICertificateService_SignatureAndRecordChecks(t *testing.T) {
+	svc := NewIMEICertificateService(nil, nil, testCertificateKey(t), "")
+	stored := testStoredVerification(t)
+
+	signed, err := svc.Issue(context.Background(), stored)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if signed.Certificate.Summary.Found != 2 || len(signed.Certificate.Inputs) != 2 {
+		t.Fatalf("unexpected certificate %+v", signed.Certificate)
+	}
+
+	// A certificate survives a JSON round trip through a third party.
+	data, _ := json.Marshal(signed)
+	var presented models.SignedIMEICertificate
+	if err := json.Unmarshal(data, &presented); err != nil {
+		t.Fatal(err)
+	}
+	if reason := svc.checkSignature(&presented); reason != "" {
+		t.Fatalf("expected valid signature, got %q", reason)
+	}
+
+	forged := presented
+	forged.Certificate.Summary.Missing = 0
+	if reason := svc.checkSignature(&forged); reason == "" {
+		t.Error("expected edited summary to break the signature")
+	}
+	if reason := NewIMEICertificateService(nil, nil, testCertificateKey(t), "").checkSignature(&presented); reason == "" {
+		t.Error("expected certificate from another key to be rejected")
+	}
+
+	// An edited record no longer matches the certificate issued for it.
+	stored.Results = bytes.Replace(stored.Results, []byte(`"total_found":2`), []byte(`"total_found":3`), 1)
+	expected, err := svc.certificateFor(stored, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := certificateMismatches(&presented.Certificate, expected); !slices.Equal(got, []string{"summary", "results_sha256"}) {
+		t.Errorf("expected summary and results mismatches, got %v", got)
+	}
+}
+
+func TestLoadCertificateKey_CreatesAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "cert.pem")
+	created, err := LoadCertificateKey(path)
+	if err != nil {
+		t.Fatalf("expected key to be created, got %v", err)
+	}
+	loaded, err := LoadCertificateKey(path)
+	if err != nil {
+		t.Fatalf("expected key to be reloaded, got %v", err)
+	}
+	if !created.Equal(loaded) {
+		t.Error("expected the same key after reload")
+	}
+
+	if _, err := LoadCertificateKey(""); err == nil {
+		t.Error("expected an error for an empty key path")
+	}
+}
+
+func TestIMEICertificateService_RenderPDF(t *testing.T) {
+	svc := NewIMEICertificateService(nil, nil, testCertificateKey(t), "")
+	signed, err := svc.Issue(context.Background(), testStoredVerification(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := svc.Render(signed, "pdf")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.HasPrefix(file.Data, []byte("%PDF")) || file.ContentType != "application/pdf" {
+		t.Errorf("unexpected PDF output %s", file.ContentType)
+	}
+}
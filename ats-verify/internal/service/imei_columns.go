@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"ats-verify/internal/models"
+)
+
+// IMEI column detection settings.
+const (
+	imeiSampleRows     = 200 // Data rows inspected to detect IMEI columns by content
+	minIMEIColumnShare = 0.8 // Share of 14-15 digit values that makes a column an IMEI column
+)
+
+// Methods of IMEI column detection.
+const (
+	DetectedByHeader  = "header"
+	DetectedByContent = "content"
+)
+
+// imeiHeaderNames lists normalized IMEI column headers (see normalizeIMEIHeader), in
+// English, Russian and Kazakh. A trailing 1-4 (IMEI 1, ИМЕЙ2) is stripped before lookup.
+var imeiHeaderNames = map[string]bool{
+	"imei":       true,
+	"imeinumber": true,
+	"imeiномер":  true,
+	"номерimei":  true,
+	"imeiкод":    true,
+	"кодimei":    true,
+	"imeiкоды":   true, // kk: IMEI коды
+	"imeiнөмірі": true, // kk: IMEI нөмірі
+	"имей":       true,
+	"имеи":       true,
+	"имейкод":    true,
+	"имейкоды":   true,
+	"имейнөмірі": true,
+}
+
+// normalizeIMEIHeader lowercases a header and drops spaces, '_', '-', '№', '#', ':', '.'
+// and quotes, so "IMEI 1", "imei_1" and "Imei-1" compare equal.
+func normalizeIMEIHeader(h string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '_', '-', '№', '#', ':', '.', '"', '\'', '\ufeff':
+			return -1
+		}
+		return r
+	}, strings.ToLower(h))
+}
+
+// isIMEIHeader reports whether a CSV header names an IMEI column.
+func isIMEIHeader(h string) bool {
+	name := normalizeIMEIHeader(h)
+	if n := len(name); n > 0 && name[n-1] >= '1' && name[n-1] <= '4' {
+		name = name[:n-1]
+	}
+	return imeiHeaderNames[name]
+}
+
+// isIMEILike reports whether a CSV value is a 14- or 15-digit number.
+func isIMEILike(v string) bool {
+	if len(v) != 14 && len(v) != 15 {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if !isASCIIDigit(v[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// detectIMEIColumns picks IMEI columns by header name or, failing that, by content: a
+// column is an IMEI column when at least minIMEIColumnShare of its non-empty sampled
+// values are 14-15 digit numbers. Columns in skip (declared device data) are ignored.
+// Returns column index → display name and the explanation for the report.
+func detectIMEIColumns(header []string, sample [][]string, skip map[int]bool) (map[int]string, []models.IMEIDetectedColumn) {
+	colMap := make(map[int]string)
+	var detected []models.IMEIDetectedColumn
+	used := make(map[string]bool)
+
+	for i, h := range header {
+		if skip[i] {
+			continue
+		}
+
+		nonEmpty, imeiLike := 0, 0
+		for _, record := range sample {
+			if i >= len(record) {
+				continue
+			}
+			v := strings.TrimSpace(record[i])
+			if v == "" {
+				continue
+			}
+			nonEmpty++
+			if isIMEILike(v) {
+				imeiLike++
+			}
+		}
+		share := 0.0
+		if nonEmpty > 0 {
+			share = float64(imeiLike) / float64(nonEmpty)
+		}
+
+		name := strings.TrimSpace(h)
+		col := models.IMEIDetectedColumn{Position: i + 1, IMEIShare: share, Sampled: nonEmpty}
+		switch {
+		case isIMEIHeader(name):
+			col.Method = DetectedByHeader
+			col.Reason = fmt.Sprintf("header %q is an IMEI column name", name)
+		case nonEmpty > 0 && share >= minIMEIColumnShare:
+			col.Method = DetectedByContent
+			col.Reason = fmt.Sprintf("%d of %d sampled values (%.0f%%) are 14-15 digit numbers", imeiLike, nonEmpty, share*100)
+		default:
+			continue
+		}
+		switch {
+		case name == "":
+			name = fmt.Sprintf("Column %d", i+1)
+		case used[name]:
+			name = fmt.Sprintf("%s (column %d)", name, i+1)
+		}
+		used[name] = true
+		col.Column = name
+		colMap[i] = name
+		detected = append(detected, col)
+	}
+	return colMap, detected
+}
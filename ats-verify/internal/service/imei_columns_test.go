@@ -0,0 +1,55 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsIMEIHeader(t *testing.T) {
+	for h, want := range map[string]bool{
+		"Imei1":       true,
+		"IMEI 2":      true,
+		"imei_number": true,
+		"ИМЕЙ":        true,
+		"Имей-1":      true,
+		"IMEI коды":   true,
+		"IMEI нөмірі": true,
+		"IMEI5":       false,
+		"Модель":      false,
+	} {
+		if got := isIMEIHeader(h); got != want {
+			t.Errorf("%q: expected %v, got %v", h, want, got)
+		}
+	}
+}
+
+func TestIMEIServiceAnalyze_DetectsColumnsBySemicolonHeaderAndContent(t *testing.T) {
+	svc := NewIMEIService()
+
+	// BOM, ';' separator, a Russian IMEI header and IMEIs under an unrelated header.
+	csvContent := "\ufeffИМЕЙ 1;Модель;Серийный\n" +
+		"49015420323751;Galaxy A15;353328110000005\n" +
+		"86012345000000;Galaxy A15;35332811000001\n"
+	pdfText := "IMEI 490154203237518, 353328110000005"
+
+	report, err := svc.Analyze(strings.NewReader(csvContent), pdfText)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(report.DetectedColumns) != 2 {
+		t.Fatalf("expected 2 detected columns, got %+v", report.DetectedColumns)
+	}
+	byHeader, byContent := report.DetectedColumns[0], report.DetectedColumns[1]
+	if byHeader.Column != "ИМЕЙ 1" || byHeader.Method != DetectedByHeader {
+		t.Errorf("unexpected header detection %+v", byHeader)
+	}
+	if byContent.Column != "Серийный" || byContent.Method != DetectedByContent || byContent.Position != 3 || byContent.IMEIShare != 1 {
+		t.Errorf("unexpected content detection %+v", byContent)
+	}
+	if report.TotalIMEIs != 4 || report.TotalFound != 2 {
+		t.Errorf("expected 4 processed / 2 found, got %d / %d", report.TotalIMEIs, report.TotalFound)
+	}
+	if !strings.Contains(report.TextReport, "--- DETECTED IMEI COLUMNS ---") {
+		t.Errorf("expected detected columns in text report, got:\n%s", report.TextReport)
+	}
+}
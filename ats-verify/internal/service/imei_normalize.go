@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDigitGap is the longest separator (in runes) allowed between the pieces of a split
+// number: spaces, line breaks and hyphens, e.g. "35 123456 789012 3" or a wrapped line.
+const maxDigitGap = 3
+
+// splitDigitRun is an IMEI-length number rebuilt from digit pieces that PDF extraction
+// separated by whitespace, hyphens or line breaks.
+type splitDigitRun struct {
+	digits  string
+	offsets []int // Byte offset in the source text of every digit
+}
+
+// source returns the original text the run was rebuilt from, with whitespace collapsed.
+func (r splitDigitRun) source(text string) string {
+	start, end := r.offsets[0], r.offsets[len(r.offsets)-1]+1
+	return strings.Join(strings.Fields(text[start:end]), " ")
+}
+
+// findSplitDigitRuns returns the 14- or 15-digit numbers formed by two or more
+// consecutive digit pieces of text joined across short separators. Unbroken digit runs
+// are not returned; the raw text already covers them.
+func findSplitDigitRuns(text string) []splitDigitRun {
+	type piece struct{ start, end int }
+
+	var out []splitDigitRun
+	var chain []piece
+	flush := func() {
+		// From every piece, take the longest span of up to 15 digits.
+		for i := range chain {
+			total, last := 0, -1
+			for j := i; j < len(chain); j++ {
+				total += chain[j].end - chain[j].start
+				if total > 15 {
+					break
+				}
+				if j > i && total >= 14 {
+					last = j
+				}
+			}
+			if last < 0 {
+				continue
+			}
+			var run splitDigitRun
+			var sb strings.Builder
+			for _, p := range chain[i : last+1] {
+				sb.WriteString(text[p.start:p.end])
+				for k := p.start; k < p.end; k++ {
+					run.offsets = append(run.offsets, k)
+				}
+			}
+			run.digits = sb.String()
+			out = append(out, run)
+		}
+		chain = chain[:0]
+	}
+
+	for i := 0; i < len(text); {
+		if !isASCIIDigit(text[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && isASCIIDigit(text[i]) {
+			i++
+		}
+		if n := len(chain); n > 0 && !isDigitSeparator(text[chain[n-1].end:start]) {
+			flush()
+		}
+		chain = append(chain, piece{start: start, end: i})
+	}
+	flush()
+	return out
+}
+
+// isDigitSeparator reports whether s may separate two pieces of one split number.
+func isDigitSeparator(s string) bool {
+	if s == "" || utf8.RuneCountInString(s) > maxDigitGap {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsSpace(r) && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
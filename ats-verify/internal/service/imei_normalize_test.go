@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindSplitDigitRuns(t *testing.T) {
+	text := "IMEI: 35 112345 678901 0; кол-во 1 шт"
+	runs := findSplitDigitRuns(text)
+	if len(runs) != 1 || runs[0].digits != "351123456789010" {
+		t.Fatalf("expected one rebuilt IMEI, got %+v", runs)
+	}
+	if got := runs[0].source(text); got != "35 112345 678901 0" {
+		t.Errorf("unexpected source %q", got)
+	}
+	if start := runs[0].offsets[0]; text[start:start+2] != "35" {
+		t.Errorf("offset does not map back to the original text: %d", start)
+	}
+
+	wrapped := "Смартфон 3511234567-\n89010 (1 шт)"
+	if runs := findSplitDigitRuns(wrapped); len(runs) != 1 || runs[0].digits != "351123456789010" {
+		t.Errorf("expected the wrapped IMEI to be rebuilt, got %+v", runs)
+	}
+
+	if runs := findSplitDigitRuns("1 2 3, 351123456789010"); len(runs) != 0 {
+		t.Errorf("expected no split runs, got %+v", runs)
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_NormalizedMatch(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "imei1\n35112345678901\n49015420323751\n"
+	docs := []PDFDocument{{
+		FileName: "decl.pdf",
+		Pages:    []string{"IMEI 35 112345 678901 0", "IMEI 490154203237518 and 4901542 0323751 8"},
+	}}
+
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.TotalFound != 2 || report.TotalNormalized != 1 {
+		t.Fatalf("expected 2 found / 1 normalized, got %d / %d", report.TotalFound, report.TotalNormalized)
+	}
+
+	split := report.Results[0]
+	if !split.Normalized || split.NormalizedFrom != "35 112345 678901 0" || split.MatchedIMEI != "351123456789010" || split.SourcePage != 1 {
+		t.Errorf("unexpected normalized match %+v", split)
+	}
+	if split.PDFCheckDigitOK == nil || !*split.PDFCheckDigitOK {
+		t.Errorf("expected the rebuilt check digit to be verified, got %+v", split)
+	}
+	if raw := report.Results[1]; raw.Normalized {
+		t.Errorf("expected the unbroken occurrence to win, got %+v", raw)
+	}
+	if !strings.Contains(report.TextReport, "--- MATCHED IN SPLIT NUMBERS (CHECK MANUALLY) ---") {
+		t.Errorf("expected split matches in text report, got:\n%s", report.TextReport)
+	}
+}
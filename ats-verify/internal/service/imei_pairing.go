@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"ats-verify/internal/models"
+)
+
+// tacPairing tells whether two different TACs belong to one device and names a TAC's
+// device for reasons. The zero value knows no pairs and no devices.
+type tacPairing struct {
+	paired   func(a, b string) bool
+	describe func(tac string) string
+}
+
+// tacPairKey orders two TACs the way tac_pairs stores them.
+func tacPairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// markDualSIMPairs checks that the IMEI columns of every CSV row belong to one device:
+// a dual-SIM phone's Imei1 and Imei2 share a TAC, or use two TACs known as a pair.
+// All valid values of a failing row are flagged, and the totals are recomputed.
+func markDualSIMPairs(report *models.IMEIVerificationReport, pairing tacPairing) {
+	report.TotalPairMismatches = 0
+	columnMismatches := make(map[string]int)
+
+	for start := 0; start < len(report.Results); {
+		end := start + 1
+		for end < len(report.Results) && report.Results[end].CSVLine == report.Results[start].CSVLine {
+			end++
+		}
+		row := report.Results[start:end]
+		start = end
+
+		var base *models.IMEIMatchResult
+		var conflicts []string
+		for i := range row {
+			res := &row[i]
+			res.PairMismatch, res.PairReason = false, ""
+			if len(res.IMEI14) != 14 {
+				continue
+			}
+			if base == nil {
+				base = res
+				continue
+			}
+			a, b := base.IMEI14[:8], res.IMEI14[:8]
+			if a == b || (pairing.paired != nil && pairing.paired(a, b)) {
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s TAC %s%s", res.Column, b, pairing.deviceLabel(b)))
+		}
+		if len(conflicts) == 0 {
+			continue
+		}
+
+		reason := fmt.Sprintf("%s TAC %s%s vs %s", base.Column, base.IMEI14[:8], pairing.deviceLabel(base.IMEI14[:8]), strings.Join(conflicts, ", "))
+		for i := range row {
+			if len(row[i].IMEI14) == 14 {
+				row[i].PairMismatch = true
+				row[i].PairReason = reason
+				columnMismatches[row[i].Column]++
+			}
+		}
+		report.TotalPairMismatches++
+	}
+
+	for i := range report.ColumnStats {
+		report.ColumnStats[i].PairMismatches = columnMismatches[report.ColumnStats[i].Column]
+	}
+}
+
+// deviceLabel returns " (manufacturer model)" for a known TAC, or "".
+func (p tacPairing) deviceLabel(tac string) string {
+	if p.describe == nil {
+		return ""
+	}
+	if d := p.describe(tac); d != "" {
+		return " (" + d + ")"
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+func TestIMEIServiceAnalyze_FlagsDualSIMPairMismatches(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "imei1,imei2\n" +
+		"35332811000000,35332811000001\n" + // same TAC
+		"35332811000002,86012345000000\n" + // different devices
+		"35332811000003,\n" // single SIM
+	report, err := svc.Analyze(strings.NewReader(csvContent), "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.TotalPairMismatches != 1 {
+		t.Fatalf("expected 1 mismatched row, got %d", report.TotalPairMismatches)
+	}
+	for _, res := range report.Results {
+		if res.PairMismatch != (res.CSVLine == 3) {
+			t.Errorf("line %d %s: unexpected pair_mismatch=%v", res.CSVLine, res.Column, res.PairMismatch)
+		}
+	}
+	if got := report.Results[2].PairReason; got != "imei1 TAC 35332811 vs imei2 TAC 86012345" {
+		t.Errorf("unexpected reason %q", got)
+	}
+	for _, stat := range report.ColumnStats {
+		if stat.PairMismatches != 1 {
+			t.Errorf("%s: expected 1 pair mismatch, got %d", stat.Column, stat.PairMismatches)
+		}
+	}
+	if !strings.Contains(report.TextReport, "--- DUAL-SIM PAIR MISMATCHES ---") {
+		t.Errorf("expected pair mismatches in text report, got:\n%s", report.TextReport)
+	}
+}
+
+func TestKnownTACPairing(t *testing.T) {
+	devices := map[string]models.TACEntry{
+		"35332811": {TAC: "35332811", Manufacturer: "Samsung", Model: "Galaxy A15"},
+		"35332812": {TAC: "35332812", Manufacturer: "SAMSUNG", Model: "galaxy a15"},
+		"86012345": {TAC: "86012345", Manufacturer: "Apple", Model: "iPhone 13"},
+	}
+	pairing := knownTACPairing(devices, []models.TACPair{{TACA: "35100000", TACB: "35200000"}})
+
+	report := &models.IMEIVerificationReport{
+		ColumnStats: []models.IMEIColumnStats{{Column: "Imei1"}, {Column: "Imei2"}},
+		Results: []models.IMEIMatchResult{
+			{CSVLine: 2, Column: "Imei1", IMEI14: "35332811000000"},
+			{CSVLine: 2, Column: "Imei2", IMEI14: "35332812000000"}, // same model
+			{CSVLine: 3, Column: "Imei1", IMEI14: "35200000000000"},
+			{CSVLine: 3, Column: "Imei2", IMEI14: "35100000000000"}, // listed pair
+			{CSVLine: 4, Column: "Imei1", IMEI14: "35332811000001"},
+			{CSVLine: 4, Column: "Imei2", IMEI14: "86012345000001"},
+		},
+	}
+	markDualSIMPairs(report, pairing)
+
+	if report.TotalPairMismatches != 1 || !report.Results[4].PairMismatch || report.Results[0].PairMismatch || report.Results[2].PairMismatch {
+		t.Fatalf("expected only line 4 to be flagged, got %+v", report.Results)
+	}
+	if want := "Imei1 TAC 35332811 (Samsung Galaxy A15) vs Imei2 TAC 86012345 (Apple iPhone 13)"; report.Results[4].PairReason != want {
+		t.Errorf("expected reason %q, got %q", want, report.Results[4].PairReason)
+	}
+}
+
+func TestParseTACPairsCSV(t *testing.T) {
+	pairs, err := ParseTACPairsCSV(strings.NewReader("tac1;tac2;note\n35332812;35332811;Galaxy A15\n35332811;35332812;dup\n1234;35332811;bad\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].TACA != "35332811" || pairs[0].TACB != "35332812" || pairs[0].Note != "Galaxy A15" {
+		t.Errorf("unexpected pairs %+v", pairs)
+	}
+}
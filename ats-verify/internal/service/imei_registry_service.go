@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// ReportTypeIMEIVerification is the analysis_reports.report_type of IMEI verifications.
+const ReportTypeIMEIVerification = "imei_verification"
+
+// maxDeclarationName is the length of analysis_reports.input_file_name,
+// analysis_reports.csv_file_name and imei_registry.declaration.
+const maxDeclarationName = 255
+
+// imeiRegistryStore is the registry storage used by IMEIRegistryService.
+type imeiRegistryStore interface {
+	HistoryByIMEIs(ctx context.Context, imeis []string) (map[string][]models.IMEIRegistryEntry, error)
+	Register(ctx context.Context, entries []models.IMEIRegistryEntry) error
+}
+
+// imeiReportStore is the verification storage used by IMEIRegistryService.
+type imeiReportStore interface {
+	Create(ctx context.Context, report *models.AnalysisReport) (uuid.UUID, error)
+	List(ctx context.Context, f repository.AnalysisReportFilter) ([]models.AnalysisReport, int, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.AnalysisReport, error)
+}
+
+// IMEIRegistryService stores IMEI verifications and keeps the registry of verified
+// devices, so that devices declared again in a later declaration are flagged.
+type IMEIRegistryService struct {
+	registryRepo imeiRegistryStore
+	reportRepo   imeiReportStore
+}
+
+// NewIMEIRegistryService creates a new IMEIRegistryService.
+func NewIMEIRegistryService(registryRepo *repository.IMEIRegistryRepository, reportRepo *repository.AnalysisReportRepository) *IMEIRegistryService {
+	return &IMEIRegistryService{registryRepo: registryRepo, reportRepo: reportRepo}
+}
+
+// IMEIVerificationInput describes the uploaded files of a verification.
+type IMEIVerificationInput struct {
+	CSVFileName  string
+	CSVSHA256    string // Hex SHA-256 of the CSV
+	PDFFileNames []string
+	PDFSHA256    []string // Hex SHA-256 of each PDF, same order as PDFFileNames
+}
+
+// Record flags devices registered by earlier verifications of other declarations, stores
+// the verification with its input hashes and full results, and registers the devices
+// found in the declaration. The text report is regenerated.
+func (s *IMEIRegistryService) Record(ctx context.Context, userID uuid.UUID, role models.UserRole, in IMEIVerificationInput, report *models.IMEIVerificationReport) error {
+	declaration := declarationName(in.PDFFileNames)
+
+	var imeis []string
+	seen := make(map[string]bool)
+	for _, res := range report.Results {
+		if res.IMEI14 != "" && !seen[res.IMEI14] {
+			seen[res.IMEI14] = true
+			imeis = append(imeis, res.IMEI14)
+		}
+	}
+
+	// Look up history before registering this verification so it does not match itself.
+	history, err := s.registryRepo.HistoryByIMEIs(ctx, imeis)
+	if err != nil {
+		return err
+	}
+	markPreviouslyDeclared(report, latestOtherDeclarations(history, in.PDFSHA256, declaration), userID, role)
+
+	reportID := uuid.New()
+	report.ReportID = reportID.String()
+	report.TextReport = generateTextReport(report)
+
+	results, err := json.Marshal(report)
+	if err == nil {
+		_, err = s.reportRepo.Create(ctx, &models.AnalysisReport{
+			ID:            reportID,
+			UserID:        userID,
+			ReportType:    ReportTypeIMEIVerification,
+			InputFileName: declaration,
+			ResultSummary: imeiReportSummary(report),
+			CSVFileName:   cutFileName(in.CSVFileName),
+			CSVSHA256:     in.CSVSHA256,
+			PDFFileNames:  in.PDFFileNames,
+			PDFSHA256:     in.PDFSHA256,
+			Results:       results,
+		})
+	}
+	if err != nil {
+		report.ReportID = ""
+		report.TextReport = generateTextReport(report)
+		return fmt.Errorf("saving verification: %w", err)
+	}
+
+	return s.registryRepo.Register(ctx, registryEntries(report, reportID, userID, declaration))
+}
+
+// ListIMEIReportsFilter holds filter parameters for listing stored verifications.
+type ListIMEIReportsFilter struct {
+	UserID *uuid.UUID
+	From   *time.Time // Inclusive
+	To     *time.Time // Exclusive
+	Page   int
+	Limit  int
+}
+
+// ListIMEIReportsResponse is the paginated list of stored verifications.
+type ListIMEIReportsResponse struct {
+	Reports []models.AnalysisReport `json:"reports"`
+	Total   int                     `json:"total"`
+	Page    int                     `json:"page"`
+	Limit   int                     `json:"limit"`
+}
+
+// canViewAllIMEIReports reports whether a role sees verifications of other users.
+func canViewAllIMEIReports(role models.UserRole) bool {
+	return role == models.RoleAdmin || role == models.RoleCustoms
+}
+
+// ListReports returns stored verifications visible to the viewer, newest first.
+// Admins and customs see all (optionally filtered by user); others only their own.
+func (s *IMEIRegistryService) ListReports(ctx context.Context, viewerID uuid.UUID, viewerRole models.UserRole, f ListIMEIReportsFilter) (*ListIMEIReportsResponse, error) {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	if !canViewAllIMEIReports(viewerRole) {
+		f.UserID = &viewerID
+	}
+
+	reports, total, err := s.reportRepo.List(ctx, repository.AnalysisReportFilter{
+		ReportType: ReportTypeIMEIVerification,
+		UserID:     f.UserID,
+		From:       f.From,
+		To:         f.To,
+		Page:       f.Page,
+		Limit:      f.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing IMEI reports: %w", err)
+	}
+
+	return &ListIMEIReportsResponse{
+		Reports: reports,
+		Total:   total,
+		Page:    f.Page,
+		Limit:   f.Limit,
+	}, nil
+}
+
+// GetReport returns a stored verification with its full results, or nil if it does not
+// exist or is not visible to the viewer.
+func (s *IMEIRegistryService) GetReport(ctx context.Context, viewerID uuid.UUID, viewerRole models.UserRole, id uuid.UUID) (*models.AnalysisReport, error) {
+	report, err := s.reportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil || report.ReportType != ReportTypeIMEIVerification {
+		return nil, nil
+	}
+	if !canViewAllIMEIReports(viewerRole) && report.UserID != viewerID {
+		return nil, nil
+	}
+	return report, nil
+}
+
+// latestOtherDeclarations returns the most recent registry entry of each IMEI that comes
+// from another declaration. Entries of verifications with the same PDFs or the same
+// declaration name are re-checks of this declaration and are skipped.
+func latestOtherDeclarations(history map[string][]models.IMEIRegistryEntry, pdfSHA256 []string, declaration string) map[string]models.IMEIRegistryEntry {
+	latest := make(map[string]models.IMEIRegistryEntry)
+	for imei, entries := range history {
+		for _, e := range entries {
+			if (declaration != "" && e.Declaration == declaration) || samePDFs(e.PDFSHA256, pdfSHA256) {
+				continue
+			}
+			latest[imei] = e
+			break
+		}
+	}
+	return latest
+}
+
+// samePDFs reports whether two verifications read the same set of PDFs.
+func samePDFs(a, b []string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// markPreviouslyDeclared sets PreviousDeclaration on results whose IMEI is in the registry.
+// The registry is shared by all users, so the earlier report and its file names are only
+// linked when the viewer could open that report (see GetReport); otherwise only the date
+// is shown.
+func markPreviouslyDeclared(report *models.IMEIVerificationReport, previous map[string]models.IMEIRegistryEntry, viewerID uuid.UUID, viewerRole models.UserRole) {
+	report.TotalPreviouslyDeclared = 0
+	for i := range report.Results {
+		res := &report.Results[i]
+		entry, ok := previous[res.IMEI14]
+		if res.IMEI14 == "" || !ok {
+			continue
+		}
+		prev := &models.IMEIPreviousDeclaration{VerifiedAt: entry.VerifiedAt}
+		if canViewAllIMEIReports(viewerRole) || entry.UserID == viewerID {
+			reportID := entry.ReportID
+			prev.ReportID = &reportID
+			prev.Declaration = entry.Declaration
+		}
+		res.PreviousDeclaration = prev
+		report.TotalPreviouslyDeclared++
+	}
+}
+
+// previousDeclarationName names an earlier verification for reports.
+func previousDeclarationName(prev *models.IMEIPreviousDeclaration) string {
+	if prev.Declaration == "" {
+		return "another user's declaration"
+	}
+	return prev.Declaration
+}
+
+// registryEntries returns one entry per distinct IMEI found in the declaration. Serial
+// numbers are not registered.
+func registryEntries(report *models.IMEIVerificationReport, reportID, userID uuid.UUID, declaration string) []models.IMEIRegistryEntry {
+	var entries []models.IMEIRegistryEntry
+	seen := make(map[string]bool)
+	for _, res := range report.Results {
+		if !res.Found || res.IMEI14 == "" || seen[res.IMEI14] {
+			continue
+		}
+		seen[res.IMEI14] = true
+		entries = append(entries, models.IMEIRegistryEntry{
+			IMEI14:      res.IMEI14,
+			ReportID:    reportID,
+			UserID:      userID,
+			Declaration: declaration,
+			CSVLine:     res.CSVLine,
+		})
+	}
+	return entries
+}
+
+// imeiReportSummary is the result_summary stored with the verification.
+func imeiReportSummary(report *models.IMEIVerificationReport) map[string]interface{} {
+	return map[string]interface{}{
+		"total":               report.TotalIMEIs,
+		"found":               report.TotalFound,
+		"missing":             report.TotalMissing,
+		"invalid":             report.TotalInvalid,
+		"unexpected":          report.TotalUnexpected,
+		"duplicates":          report.TotalDuplicates,
+		"previously_declared": report.TotalPreviouslyDeclared,
+		"pair_mismatches":     report.TotalPairMismatches,
+	}
+}
+
+// declarationName joins PDF file names, cut to the column length.
+func declarationName(fileNames []string) string {
+	return cutFileName(strings.Join(fileNames, ", "))
+}
+
+// cutFileName cuts a file name to the column length.
+func cutFileName(name string) string {
+	if r := []rune(name); len(r) > maxDeclarationName {
+		name = string(r[:maxDeclarationName])
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+func TestIMEIServiceAnalyze_FlagsDuplicatesInCSV(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "imei1,imei2\n49015420323751,490154203237518\n35332811000000,49015420323751\n"
+	report, err := svc.Analyze(strings.NewReader(csvContent), "490154203237518")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.TotalDuplicates != 2 {
+		t.Fatalf("expected 2 duplicates, got %d", report.TotalDuplicates)
+	}
+	dup := report.Results[1]
+	if dup.DuplicateOfLine != 2 || dup.DuplicateOfColumn != "imei1" {
+		t.Errorf("expected row 2 imei2 to duplicate row 2 imei1, got %+v", dup)
+	}
+	if report.Results[0].DuplicateOfLine != 0 {
+		t.Errorf("expected the first occurrence not to be flagged, got %+v", report.Results[0])
+	}
+	if !strings.Contains(report.TextReport, "--- DUPLICATE IMEIs IN CSV ---") {
+		t.Errorf("expected duplicates section in text report, got:\n%s", report.TextReport)
+	}
+}
+
+func TestMarkPreviouslyDeclaredAndRegistryEntries(t *testing.T) {
+	earlier := uuid.New()
+	verifiedAt := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	report := &models.IMEIVerificationReport{Results: []models.IMEIMatchResult{
+		{CSVLine: 2, IMEI14: "49015420323751", Found: true},
+		{CSVLine: 3, IMEI14: "49015420323751", Found: true},
+		{CSVLine: 4, IMEI14: "35332811000000", Found: false},
+		{CSVLine: 5, RawValue: "abc"},
+	}}
+
+	owner := uuid.New()
+	previous := map[string]models.IMEIRegistryEntry{
+		"49015420323751": {IMEI14: "49015420323751", ReportID: earlier, UserID: owner, Declaration: "dt-aug.pdf", VerifiedAt: verifiedAt},
+	}
+
+	markPreviouslyDeclared(report, previous, owner, models.RolePaidUser)
+	if report.TotalPreviouslyDeclared != 2 {
+		t.Fatalf("expected 2 previously declared results, got %d", report.TotalPreviouslyDeclared)
+	}
+	if prev := report.Results[0].PreviousDeclaration; prev == nil || prev.ReportID == nil || *prev.ReportID != earlier || prev.Declaration != "dt-aug.pdf" {
+		t.Errorf("expected link to the earlier verification, got %+v", prev)
+	}
+
+	// Another user only learns that the device was declared, not where.
+	markPreviouslyDeclared(report, previous, uuid.New(), models.RolePaidUser)
+	if prev := report.Results[0].PreviousDeclaration; prev == nil || prev.ReportID != nil || prev.Declaration != "" || !prev.VerifiedAt.Equal(verifiedAt) {
+		t.Errorf("expected the earlier report to be hidden from another user, got %+v", prev)
+	}
+	markPreviouslyDeclared(report, previous, uuid.New(), models.RoleCustoms)
+	if prev := report.Results[0].PreviousDeclaration; prev == nil || prev.ReportID == nil {
+		t.Errorf("expected customs to see the earlier report, got %+v", prev)
+	}
+
+	entries := registryEntries(report, uuid.New(), uuid.New(), "dt-sep.pdf")
+	if len(entries) != 1 || entries[0].IMEI14 != "49015420323751" || entries[0].CSVLine != 2 {
+		t.Errorf("expected one entry for the found IMEI, got %+v", entries)
+	}
+}
+
+// memoryIMEIStore keeps verifications and registry entries in memory.
+type memoryIMEIStore struct {
+	reports []models.AnalysisReport
+	entries []models.IMEIRegistryEntry // Oldest first
+}
+
+func (m *memoryIMEIStore) Create(ctx context.Context, report *models.AnalysisReport) (uuid.UUID, error) {
+	m.reports = append(m.reports, *report)
+	return report.ID, nil
+}
+
+func (m *memoryIMEIStore) List(ctx context.Context, f repository.AnalysisReportFilter) ([]models.AnalysisReport, int, error) {
+	return m.reports, len(m.reports), nil
+}
+
+func (m *memoryIMEIStore) GetByID(ctx context.Context, id uuid.UUID) (*models.AnalysisReport, error) {
+	for i := range m.reports {
+		if m.reports[i].ID == id {
+			return &m.reports[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryIMEIStore) HistoryByIMEIs(ctx context.Context, imeis []string) (map[string][]models.IMEIRegistryEntry, error) {
+	history := make(map[string][]models.IMEIRegistryEntry)
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		e := m.entries[i]
+		if !slices.Contains(imeis, e.IMEI14) {
+			continue
+		}
+		if report, _ := m.GetByID(ctx, e.ReportID); report != nil {
+			e.PDFSHA256 = report.PDFSHA256
+		}
+		history[e.IMEI14] = append(history[e.IMEI14], e)
+	}
+	return history, nil
+}
+
+func (m *memoryIMEIStore) Register(ctx context.Context, entries []models.IMEIRegistryEntry) error {
+	for _, e := range entries {
+		e.VerifiedAt = time.Now()
+		m.entries = append(m.entries, e)
+	}
+	return nil
+}
+
+func TestIMEIRegistryService_RecheckIsNotPreviouslyDeclared(t *testing.T) {
+	store := &memoryIMEIStore{}
+	svc := &IMEIRegistryService{registryRepo: store, reportRepo: store}
+	user := uuid.New()
+	in := IMEIVerificationInput{
+		CSVFileName:  "devices.csv",
+		PDFFileNames: []string{"dt.pdf"},
+		PDFSHA256:    []string{strings.Repeat("b", 64)},
+	}
+	newReport := func() *models.IMEIVerificationReport {
+		return &models.IMEIVerificationReport{Results: []models.IMEIMatchResult{{CSVLine: 2, IMEI14: "49015420323751", Found: true}}}
+	}
+
+	for run := 1; run <= 2; run++ {
+		report := newReport()
+		if err := svc.Record(context.Background(), user, models.RolePaidUser, in, report); err != nil {
+			t.Fatalf("run %d: expected no error, got %v", run, err)
+		}
+		if report.TotalPreviouslyDeclared != 0 {
+			t.Errorf("run %d: expected a re-check not to flag its own devices, got %+v", run, report.Results[0].PreviousDeclaration)
+		}
+	}
+
+	// Another declaration with the same device is flagged.
+	other := in
+	other.PDFFileNames = []string{"dt-2.pdf"}
+	other.PDFSHA256 = []string{strings.Repeat("c", 64)}
+	report := newReport()
+	if err := svc.Record(context.Background(), user, models.RolePaidUser, other, report); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if prev := report.Results[0].PreviousDeclaration; report.TotalPreviouslyDeclared != 1 || prev == nil || prev.Declaration != "dt.pdf" {
+		t.Errorf("expected the device to be flagged against dt.pdf, got %+v", prev)
+	}
+}
+
+func TestIMEIRegistryService_CutsLongCSVFileName(t *testing.T) {
+	store := &memoryIMEIStore{}
+	svc := &IMEIRegistryService{registryRepo: store, reportRepo: store}
+	in := IMEIVerificationInput{CSVFileName: strings.Repeat("ж", 300) + ".csv", PDFFileNames: []string{"dt.pdf"}}
+
+	report := &models.IMEIVerificationReport{}
+	if err := svc.Record(context.Background(), uuid.New(), models.RolePaidUser, in, report); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(store.reports) != 1 || len([]rune(store.reports[0].CSVFileName)) != maxDeclarationName {
+		t.Errorf("expected the CSV name cut to %d runes, got %+v", maxDeclarationName, store.reports)
+	}
+}
+
+func TestIMEIReportSummary_PairMismatches(t *testing.T) {
+	summary := imeiReportSummary(&models.IMEIVerificationReport{TotalIMEIs: 4, TotalPairMismatches: 1})
+	if summary["pair_mismatches"] != 1 || summary["total"] != 4 {
+		t.Errorf("expected pair mismatches in the summary, got %v", summary)
+	}
+}
+
+func TestDeclarationName(t *testing.T) {
+	if got := declarationName([]string{"part1.pdf", "part2.pdf"}); got != "part1.pdf, part2.pdf" {
+		t.Errorf("unexpected name %q", got)
+	}
+	if got := declarationName([]string{strings.Repeat("д", 300)}); len([]rune(got)) != maxDeclarationName {
+		t.Errorf("expected name cut to %d runes, got %d", maxDeclarationName, len([]rune(got)))
+	}
+}
+
+func TestCanViewAllIMEIReports(t *testing.T) {
+	for role, want := range map[models.UserRole]bool{
+		models.RoleAdmin:    true,
+		models.RoleCustoms:  true,
+		models.RolePaidUser: false,
+	} {
+		if got := canViewAllIMEIReports(role); got != want {
+			t.Errorf("%s: expected %v, got %v", role, want, got)
+		}
+	}
+}
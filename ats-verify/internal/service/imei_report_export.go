@@ -0,0 +1,562 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// ExportFormat is a downloadable format of a stored IMEI verification.
+type ExportFormat string
+
+const (
+	ExportXLSX ExportFormat = "xlsx"
+	ExportCSV  ExportFormat = "csv"
+	ExportPDF  ExportFormat = "pdf"
+)
+
+// ErrInvalidExportFormat is returned for an unknown export format.
+var ErrInvalidExportFormat = errors.New("invalid export format, expected xlsx, csv or pdf")
+
+// ErrNoStoredResults is returned for verifications saved before full results were stored.
+var ErrNoStoredResults = errors.New("report has no stored results to export")
+
+// ParseExportFormat validates the format query parameter.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(s)); f {
+	case ExportXLSX, ExportCSV, ExportPDF:
+		return f, nil
+	}
+	return "", ErrInvalidExportFormat
+}
+
+// ExportedFile is a rendered report ready to be served as a download.
+type ExportedFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// IMEIReportExporter renders stored IMEI verifications for official case files.
+type IMEIReportExporter struct {
+	userRepo *repository.UserRepository
+	fontPath string // TTF with Cyrillic glyphs for PDF; text is transliterated when empty
+}
+
+// NewIMEIReportExporter creates a new IMEIReportExporter.
+func NewIMEIReportExporter(userRepo *repository.UserRepository, fontPath string) *IMEIReportExporter {
+	return &IMEIReportExporter{userRepo: userRepo, fontPath: fontPath}
+}
+
+// imeiExport is everything a rendered report shows.
+type imeiExport struct {
+	stored      *models.AnalysisReport
+	report      *models.IMEIVerificationReport
+	officer     string // User who ran the verification
+	generatedAt time.Time
+}
+
+// Export renders a stored verification in the requested format.
+func (e *IMEIReportExporter) Export(ctx context.Context, stored *models.AnalysisReport, format ExportFormat) (*ExportedFile, error) {
+	if len(stored.Results) == 0 {
+		return nil, ErrNoStoredResults
+	}
+	var report models.IMEIVerificationReport
+	if err := json.Unmarshal(stored.Results, &report); err != nil {
+		return nil, fmt.Errorf("decoding stored results: %w", err)
+	}
+
+	doc := imeiExport{
+		stored:      stored,
+		report:      &report,
+		officer:     e.officerName(ctx, stored.UserID),
+		generatedAt: time.Now().UTC(),
+	}
+
+	var (
+		data        []byte
+		contentType string
+		err         error
+	)
+	switch format {
+	case ExportXLSX:
+		data, err = renderIMEIXLSX(doc)
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportCSV:
+		data, err = renderIMEICSV(doc)
+		contentType = "text/csv; charset=utf-8"
+	case ExportPDF:
+		data, err = renderIMEIPDF(doc, e.fontPath)
+		contentType = "application/pdf"
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s report: %w", format, err)
+	}
+
+	return &ExportedFile{
+		FileName:    fmt.Sprintf("imei-report-%s.%s", stored.ID, format),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// officerName returns the username of the verifying officer, or the user ID if the user is gone.
+func (e *IMEIReportExporter) officerName(ctx context.Context, userID uuid.UUID) string {
+	return usernameOf(ctx, e.userRepo, userID)
+}
+
+// usernameOf returns the username of a user, or the user ID if the user is gone.
+func usernameOf(ctx context.Context, userRepo *repository.UserRepository, userID uuid.UUID) string {
+	if userRepo != nil {
+		if u, err := userRepo.GetByID(ctx, userID); err == nil && u != nil {
+			return u.Username
+		}
+	}
+	return userID.String()
+}
+
+// summaryRows returns label/value pairs of the report header and totals.
+func (d imeiExport) summaryRows() [][2]string {
+	r := d.report
+	rows := [][2]string{
+		{"Report ID", d.stored.ID.String()},
+		{"Verified at (UTC)", d.stored.CreatedAt.UTC().Format("2006-01-02 15:04:05")},
+		{"Officer", d.officer},
+		{"Generated at (UTC)", d.generatedAt.Format("2006-01-02 15:04:05")},
+	}
+	if r.Mode == ModeSerial {
+		rows = append(rows,
+			[2]string{"Mode", "Serial numbers"},
+			[2]string{"Match rule", r.MatchRule},
+			[2]string{"Total serial numbers processed", strconv.Itoa(r.TotalIMEIs)},
+		)
+	} else {
+		rows = append(rows, [2]string{"Total IMEIs processed", strconv.Itoa(r.TotalIMEIs)})
+	}
+	rows = append(rows, [][2]string{
+		{"Total Found in PDF", strconv.Itoa(r.TotalFound)},
+		{"Total Missing", strconv.Itoa(r.TotalMissing)},
+		{"Total Invalid", strconv.Itoa(r.TotalInvalid)},
+		{"Dual-SIM pair mismatches (rows)", strconv.Itoa(r.TotalPairMismatches)},
+		{"Matched in split numbers", strconv.Itoa(r.TotalNormalized)},
+		{"Total Unexpected in PDF", strconv.Itoa(r.TotalUnexpected)},
+		{"Duplicates in CSV", strconv.Itoa(r.TotalDuplicates)},
+		{"Previously declared", strconv.Itoa(r.TotalPreviouslyDeclared)},
+	}...)
+	if r.TotalTACResolved > 0 {
+		rows = append(rows,
+			[2]string{"Devices identified by TAC", strconv.Itoa(r.TotalTACResolved)},
+			[2]string{"Brand mismatches", strconv.Itoa(r.TotalBrandMismatches)},
+		)
+	}
+	return rows
+}
+
+// inputFiles returns the uploaded files with their SHA-256 hashes, CSV first.
+func (d imeiExport) inputFiles() [][2]string {
+	files := [][2]string{{d.stored.CSVFileName, d.stored.CSVSHA256}}
+	for i, name := range d.stored.PDFFileNames {
+		hash := ""
+		if i < len(d.stored.PDFSHA256) {
+			hash = d.stored.PDFSHA256[i]
+		}
+		files = append(files, [2]string{name, hash})
+	}
+	return files
+}
+
+// resultStatus classifies a line for exports.
+func resultStatus(res models.IMEIMatchResult) string {
+	switch {
+	case res.Found:
+		return "FOUND"
+	case res.Validity == models.IMEINonNumeric || res.Validity == models.IMEIWrongLength:
+		return "INVALID"
+	default:
+		return "MISSING"
+	}
+}
+
+// imeiLineHeader is the header of line-by-line exports.
+var imeiLineHeader = []string{
+	"csv_line", "column", "raw_value", "validity", "status", "expected_imei", "matched_imei", "normalized_from",
+	"source_file", "source_page", "goods_item", "duplicate_of", "previous_declaration", "pair_mismatch",
+	"declared_brand", "declared_model", "track_number", "device_manufacturer", "device_model", "brand_mismatch",
+}
+
+// imeiLineRecord renders one result in the order of imeiLineHeader.
+func imeiLineRecord(res models.IMEIMatchResult) []string {
+	itoa := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	duplicateOf := ""
+	if res.DuplicateOfLine > 0 {
+		duplicateOf = fmt.Sprintf("line %d, %s", res.DuplicateOfLine, res.DuplicateOfColumn)
+	}
+	previous := ""
+	if p := res.PreviousDeclaration; p != nil {
+		previous = fmt.Sprintf("%s (%s)", previousDeclarationName(p), p.VerifiedAt.UTC().Format("2006-01-02"))
+	}
+	mismatch := ""
+	if res.BrandMismatch {
+		mismatch = res.MismatchReason
+	}
+	return []string{
+		strconv.Itoa(res.CSVLine), res.Column, res.RawValue, string(res.Validity), resultStatus(res),
+		res.ExpectedIMEI, res.MatchedIMEI, res.NormalizedFrom, res.SourceFile, itoa(res.SourcePage), itoa(res.GoodsItem),
+		duplicateOf, previous, res.PairReason, res.DeclaredBrand, res.DeclaredModel, res.TrackNumber,
+		res.DeviceManufacturer, res.DeviceModel, mismatch,
+	}
+}
+
+// csvSafeRecord guards user-supplied cells against formula injection: a cell that
+// starts with =, +, -, @, tab or CR is prefixed with ' so spreadsheets show it as text.
+func csvSafeRecord(record []string) []string {
+	for i, v := range record {
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			record[i] = "'" + v
+		}
+	}
+	return record
+}
+
+// renderIMEICSV writes the line-by-line results. A UTF-8 BOM lets Excel open Cyrillic values.
+func renderIMEICSV(d imeiExport) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write(imeiLineHeader); err != nil {
+		return nil, fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, res := range d.report.Results {
+		if err := cw.Write(csvSafeRecord(imeiLineRecord(res))); err != nil {
+			return nil, fmt.Errorf("writing CSV row: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// XLSX sheet names.
+const (
+	sheetSummary = "Summary"
+	sheetColumns = "Columns"
+	sheetLines   = "Lines"
+)
+
+// renderIMEIXLSX builds a workbook with summary, per-column and line-by-line sheets.
+// Missing lines are highlighted red, invalid ones yellow.
+func renderIMEIXLSX(d imeiExport) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	missing, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}})
+	if err != nil {
+		return nil, err
+	}
+	invalid, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFEB9C"}}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Summary
+	if err := f.SetSheetName("Sheet1", sheetSummary); err != nil {
+		return nil, err
+	}
+	row := 1
+	setRow := func(sheet string, values ...interface{}) error {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		row++
+		return f.SetSheetRow(sheet, cell, &values)
+	}
+	if err := setRow(sheetSummary, "IMEI VERIFICATION REPORT"); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(sheetSummary, "A1", "A1", bold)
+	row++
+	for _, r := range d.summaryRows() {
+		if err := setRow(sheetSummary, r[0], r[1]); err != nil {
+			return nil, err
+		}
+	}
+	row++
+	if err := setRow(sheetSummary, "Input file", "SHA-256"); err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("A%d", row-1)
+	f.SetCellStyle(sheetSummary, header, fmt.Sprintf("B%d", row-1), bold)
+	for _, file := range d.inputFiles() {
+		if err := setRow(sheetSummary, file[0], file[1]); err != nil {
+			return nil, err
+		}
+	}
+	f.SetColWidth(sheetSummary, "A", "A", 30)
+	f.SetColWidth(sheetSummary, "B", "B", 70)
+
+	// Columns
+	if _, err := f.NewSheet(sheetColumns); err != nil {
+		return nil, err
+	}
+	row = 1
+	if err := setRow(sheetColumns, "Column", "Total", "Found", "Missing", "Invalid", "Pair mismatches"); err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(sheetColumns, "A1", "F1", bold)
+	for _, stat := range d.report.ColumnStats {
+		if err := setRow(sheetColumns, stat.Column, stat.Total, stat.Found, stat.Missing, stat.Invalid, stat.PairMismatches); err != nil {
+			return nil, err
+		}
+	}
+
+	// Lines
+	if _, err := f.NewSheet(sheetLines); err != nil {
+		return nil, err
+	}
+	row = 1
+	headerRow := make([]interface{}, len(imeiLineHeader))
+	for i, h := range imeiLineHeader {
+		headerRow[i] = h
+	}
+	if err := setRow(sheetLines, headerRow...); err != nil {
+		return nil, err
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(imeiLineHeader))
+	f.SetCellStyle(sheetLines, "A1", lastCol+"1", bold)
+	for _, res := range d.report.Results {
+		record := imeiLineRecord(res)
+		values := make([]interface{}, len(record))
+		for i, v := range record {
+			values[i] = v
+		}
+		values[0] = res.CSVLine
+		if err := setRow(sheetLines, values...); err != nil {
+			return nil, err
+		}
+		style := 0
+		switch resultStatus(res) {
+		case "MISSING":
+			style = missing
+		case "INVALID":
+			style = invalid
+		}
+		if style != 0 {
+			f.SetCellStyle(sheetLines, fmt.Sprintf("A%d", row-1), fmt.Sprintf("%s%d", lastCol, row-1), style)
+		}
+	}
+	f.SetColWidth(sheetLines, "B", "H", 18)
+	if err := f.SetPanes(sheetLines, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfFontFamily is the family name of the configured Unicode font.
+const pdfFontFamily = "report"
+
+// renderIMEIPDF builds a printable A4 report: header, input hashes, totals and every line
+// that needs attention. The full line-by-line list is in the XLSX and CSV exports.
+func renderIMEIPDF(d imeiExport, fontPath string) ([]byte, error) {
+	pdf, family, text, err := newReportPDF("IMEI verification report "+d.stored.ID.String(), fontPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(family, "", 8)
+		pdf.CellFormat(0, 5, text(fmt.Sprintf("Report %s - generated %s UTC - page %d/{nb}",
+			d.stored.ID, d.generatedAt.Format("2006-01-02 15:04"), pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	heading := func(s string) {
+		pdf.Ln(3)
+		pdf.SetFont(family, "B", 11)
+		pdf.CellFormat(0, 7, text(s), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+	}
+	table := func(widths []float64, header []string, rows [][]string) {
+		pdf.SetFont(family, "B", 8)
+		pdf.SetFillColor(230, 230, 230)
+		for i, h := range header {
+			pdf.CellFormat(widths[i], 6, text(h), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(family, "", 8)
+		for _, r := range rows {
+			for i, v := range r {
+				pdf.CellFormat(widths[i], 5, fitPDFCell(pdf, text, v, widths[i]), "1", 0, "L", false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+
+	pdf.SetFont(family, "B", 16)
+	pdf.CellFormat(0, 10, text("IMEI VERIFICATION REPORT"), "", 1, "C", false, 0, "")
+
+	heading("Summary")
+	pdf.SetFont(family, "", 9)
+	for _, r := range d.summaryRows() {
+		pdf.CellFormat(60, 5, text(r[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, text(r[1]), "", 1, "L", false, 0, "")
+	}
+
+	heading("Input files")
+	var files [][]string
+	for _, file := range d.inputFiles() {
+		files = append(files, []string{file[0], file[1]})
+	}
+	table([]float64{62, 118}, []string{"File", "SHA-256"}, files)
+
+	heading("Statistics by column")
+	var columns [][]string
+	for _, s := range d.report.ColumnStats {
+		columns = append(columns, []string{s.Column, strconv.Itoa(s.Total), strconv.Itoa(s.Found), strconv.Itoa(s.Missing), strconv.Itoa(s.Invalid)})
+	}
+	table([]float64{60, 30, 30, 30, 30}, []string{"Column", "Total", "Found", "Missing", "Invalid"}, columns)
+
+	var problems [][]string
+	for _, res := range d.report.Results {
+		status := resultStatus(res)
+		switch {
+		case res.DuplicateOfLine > 0:
+			status = fmt.Sprintf("DUPLICATE of line %d", res.DuplicateOfLine)
+		case res.PreviousDeclaration != nil:
+			status = "PREVIOUSLY DECLARED: " + previousDeclarationName(res.PreviousDeclaration)
+		case res.PairMismatch:
+			status = "DUAL-SIM MISMATCH: " + res.PairReason
+		case res.Normalized:
+			status = "SPLIT NUMBER, CHECK: " + res.NormalizedFrom
+		case status == "FOUND":
+			continue
+		}
+		problems = append(problems, []string{strconv.Itoa(res.CSVLine), res.Column, res.RawValue, status})
+	}
+	heading(fmt.Sprintf("IMEIs requiring attention (%d)", len(problems)))
+	if len(problems) == 0 {
+		pdf.SetFont(family, "", 9)
+		pdf.CellFormat(0, 5, text("All CSV IMEIs were found in the declaration."), "", 1, "L", false, 0, "")
+	} else {
+		table([]float64{18, 30, 45, 87}, []string{"Line", "Column", "Value", "Status"}, problems)
+	}
+
+	if len(d.report.UnexpectedInPDF) > 0 {
+		heading(fmt.Sprintf("Unexpected in PDF, not in CSV (%d)", len(d.report.UnexpectedInPDF)))
+		var rows [][]string
+		for _, u := range d.report.UnexpectedInPDF {
+			rows = append(rows, []string{u.IMEI, u.SourceFile, strconv.Itoa(u.SourcePage)})
+		}
+		table([]float64{45, 115, 20}, []string{"IMEI", "File", "Page"}, rows)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newReportPDF creates an A4 document with the configured Unicode font, or a built-in
+// font when fontPath is empty. Returns the font family and the text converter to use.
+func newReportPDF(title, fontPath string) (*fpdf.Fpdf, string, func(string) string, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetTitle(title, true)
+	pdf.AliasNbPages("")
+
+	if fontPath == "" {
+		return pdf, "Helvetica", pdfLatinText(pdf), nil
+	}
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("loading font: %w", err)
+	}
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", font)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "B", font)
+	if err := pdf.Error(); err != nil {
+		return nil, "", nil, fmt.Errorf("loading font: %w", err)
+	}
+	return pdf, pdfFontFamily, func(s string) string { return s }, nil
+}
+
+// fitPDFCell converts s with text and cuts it with an ellipsis so it fits a table cell of width w.
+func fitPDFCell(pdf *fpdf.Fpdf, text func(string) string, s string, w float64) string {
+	const padding = 2
+	if out := text(s); pdf.GetStringWidth(out) <= w-padding {
+		return out
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.GetStringWidth(text(string(r)+"...")) > w-padding {
+		r = r[:len(r)-1]
+	}
+	return text(string(r) + "...")
+}
+
+// pdfLatinText returns a converter for the built-in PDF fonts: Cyrillic is transliterated,
+// the rest is encoded as cp1252.
+func pdfLatinText(pdf *fpdf.Fpdf) func(string) string {
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	return func(s string) string {
+		return tr(transliterateCyrillic(s))
+	}
+}
+
+// cyrillicLatin maps Russian and Kazakh letters to Latin (lower case).
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'ә': "a", 'ғ': "gh", 'қ': "q", 'ң': "ng", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h", 'і': "i",
+}
+
+// transliterateCyrillic replaces Cyrillic letters with Latin ones, keeping the case of the
+// first letter.
+func transliterateCyrillic(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		latin, ok := cyrillicLatin[unicode.ToLower(r)]
+		if !ok {
+			sb.WriteRune(r)
+			continue
+		}
+		if unicode.IsUpper(r) && latin != "" {
+			latin = strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		sb.WriteString(latin)
+	}
+	return sb.String()
+}
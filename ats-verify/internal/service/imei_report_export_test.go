@@ -0,0 +1,124 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"ats-verify/internal/models"
+)
+
+func testStoredIMEIReport(t *testing.T) *models.AnalysisReport {
+	t.Helper()
+	report := models.IMEIVerificationReport{
+		TotalIMEIs:   2,
+		TotalFound:   1,
+		TotalMissing: 1,
+		ColumnStats:  []models.IMEIColumnStats{{Column: "Imei1", Total: 2, Found: 1, Missing: 1}},
+		Results: []models.IMEIMatchResult{
+			{CSVLine: 2, Column: "Imei1", RawValue: "353328110000005", Validity: models.IMEIValid15, IMEI14: "35332811000000", Found: true, SourceFile: "декларация.pdf", SourcePage: 1},
+			{CSVLine: 3, Column: "Imei1", RawValue: "353328110000013", Validity: models.IMEIValid15, IMEI14: "35332811000001"},
+		},
+	}
+	results, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.AnalysisReport{
+		ID:           uuid.New(),
+		UserID:       uuid.New(),
+		ReportType:   ReportTypeIMEIVerification,
+		CreatedAt:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		CSVFileName:  "devices.csv",
+		CSVSHA256:    strings.Repeat("a", 64),
+		PDFFileNames: []string{"декларация.pdf"},
+		PDFSHA256:    []string{strings.Repeat("b", 64)},
+		Results:      results,
+	}
+}
+
+func TestIMEIReportExporter_Formats(t *testing.T) {
+	exporter := NewIMEIReportExporter(nil, "")
+	stored := testStoredIMEIReport(t)
+
+	csvFile, err := exporter.Export(context.Background(), stored, ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimPrefix(string(csvFile.Data), "\ufeff"), "\n")
+	if !strings.HasPrefix(lines[0], "csv_line,column,raw_value") || !strings.Contains(lines[2], "MISSING") {
+		t.Errorf("unexpected CSV export:\n%s", csvFile.Data)
+	}
+
+	xlsxFile, err := exporter.Export(context.Background(), stored, ExportXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := excelize.OpenReader(bytes.NewReader(xlsxFile.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	if got := wb.GetSheetList(); strings.Join(got, ",") != "Summary,Columns,Lines" {
+		t.Errorf("unexpected sheets %v", got)
+	}
+	foundStyle, _ := wb.GetCellStyle(sheetLines, "A2")
+	missingStyle, _ := wb.GetCellStyle(sheetLines, "A3")
+	if missingStyle == foundStyle {
+		t.Error("expected the missing line to be highlighted")
+	}
+
+	pdfFile, err := exporter.Export(context.Background(), stored, ExportPDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdfFile.Data, []byte("%PDF")) || pdfFile.ContentType != "application/pdf" {
+		t.Error("expected a PDF document")
+	}
+}
+
+func TestIMEIReportExporter_CSVFormulaInjection(t *testing.T) {
+	stored := testStoredIMEIReport(t)
+	report := models.IMEIVerificationReport{Results: []models.IMEIMatchResult{
+		{CSVLine: 2, Column: "Imei1", RawValue: "=HYPERLINK(\"http://x\")", Validity: models.IMEINonNumeric, DeclaredModel: "-1+1"},
+	}}
+	results, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Results = results
+
+	csvFile, err := NewIMEIReportExporter(nil, "").Export(context.Background(), stored, ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(csvFile.Data), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := records[1]
+	if row[2] != `'=HYPERLINK("http://x")` || row[15] != "'-1+1" {
+		t.Errorf("expected formula-like cells to be prefixed with ', got %q", row)
+	}
+}
+
+func TestIMEIReportExporter_NoStoredResults(t *testing.T) {
+	stored := testStoredIMEIReport(t)
+	stored.Results = nil
+	if _, err := NewIMEIReportExporter(nil, "").Export(context.Background(), stored, ExportPDF); err != ErrNoStoredResults {
+		t.Errorf("expected ErrNoStoredResults, got %v", err)
+	}
+}
+
+func TestTransliterateCyrillic(t *testing.T) {
+	if got := transliterateCyrillic("Декларация Қазақстан"); got != "Deklaratsiya Qazaqstan" {
+		t.Errorf("unexpected transliteration %q", got)
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ats-verify/internal/models"
+)
+
+// Verification modes of IMEIVerificationReport.
+const (
+	ModeIMEI   = "imei"
+	ModeSerial = "serial"
+)
+
+// DetectedBySelection marks identifier columns picked by the user (serial mode).
+const DetectedBySelection = "selected"
+
+// minSerialLength is the fewest letters and digits a serial number must have; shorter
+// values ("1", "N/A") would match unrelated declaration text.
+const minSerialLength = 4
+
+// SerialMatchRule decides when a CSV serial number and a PDF token are the same device.
+type SerialMatchRule string
+
+const (
+	SerialMatchExact  SerialMatchRule = "exact"  // Whole token, character for character
+	SerialMatchPrefix SerialMatchRule = "prefix" // First PrefixLength characters
+	SerialMatchAlnum  SerialMatchRule = "alnum"  // Letters and digits only, case-insensitive
+)
+
+// SerialOptions configures a serial-number verification.
+type SerialOptions struct {
+	Columns      []string // CSV headers of the identifier columns, compared case-insensitively
+	Rule         SerialMatchRule
+	PrefixLength int // Characters compared by SerialMatchPrefix
+}
+
+// ParseSerialMatchRule parses a match rule; an empty value means SerialMatchExact.
+func ParseSerialMatchRule(s string) (SerialMatchRule, error) {
+	switch rule := SerialMatchRule(strings.ToLower(strings.TrimSpace(s))); rule {
+	case "":
+		return SerialMatchExact, nil
+	case SerialMatchExact, SerialMatchPrefix, SerialMatchAlnum:
+		return rule, nil
+	}
+	return "", fmt.Errorf("unknown match rule %q (expected exact, prefix or alnum)", s)
+}
+
+// describe renders the rule for reports.
+func (o SerialOptions) describe() string {
+	switch o.Rule {
+	case SerialMatchPrefix:
+		return fmt.Sprintf("prefix of %d characters", o.PrefixLength)
+	case SerialMatchAlnum:
+		return "letters and digits only, case-insensitive"
+	}
+	return "exact"
+}
+
+// key returns the match key of a serial number or PDF token under the rule, and
+// whether the value is long enough to be matched.
+func (o SerialOptions) key(v string) (string, bool) {
+	alnum := serialAlnum(v)
+	if utf8.RuneCountInString(alnum) < minSerialLength {
+		return "", false
+	}
+	switch o.Rule {
+	case SerialMatchPrefix:
+		if utf8.RuneCountInString(v) < o.PrefixLength {
+			return "", false
+		}
+		return string([]rune(v)[:o.PrefixLength]), true
+	case SerialMatchAlnum:
+		return alnum, true
+	}
+	return v, true
+}
+
+// cyrillicHomoglyphs maps uppercase Cyrillic letters that look like Latin ones; OCR and
+// manual typing mix them up in serial numbers.
+var cyrillicHomoglyphs = map[rune]rune{
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+	'Р': 'P', 'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X',
+}
+
+// serialAlnum uppercases v, folds Cyrillic homoglyphs to Latin and drops everything but
+// letters and digits.
+func serialAlnum(v string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToUpper(r)
+		if l, ok := cyrillicHomoglyphs[r]; ok {
+			return l
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, v)
+}
+
+// isSerialRune reports whether r can be part of a serial number token in PDF text.
+func isSerialRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '/' || r == '.'
+}
+
+// forEachSerialToken calls fn with every token of text: a maximal run of letters,
+// digits and "-_/." without leading or trailing punctuation.
+func forEachSerialToken(text string, fn func(token string)) {
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return !isSerialRune(r) }) {
+		if token := strings.Trim(field, "-_/."); token != "" {
+			fn(token)
+		}
+	}
+}
+
+// serialToken is the first occurrence of a match key in the documents.
+type serialToken struct {
+	ref  pageRef
+	text string
+}
+
+// serialIndex maps the match keys of all PDF tokens to their first page and goods item.
+type serialIndex struct {
+	*documentIndex
+	tokens     map[string]serialToken
+	itemTokens map[itemWindowKey]int
+}
+
+func newSerialIndex(idx *documentIndex, opts SerialOptions) *serialIndex {
+	s := &serialIndex{
+		documentIndex: idx,
+		tokens:        make(map[string]serialToken),
+		itemTokens:    make(map[itemWindowKey]int),
+	}
+	for i, doc := range idx.docs {
+		for n, page := range idx.pages[i] {
+			ref := pageRef{doc: i, page: n}
+			forEachSerialToken(page.text, func(token string) {
+				key, ok := opts.key(token)
+				if _, seen := s.tokens[key]; ok && !seen {
+					s.tokens[key] = serialToken{ref: ref, text: token}
+				}
+			})
+		}
+		for _, item := range doc.Items {
+			forEachSerialToken(item.Description, func(token string) {
+				key, ok := opts.key(token)
+				wk := itemWindowKey{doc: i, window: key}
+				if _, seen := s.itemTokens[wk]; ok && !seen {
+					s.itemTokens[wk] = item.Number
+				}
+			})
+		}
+	}
+	return s
+}
+
+// match looks a classified serial number up in the PDF tokens.
+func (s *serialIndex) match(res *models.IMEIMatchResult) {
+	t, ok := s.tokens[res.Identifier]
+	if !ok {
+		return
+	}
+	s.recordMatch(res, t.ref, s.itemTokens[itemWindowKey{doc: t.ref.doc, window: res.Identifier}])
+	res.MatchedIMEI = t.text
+}
+
+// selectSerialColumns maps the user's column names to CSV indexes.
+func selectSerialColumns(header []string, names []string) (map[int]string, []models.IMEIDetectedColumn, error) {
+	colMap := make(map[int]string)
+	var detected []models.IMEIDetectedColumn
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for i, h := range header {
+			if _, ok := colMap[i]; ok || !strings.EqualFold(strings.TrimSpace(h), name) {
+				continue
+			}
+			colMap[i] = strings.TrimSpace(h)
+			detected = append(detected, models.IMEIDetectedColumn{
+				Column:   colMap[i],
+				Position: i + 1,
+				Method:   DetectedBySelection,
+				Reason:   "selected as an identifier column",
+			})
+			found = true
+			break
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("CSV has no column %q", name)
+		}
+	}
+	return colMap, detected, nil
+}
+
+// AnalyzeSerials compares serial numbers from the selected CSV columns against several
+// PDFs under opts.Rule. It produces the same report as AnalyzeDocuments; checks that
+// only make sense for IMEIs (check digits, dual-SIM pairing, unexpected PDF values) are
+// skipped.
+func (s *IMEIService) AnalyzeSerials(csvReader io.Reader, docs []PDFDocument, opts SerialOptions) (*models.IMEIVerificationReport, error) {
+	var names []string
+	for _, c := range opts.Columns {
+		if c = strings.TrimSpace(c); c != "" {
+			names = append(names, c)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one identifier column is required")
+	}
+	if opts.Rule == "" {
+		opts.Rule = SerialMatchExact
+	}
+	if opts.Rule == SerialMatchPrefix && opts.PrefixLength < minSerialLength {
+		return nil, fmt.Errorf("prefix length must be at least %d", minSerialLength)
+	}
+
+	report := &models.IMEIVerificationReport{Mode: ModeSerial, MatchRule: opts.describe()}
+	index := newSerialIndex(newDocumentIndex(docs, report), opts)
+
+	_, err := runIdentifierCSV(csvReader, report, identifierPipeline{
+		columns: func(header []string, _ [][]string, _ map[int]bool) (map[int]string, []models.IMEIDetectedColumn, error) {
+			return selectSerialColumns(header, names)
+		},
+		classify: func(raw string) models.IMEIMatchResult {
+			res := models.IMEIMatchResult{RawValue: raw, Validity: models.IMEIWrongLength}
+			if key, ok := opts.key(raw); ok {
+				res.Validity = models.SerialValid
+				res.Identifier = key
+			}
+			return res
+		},
+		key:   func(res *models.IMEIMatchResult) string { return res.Identifier },
+		valid: func(res *models.IMEIMatchResult) bool { return res.Validity == models.SerialValid },
+		match: index.match,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.GoodsItems = index.goodsItemStats()
+	report.TextReport = generateTextReport(report)
+
+	return report, nil
+}
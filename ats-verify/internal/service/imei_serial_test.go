@@ -0,0 +1,69 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+func TestIMEIServiceAnalyzeSerials_MatchRules(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "Serial;Модель\n" +
+		"PF3ABC12;ThinkPad E14\n" +
+		"c02-xk9-lmd6;MacBook Air\n" +
+		"R9ZT9000;Galaxy Tab\n" +
+		"N/A;Unknown\n"
+	docs := []PDFDocument{{
+		FileName: "decl.pdf",
+		Pages:    []string{"S/N: PF3ABC12, C02XK9LMD6.", "Серийный номер R9ZT9001"},
+	}}
+
+	tests := []struct {
+		name  string
+		opts  SerialOptions
+		found []bool
+	}{
+		{"exact", SerialOptions{Rule: SerialMatchExact}, []bool{true, false, false, false}},
+		{"alnum", SerialOptions{Rule: SerialMatchAlnum}, []bool{true, true, false, false}},
+		{"prefix", SerialOptions{Rule: SerialMatchPrefix, PrefixLength: 7}, []bool{true, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Columns = []string{"serial"}
+			report, err := svc.AnalyzeSerials(strings.NewReader(csvContent), docs, tt.opts)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if report.Mode != ModeSerial || len(report.Results) != len(tt.found) {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			for i, res := range report.Results {
+				if res.Found != tt.found[i] {
+					t.Errorf("%q: expected found=%v, got %v", res.RawValue, tt.found[i], res.Found)
+				}
+			}
+			if last := report.Results[3]; last.Validity != models.IMEIWrongLength || report.TotalInvalid != 1 {
+				t.Errorf("expected N/A to be invalid, got %+v", last)
+			}
+			if !strings.Contains(report.TextReport, "SERIAL NUMBER VERIFICATION REPORT") {
+				t.Errorf("expected serial report title, got:\n%s", report.TextReport)
+			}
+		})
+	}
+}
+
+func TestIMEIServiceAnalyzeSerials_UnknownColumn(t *testing.T) {
+	svc := NewIMEIService()
+	_, err := svc.AnalyzeSerials(strings.NewReader("Serial\nPF3ABC12\n"), []PDFDocument{{Pages: []string{""}}}, SerialOptions{Columns: []string{"SN"}})
+	if err == nil || !strings.Contains(err.Error(), `"SN"`) {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
+
+func TestSerialAlnum_FoldsCyrillicHomoglyphs(t *testing.T) {
+	if got := serialAlnum("с02-хк9 lmd6"); got != "C02XK9LMD6" {
+		t.Errorf("expected C02XK9LMD6, got %q", got)
+	}
+}
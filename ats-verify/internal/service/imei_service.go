@@ -1,11 +1,12 @@
 package service
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"ats-verify/internal/models"
 )
@@ -18,42 +19,143 @@ func NewIMEIService() *IMEIService {
 	return &IMEIService{}
 }
 
-// imeiColumns lists the CSV column names to scan for IMEI values.
-var imeiColumns = []string{"imei", "imei1", "imei2", "imei3", "imei4", "imei_number"}
+// imeiContextColumns maps optional CSV columns with declared device data to their aliases.
+var imeiContextColumns = map[string][]string{
+	"brand": {"brand", "manufacturer", "бренд", "марка", "производитель"},
+	"model": {"model", "модель", "device_model"},
+	"track": {"track", "track_number", "трек", "трек-номер", "трек номер"},
+}
 
 // regex15Digits matches 15-digit sequences in PDF text for IMEI extraction.
 var regex15Digits = regexp.MustCompile(`\b\d{15}\b`)
 
+// imeiOccurrence is the first CSV cell holding an IMEI.
+type imeiOccurrence struct {
+	line   int
+	column string
+}
+
+// PDFDocument is the per-page text of one uploaded PDF and, when its layout could be
+// parsed, its declaration goods items.
+type PDFDocument struct {
+	FileName string
+	Pages    []string
+	Items    []models.DeclarationItem
+	Backend  string            // TextExtractor that produced Pages
+	Quality  ExtractionQuality // Quality of Pages
+	Repaired bool              // Text was read after rebuilding a damaged cross-reference table
+}
+
 // Analyze compares IMEIs from a multi-column CSV against text extracted from a PDF.
-// CSV columns: Imei1..Imei4 (any subset). PDF text: 15-digit sequences.
+// CSV columns: IMEI headers (Imei1..Imei4, ИМЕЙ, ...) or columns of 14-15 digit values.
+// PDF text: 15-digit sequences.
 // Match rule: 14-digit IMEI (from CSV) must be a prefix of a 15-digit sequence (from PDF).
 func (s *IMEIService) Analyze(csvReader io.Reader, pdfTextContent string) (*models.IMEIVerificationReport, error) {
-	reader := csv.NewReader(csvReader)
-	reader.TrimLeadingSpace = true
+	return s.AnalyzeDocuments(csvReader, []PDFDocument{{Pages: []string{pdfTextContent}}})
+}
+
+// AnalyzeDocuments compares IMEIs from one CSV against several PDFs (a declaration split
+// into parts). Each IMEI is attributed to the first file and page containing it.
+func (s *IMEIService) AnalyzeDocuments(csvReader io.Reader, docs []PDFDocument) (*models.IMEIVerificationReport, error) {
+	report := &models.IMEIVerificationReport{Mode: ModeIMEI}
+	index := newDocumentIndex(docs, report)
+	index.indexIMEIs()
+
+	firstSeen, err := runIdentifierCSV(csvReader, report, identifierPipeline{
+		columns: func(header []string, sample [][]string, skip map[int]bool) (map[int]string, []models.IMEIDetectedColumn, error) {
+			colMap, detected := detectIMEIColumns(header, sample, skip)
+			if len(colMap) == 0 {
+				return nil, nil, fmt.Errorf("CSV must contain at least one IMEI column (an IMEI/ИМЕЙ header or 14-15 digit values)")
+			}
+			return colMap, detected, nil
+		},
+		classify: classifyIMEI,
+		key:      func(res *models.IMEIMatchResult) string { return res.IMEI14 },
+		valid: func(res *models.IMEIMatchResult) bool {
+			return res.Validity == models.IMEIValid15 || res.Validity == models.IMEIValid14
+		},
+		match: index.match,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only same-TAC pairs are known here; TACService.Annotate adds known TAC pairs.
+	markDualSIMPairs(report, tacPairing{})
+	report.GoodsItems = index.goodsItemStats()
+
+	report.UnexpectedInPDF = index.unexpected(firstSeen)
+	report.TotalUnexpected = len(report.UnexpectedInPDF)
+
+	report.TextReport = generateTextReport(report)
+
+	return report, nil
+}
+
+// identifierPipeline is the mode-specific part of a CSV-vs-PDF verification.
+type identifierPipeline struct {
+	// columns picks the identifier columns (index → display name); skip holds the
+	// declared device data columns.
+	columns  func(header []string, sample [][]string, skip map[int]bool) (map[int]string, []models.IMEIDetectedColumn, error)
+	classify func(raw string) models.IMEIMatchResult
+	key      func(res *models.IMEIMatchResult) string // Match key; "" for values that cannot be matched
+	valid    func(res *models.IMEIMatchResult) bool
+	match    func(res *models.IMEIMatchResult)
+}
+
+// runIdentifierCSV streams the CSV through p and fills the results, totals, column
+// stats and duplicates of report. Returns the first occurrence of every match key.
+func runIdentifierCSV(csvReader io.Reader, report *models.IMEIVerificationReport, p identifierPipeline) (map[string]imeiOccurrence, error) {
+	// Rows are streamed (',' or ';' separated, optional BOM); only results are kept.
+	reader, err := NewRobustCSVReader(csvReader)
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV: %w", err)
+	}
+	reader.ReuseRecord = true
 
-	// Read header and find IMEI columns.
 	header, err := reader.Read()
 	if err != nil {
 		return nil, fmt.Errorf("reading CSV header: %w", err)
 	}
+	header = append([]string(nil), header...)
 
-	// Map: column index → column name (only IMEI columns).
-	colMap := make(map[int]string)
+	// Map: context field → column index (first matching column wins).
+	contextCols := make(map[string]int)
+	contextIdx := make(map[int]bool)
 	for i, col := range header {
 		lower := strings.ToLower(strings.TrimSpace(col))
-		for _, target := range imeiColumns {
-			if lower == target {
-				colMap[i] = strings.TrimSpace(col)
-				break
+		for field, aliases := range imeiContextColumns {
+			if _, ok := contextCols[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if lower == alias {
+					contextCols[field] = i
+					contextIdx[i] = true
+				}
 			}
 		}
 	}
-	if len(colMap) == 0 {
-		return nil, fmt.Errorf("CSV must contain at least one IMEI column (imei, imei1..imei4)")
+
+	// Buffer the first rows to detect columns by content; they are processed first.
+	var sample [][]string
+	for len(sample) < imeiSampleRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		sample = append(sample, append([]string(nil), record...))
 	}
 
-	// Extract all 15-digit sequences from PDF.
-	pdf15Digits := regex15Digits.FindAllString(pdfTextContent, -1)
+	// Map: column index → column name (only identifier columns).
+	colMap, detected, err := p.columns(header, sample, contextIdx)
+	if err != nil {
+		return nil, err
+	}
+	report.DetectedColumns = detected
 
 	// Per-column stats tracker.
 	statsMap := make(map[string]*models.IMEIColumnStats)
@@ -61,106 +163,650 @@ func (s *IMEIService) Analyze(csvReader io.Reader, pdfTextContent string) (*mode
 		statsMap[colName] = &models.IMEIColumnStats{Column: colName}
 	}
 
-	report := &models.IMEIVerificationReport{}
+	columnOrder := sortedColumnIndexes(colMap)
+	firstSeen := make(map[string]imeiOccurrence)
 	csvLine := 1 // header is line 1, data starts at 2
 
 	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			continue
+		var record []string
+		if len(sample) > 0 {
+			record, sample = sample[0], sample[1:]
+		} else {
+			record, err = reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				continue
+			}
 		}
 		csvLine++
 
-		for colIdx, colName := range colMap {
+		for _, colIdx := range columnOrder {
+			colName := colMap[colIdx]
 			if colIdx >= len(record) {
 				continue
 			}
 
-			imei := strings.TrimSpace(record[colIdx])
-			if imei == "" {
+			raw := strings.TrimSpace(record[colIdx])
+			if raw == "" {
 				continue
 			}
 
-			// Normalize: take first 14 digits if longer.
-			imei14 := imei
-			if len(imei14) > 14 {
-				imei14 = imei14[:14]
-			}
-			if len(imei14) < 14 {
-				continue // Skip invalid short IMEIs.
-			}
+			res := p.classify(raw)
+			res.CSVLine = csvLine
+			res.Column = colName
+			res.DeclaredBrand = recordField(record, contextCols, "brand")
+			res.DeclaredModel = recordField(record, contextCols, "model")
+			res.TrackNumber = recordField(record, contextCols, "track")
 
 			report.TotalIMEIs++
 			statsMap[colName].Total++
+			if !p.valid(&res) {
+				report.TotalInvalid++
+				statsMap[colName].Invalid++
+			}
 
-			// EXACT BOT LOGIC: Check if PDF text directly contains the 14-digit IMEI.
-			found := strings.Contains(pdfTextContent, imei14)
-			matched := ""
-			// Provide the 15-digit match to the UI if available, else indicate a generic match.
-			if found {
-				for _, seq := range pdf15Digits {
-					if strings.HasPrefix(seq, imei14) {
-						matched = seq
-						break
-					}
-				}
-				if matched == "" {
-					matched = "(prefix matched in text)"
+			// Structurally invalid values cannot be matched and count as missing.
+			if key := p.key(&res); key != "" {
+				if first, ok := firstSeen[key]; ok {
+					res.DuplicateOfLine = first.line
+					res.DuplicateOfColumn = first.column
+					report.TotalDuplicates++
+				} else {
+					firstSeen[key] = imeiOccurrence{line: csvLine, column: colName}
 				}
+				p.match(&res)
 			}
-			if found {
+
+			if res.Found {
 				report.TotalFound++
 				statsMap[colName].Found++
+				if res.Normalized {
+					report.TotalNormalized++
+				}
 			} else {
 				report.TotalMissing++
 				statsMap[colName].Missing++
 			}
 
-			report.Results = append(report.Results, models.IMEIMatchResult{
-				CSVLine:     csvLine,
-				Column:      colName,
-				IMEI14:      imei14,
-				MatchedIMEI: matched,
-				Found:       found,
-			})
+			report.Results = append(report.Results, res)
 		}
 	}
 
 	// Collect per-column stats in deterministic order.
-	for _, colName := range colMap {
-		report.ColumnStats = append(report.ColumnStats, *statsMap[colName])
+	for _, colIdx := range columnOrder {
+		report.ColumnStats = append(report.ColumnStats, *statsMap[colMap[colIdx]])
 	}
+	return firstSeen, nil
+}
 
-	report.TextReport = generateTextReport(report)
+// pdfPage is a page of PDF text with its 15-digit sequences.
+type pdfPage struct {
+	number    int
+	text      string
+	sequences []string
+}
 
-	return report, nil
+// goodsItemKey identifies a goods item of one document.
+type goodsItemKey struct {
+	doc  int
+	item int
+}
+
+// pageRef identifies a page of one document (0-based indexes into documentIndex.pages).
+type pageRef struct {
+	doc  int
+	page int
+}
+
+// sequenceMatch is a 15-digit sequence and the page it was found on.
+type sequenceMatch struct {
+	ref    pageRef
+	digits string
+}
+
+// splitMatch is the first page containing a 14-digit window of a split number.
+type splitMatch struct {
+	ref    pageRef
+	digits string // Rebuilt number
+	source string // Original text of the number
+}
+
+// itemWindowKey identifies a digit window of a goods item description in one document.
+type itemWindowKey struct {
+	doc    int
+	window string
+}
+
+// documentIndex holds the pages of all documents of one analysis and the CSV lines
+// matched per goods item.
+//
+// Every 14-digit window of every digit run is indexed once, so a CSV IMEI is matched
+// with a map lookup instead of scanning all page texts. A window maps to its first page,
+// which keeps the semantics of strings.Contains over the pages in document order.
+// 15-digit sequences are indexed by their 14-digit prefix across all pages, so the full
+// number is found even when the prefix first appears on another page.
+// Numbers split by spaces, hyphens or line breaks are indexed separately and only
+// used when the raw text has no match.
+type documentIndex struct {
+	docs      []PDFDocument
+	pages     [][]pdfPage
+	report    *models.IMEIVerificationReport
+	itemLines map[goodsItemKey]map[int]bool
+
+	windows     map[string]pageRef         // 14-digit window → first page containing it
+	split       map[string]splitMatch      // 14-digit window of a split number → first occurrence
+	sequences   map[string][]sequenceMatch // 14-digit prefix → 15-digit sequences in document order
+	itemWindows map[itemWindowKey]int      // 14- and 15-digit window → first goods item containing it
+}
+
+// newDocumentIndex splits documents into pages and initializes report.FileStats.
+func newDocumentIndex(docs []PDFDocument, report *models.IMEIVerificationReport) *documentIndex {
+	idx := &documentIndex{
+		docs:      docs,
+		pages:     make([][]pdfPage, len(docs)),
+		report:    report,
+		itemLines: make(map[goodsItemKey]map[int]bool),
+	}
+	for i, doc := range docs {
+		stats := models.IMEIFileStats{
+			FileName:     doc.FileName,
+			Pages:        len(doc.Pages),
+			Backend:      doc.Backend,
+			GarbageRatio: doc.Quality.GarbageRatio,
+			Repaired:     doc.Repaired,
+		}
+		for n, text := range doc.Pages {
+			page := pdfPage{number: n + 1, text: text, sequences: regex15Digits.FindAllString(text, -1)}
+			stats.IMEISequences += len(page.sequences)
+			idx.pages[i] = append(idx.pages[i], page)
+		}
+		report.FileStats = append(report.FileStats, stats)
+	}
+	return idx
+}
+
+// indexIMEIs indexes the digit windows, 15-digit sequences and split numbers of every
+// page and the digit windows of every goods item.
+func (idx *documentIndex) indexIMEIs() {
+	idx.windows = make(map[string]pageRef)
+	idx.split = make(map[string]splitMatch)
+	idx.sequences = make(map[string][]sequenceMatch)
+	idx.itemWindows = make(map[itemWindowKey]int)
+
+	for i, doc := range idx.docs {
+		for n, page := range idx.pages[i] {
+			ref := pageRef{doc: i, page: n}
+			forEachDigitWindow(page.text, 14, func(w string) {
+				if _, ok := idx.windows[w]; !ok {
+					idx.windows[w] = ref
+				}
+			})
+			for _, seq := range page.sequences {
+				idx.sequences[seq[:14]] = append(idx.sequences[seq[:14]], sequenceMatch{ref: ref, digits: seq})
+			}
+			for _, run := range findSplitDigitRuns(page.text) {
+				forEachDigitWindow(run.digits, 14, func(w string) {
+					if _, ok := idx.split[w]; !ok {
+						idx.split[w] = splitMatch{ref: ref, digits: run.digits, source: run.source(page.text)}
+					}
+				})
+			}
+		}
+		for _, item := range doc.Items {
+			addWindow := func(w string) {
+				key := itemWindowKey{doc: i, window: w}
+				if _, ok := idx.itemWindows[key]; !ok {
+					idx.itemWindows[key] = item.Number
+				}
+			}
+			forEachDigitWindow(item.Description, 14, addWindow)
+			forEachDigitWindow(item.Description, 15, addWindow)
+			for _, run := range findSplitDigitRuns(item.Description) {
+				forEachDigitWindow(run.digits, 14, addWindow)
+				forEachDigitWindow(run.digits, 15, addWindow)
+			}
+		}
+	}
+}
+
+// forEachDigitWindow calls fn for every substring of n ASCII digits of text.
+// The windows share memory with text.
+func forEachDigitWindow(text string, n int, fn func(window string)) {
+	runStart := -1
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && isASCIIDigit(text[i]) {
+			if runStart < 0 {
+				runStart = i
+			}
+			continue
+		}
+		if runStart >= 0 {
+			for j := runStart; j+n <= i; j++ {
+				fn(text[j : j+n])
+			}
+			runStart = -1
+		}
+	}
+}
+
+// match looks up res.IMEI14 in the documents and records the first hit, including the
+// 15-digit sequence, check digit and goods item when available. A page with a 15-digit
+// sequence is preferred over a bare 14-digit window, and the expected IMEI over other
+// check digits. Matches found only in rebuilt split numbers are marked Normalized.
+func (idx *documentIndex) match(res *models.IMEIMatchResult) {
+	// EXACT BOT LOGIC: the PDF text must directly contain the 14-digit IMEI.
+	ref, ok := idx.windows[res.IMEI14]
+	seq, hasSeq := "", false
+	if m, found := idx.sequence(res); found {
+		ref, seq, hasSeq = m.ref, m.digits, true
+	}
+	if !ok {
+		split, ok := idx.split[res.IMEI14]
+		if !ok {
+			return
+		}
+		ref = split.ref
+		seq, hasSeq = split.digits, len(split.digits) == 15 && strings.HasPrefix(split.digits, res.IMEI14)
+		res.Normalized = true
+		res.NormalizedFrom = split.source
+	}
+	idx.recordMatch(res, ref, idx.itemWindows[itemWindowKey{doc: ref.doc, window: res.IMEI14}])
+
+	// Provide the 15-digit match to the UI if available, else indicate a generic match.
+	if hasSeq {
+		res.MatchedIMEI = seq
+		ok := seq == res.ExpectedIMEI
+		res.PDFCheckDigitOK = &ok
+	} else {
+		res.MatchedIMEI = "(prefix matched in text)"
+	}
+}
+
+// sequence returns the 15-digit sequence for res.IMEI14: the first one equal to the
+// expected IMEI, else the first one in document order.
+func (idx *documentIndex) sequence(res *models.IMEIMatchResult) (sequenceMatch, bool) {
+	matches := idx.sequences[res.IMEI14]
+	if len(matches) == 0 {
+		return sequenceMatch{}, false
+	}
+	for _, m := range matches {
+		if m.digits == res.ExpectedIMEI {
+			return m, true
+		}
+	}
+	return matches[0], true
+}
+
+// recordMatch marks res as found on the page and counts it for the file and, when
+// goodsItem is not 0, for the goods item.
+func (idx *documentIndex) recordMatch(res *models.IMEIMatchResult, ref pageRef, goodsItem int) {
+	res.Found = true
+	res.SourceFile = idx.docs[ref.doc].FileName
+	res.SourcePage = idx.pages[ref.doc][ref.page].number
+	idx.report.FileStats[ref.doc].Matched++
+
+	if goodsItem == 0 {
+		return
+	}
+	res.GoodsItem = goodsItem
+	key := goodsItemKey{doc: ref.doc, item: goodsItem}
+	if idx.itemLines[key] == nil {
+		idx.itemLines[key] = make(map[int]bool)
+	}
+	idx.itemLines[key][res.CSVLine] = true
+}
+
+// unexpected returns the Luhn-valid 15-digit sequences whose 14-digit prefix is not in
+// csvIMEIs, once per IMEI at its first occurrence, and counts them per file.
+func (idx *documentIndex) unexpected(csvIMEIs map[string]imeiOccurrence) []models.IMEIUnexpected {
+	out := []models.IMEIUnexpected{}
+	seen := make(map[string]bool)
+	for i := range idx.pages {
+		for _, page := range idx.pages[i] {
+			for _, loc := range regex15Digits.FindAllStringIndex(page.text, -1) {
+				seq := page.text[loc[0]:loc[1]]
+				if _, inCSV := csvIMEIs[seq[:14]]; seen[seq] || inCSV || luhnCheckDigit(seq[:14]) != seq[14] {
+					continue
+				}
+				seen[seq] = true
+
+				u := models.IMEIUnexpected{
+					IMEI:       seq,
+					SourceFile: idx.docs[i].FileName,
+					SourcePage: page.number,
+					Line:       strings.Count(page.text[:loc[0]], "\n") + 1,
+					Context:    textContext(page.text, loc[0], loc[1]),
+				}
+				if number, ok := idx.itemWindows[itemWindowKey{doc: i, window: seq}]; ok {
+					u.GoodsItem = number
+				}
+				idx.report.FileStats[i].Unexpected++
+				out = append(out, u)
+			}
+		}
+	}
+	return out
+}
+
+// contextRadius is the number of bytes kept on each side of a match in long lines.
+const contextRadius = 60
+
+// textContext returns the line containing text[start:end], cut to contextRadius around
+// the match when the line is long (plain text without line breaks).
+func textContext(text string, start, end int) string {
+	lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+	lineEnd := len(text)
+	if n := strings.IndexByte(text[end:], '\n'); n >= 0 {
+		lineEnd = end + n
+	}
+	if start-lineStart > contextRadius {
+		lineStart = start - contextRadius
+	}
+	if lineEnd-end > contextRadius {
+		lineEnd = end + contextRadius
+	}
+	// Do not cut multi-byte characters.
+	for lineStart > 0 && !utf8.RuneStart(text[lineStart]) {
+		lineStart--
+	}
+	for lineEnd < len(text) && !utf8.RuneStart(text[lineEnd]) {
+		lineEnd++
+	}
+	return strings.Join(strings.Fields(text[lineStart:lineEnd]), " ")
+}
+
+// goodsItemStats compares every parsed goods item with its declared quantity.
+// Dual-SIM devices list two IMEIs, so the quantity matches either the IMEI count
+// in graph 31 or the number of matched CSV devices.
+func (idx *documentIndex) goodsItemStats() []models.IMEIGoodsItemStats {
+	var stats []models.IMEIGoodsItemStats
+	for i, doc := range idx.docs {
+		for _, item := range doc.Items {
+			st := models.IMEIGoodsItemStats{
+				SourceFile:       doc.FileName,
+				ItemNumber:       item.Number,
+				Page:             item.Page,
+				DeclaredQuantity: item.DeclaredQuantity,
+				IMEIsInPDF:       len(item.IMEIs),
+				MatchedDevices:   len(idx.itemLines[goodsItemKey{doc: i, item: item.Number}]),
+			}
+			if st.DeclaredQuantity > 0 {
+				ok := st.IMEIsInPDF == st.DeclaredQuantity || st.MatchedDevices == st.DeclaredQuantity
+				st.QuantityMatches = &ok
+			}
+			stats = append(stats, st)
+		}
+	}
+	return stats
+}
+
+// recordField returns the trimmed value of an optional context column.
+func recordField(record []string, cols map[string]int, field string) string {
+	if idx, ok := cols[field]; ok && idx < len(record) {
+		return strings.TrimSpace(record[idx])
+	}
+	return ""
+}
+
+// sortedColumnIndexes returns the CSV indexes of IMEI columns in header order.
+func sortedColumnIndexes(colMap map[int]string) []int {
+	indexes := make([]int, 0, len(colMap))
+	for idx := range colMap {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// classifyIMEI checks the structure of a raw CSV value and fills RawValue, Validity,
+// IMEI14 and ExpectedIMEI. IMEI14 stays empty for values that cannot be matched.
+func classifyIMEI(raw string) models.IMEIMatchResult {
+	res := models.IMEIMatchResult{RawValue: raw}
+
+	for _, r := range raw {
+		if r < '0' || r > '9' {
+			res.Validity = models.IMEINonNumeric
+			return res
+		}
+	}
+
+	switch len(raw) {
+	case 14:
+		res.Validity = models.IMEIValid14
+	case 15:
+		if luhnCheckDigit(raw[:14]) == raw[14] {
+			res.Validity = models.IMEIValid15
+		} else {
+			res.Validity = models.IMEIBadLuhn
+		}
+	default:
+		res.Validity = models.IMEIWrongLength
+		return res
+	}
+
+	res.IMEI14 = raw[:14]
+	res.ExpectedIMEI = res.IMEI14 + string(luhnCheckDigit(res.IMEI14))
+	return res
+}
+
+// luhnCheckDigit computes the Luhn check digit for a string of digits
+// (every second digit from the right of the payload is doubled).
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
 }
 
 func generateTextReport(report *models.IMEIVerificationReport) string {
 	var sb strings.Builder
 
+	// Section titles name the identifier kind: IMEI or serial number.
+	noun := "IMEI"
+	if report.Mode == ModeSerial {
+		noun = "SERIAL NUMBER"
+	}
+
 	sb.WriteString("=========================================\n")
-	sb.WriteString("        IMEI VERIFICATION REPORT\n")
+	if report.Mode == ModeSerial {
+		sb.WriteString("   SERIAL NUMBER VERIFICATION REPORT\n")
+	} else {
+		sb.WriteString("        IMEI VERIFICATION REPORT\n")
+	}
 	sb.WriteString("=========================================\n\n")
 
-	sb.WriteString(fmt.Sprintf("Total IMEIs processed: %d\n", report.TotalIMEIs))
+	if report.Mode == ModeSerial {
+		sb.WriteString(fmt.Sprintf("Match rule: %s\n", report.MatchRule))
+		sb.WriteString(fmt.Sprintf("Total serial numbers processed: %d\n", report.TotalIMEIs))
+	} else {
+		sb.WriteString(fmt.Sprintf("Total IMEIs processed: %d\n", report.TotalIMEIs))
+	}
 	sb.WriteString(fmt.Sprintf("Total Found in PDF: %d\n", report.TotalFound))
-	sb.WriteString(fmt.Sprintf("Total Missing: %d\n\n", report.TotalMissing))
+	sb.WriteString(fmt.Sprintf("Total Missing: %d\n", report.TotalMissing))
+	sb.WriteString(fmt.Sprintf("Total Invalid: %d\n", report.TotalInvalid))
+	sb.WriteString(fmt.Sprintf("Total Unexpected in PDF: %d\n", report.TotalUnexpected))
+	sb.WriteString(fmt.Sprintf("Duplicates in CSV: %d\n", report.TotalDuplicates))
+	if report.ReportID != "" {
+		sb.WriteString(fmt.Sprintf("Previously declared: %d\n", report.TotalPreviouslyDeclared))
+	}
+	if report.TotalPairMismatches > 0 {
+		sb.WriteString(fmt.Sprintf("Dual-SIM pair mismatches (rows): %d\n", report.TotalPairMismatches))
+	}
+	if report.TotalNormalized > 0 {
+		sb.WriteString(fmt.Sprintf("Matched in split numbers (check manually): %d\n", report.TotalNormalized))
+	}
+	if report.TotalTACResolved > 0 {
+		sb.WriteString(fmt.Sprintf("Devices identified by TAC: %d\n", report.TotalTACResolved))
+		sb.WriteString(fmt.Sprintf("Brand mismatches: %d\n", report.TotalBrandMismatches))
+	}
+	sb.WriteString("\n")
+
+	if len(report.DetectedColumns) > 0 {
+		if report.Mode == ModeSerial {
+			sb.WriteString("--- IDENTIFIER COLUMNS ---\n")
+		} else {
+			sb.WriteString("--- DETECTED IMEI COLUMNS ---\n")
+		}
+		for _, col := range report.DetectedColumns {
+			sb.WriteString(fmt.Sprintf("%s (column %d, by %s): %s\n", col.Column, col.Position, col.Method, col.Reason))
+		}
+		sb.WriteString("\n")
+	}
 
 	sb.WriteString("--- STATISTICS BY COLUMN ---\n")
 	for _, stat := range report.ColumnStats {
-		sb.WriteString(fmt.Sprintf("%s: %d processed (%d found, %d missing)\n", stat.Column, stat.Total, stat.Found, stat.Missing))
+		sb.WriteString(fmt.Sprintf("%s: %d processed (%d found, %d missing, %d invalid", stat.Column, stat.Total, stat.Found, stat.Missing, stat.Invalid))
+		if stat.PairMismatches > 0 {
+			sb.WriteString(fmt.Sprintf(", %d in mismatched dual-SIM rows", stat.PairMismatches))
+		}
+		sb.WriteString(")\n")
 	}
 	sb.WriteString("\n")
 
+	if len(report.FileStats) > 1 || (len(report.FileStats) == 1 && report.FileStats[0].Backend != "") {
+		sb.WriteString("--- STATISTICS BY FILE ---\n")
+		for _, stat := range report.FileStats {
+			sb.WriteString(fmt.Sprintf("%s: %d pages, %d IMEI sequences, %d matched", stat.FileName, stat.Pages, stat.IMEISequences, stat.Matched))
+			if stat.Backend != "" {
+				sb.WriteString(fmt.Sprintf(" (extracted by %s)", stat.Backend))
+			}
+			if stat.Repaired {
+				sb.WriteString(" [damaged PDF, cross-reference table rebuilt]")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(report.GoodsItems) > 0 {
+		sb.WriteString("--- GOODS ITEMS (GRAPH 31/32) ---\n")
+		for _, item := range report.GoodsItems {
+			status := "quantity not declared"
+			if item.QuantityMatches != nil {
+				status = "OK"
+				if !*item.QuantityMatches {
+					status = "QUANTITY MISMATCH"
+				}
+			}
+			sb.WriteString(fmt.Sprintf("%s item %d (p. %d): declared %d, %d IMEIs in PDF, %d CSV devices matched - %s\n",
+				item.SourceFile, item.ItemNumber, item.Page, item.DeclaredQuantity, item.IMEIsInPDF, item.MatchedDevices, status))
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalInvalid > 0 {
+		sb.WriteString(fmt.Sprintf("--- INVALID %s VALUES ---\n", noun))
+		for _, res := range report.Results {
+			switch res.Validity {
+			case models.IMEINonNumeric, models.IMEIWrongLength:
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %q (%s)\n", res.CSVLine, res.Column, res.RawValue, res.Validity))
+			case models.IMEIBadLuhn:
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s (bad_luhn, expected %s)\n", res.CSVLine, res.Column, res.RawValue, res.ExpectedIMEI))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	var checkDigitMismatches []models.IMEIMatchResult
+	for _, res := range report.Results {
+		if res.PDFCheckDigitOK != nil && !*res.PDFCheckDigitOK {
+			checkDigitMismatches = append(checkDigitMismatches, res)
+		}
+	}
+	if len(checkDigitMismatches) > 0 {
+		sb.WriteString("--- PDF CHECK DIGIT MISMATCHES ---\n")
+		for _, res := range checkDigitMismatches {
+			sb.WriteString(fmt.Sprintf("Line %d [%s]: PDF has %s, expected %s\n", res.CSVLine, res.Column, res.MatchedIMEI, res.ExpectedIMEI))
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalPairMismatches > 0 {
+		sb.WriteString("--- DUAL-SIM PAIR MISMATCHES ---\n")
+		lastLine := 0
+		for _, res := range report.Results {
+			if res.PairMismatch && res.CSVLine != lastLine {
+				lastLine = res.CSVLine
+				sb.WriteString(fmt.Sprintf("Line %d: %s\n", res.CSVLine, res.PairReason))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalNormalized > 0 {
+		sb.WriteString("--- MATCHED IN SPLIT NUMBERS (CHECK MANUALLY) ---\n")
+		for _, res := range report.Results {
+			if res.Normalized {
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s found as %q%s\n", res.CSVLine, res.Column, imeiLabel(res), res.NormalizedFrom, sourceLabel(res)))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalUnexpected > 0 {
+		sb.WriteString("--- UNEXPECTED IN PDF (NOT IN CSV) ---\n")
+		for _, u := range report.UnexpectedInPDF {
+			loc := fmt.Sprintf("p. %d, line %d", u.SourcePage, u.Line)
+			if u.SourceFile != "" {
+				loc = u.SourceFile + ", " + loc
+			}
+			sb.WriteString(fmt.Sprintf("%s (%s): %s\n", u.IMEI, loc, u.Context))
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalDuplicates > 0 {
+		sb.WriteString(fmt.Sprintf("--- DUPLICATE %ss IN CSV ---\n", noun))
+		for _, res := range report.Results {
+			if res.DuplicateOfLine > 0 {
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s (first seen on line %d [%s])\n", res.CSVLine, res.Column, imeiLabel(res), res.DuplicateOfLine, res.DuplicateOfColumn))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalPreviouslyDeclared > 0 {
+		sb.WriteString("--- PREVIOUSLY DECLARED DEVICES ---\n")
+		for _, res := range report.Results {
+			if prev := res.PreviousDeclaration; prev != nil {
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s (verified %s in %s", res.CSVLine, res.Column, imeiLabel(res),
+					prev.VerifiedAt.Format("2006-01-02"), previousDeclarationName(prev)))
+				if prev.ReportID != nil {
+					sb.WriteString(fmt.Sprintf(", report %s", prev.ReportID))
+				}
+				sb.WriteString(")\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
 	if report.TotalMissing > 0 {
-		sb.WriteString("--- MISSING IMEI DETAILS ---\n")
+		sb.WriteString(fmt.Sprintf("--- MISSING %s DETAILS ---\n", noun))
 		for _, res := range report.Results {
 			if !res.Found {
-				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s (Missing)\n", res.CSVLine, res.Column, res.IMEI14))
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s (Missing)\n", res.CSVLine, res.Column, imeiLabel(res)))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if report.TotalBrandMismatches > 0 {
+		sb.WriteString("--- BRAND MISMATCHES ---\n")
+		for _, res := range report.Results {
+			if res.BrandMismatch {
+				sb.WriteString(fmt.Sprintf("Line %d [%s]: %s -> %s\n", res.CSVLine, res.Column, imeiLabel(res), res.MismatchReason))
 			}
 		}
 		sb.WriteString("\n")
@@ -169,11 +815,44 @@ func generateTextReport(report *models.IMEIVerificationReport) string {
 	sb.WriteString("--- FULL MAPPING ---\n")
 	for _, res := range report.Results {
 		if res.Found {
-			sb.WriteString(fmt.Sprintf("Line %d [%s]: %s -> MATCHED: %s\n", res.CSVLine, res.Column, res.IMEI14, res.MatchedIMEI))
+			sb.WriteString(fmt.Sprintf("Line %d [%s]: %s -> MATCHED: %s%s%s\n", res.CSVLine, res.Column, imeiLabel(res), res.MatchedIMEI, sourceLabel(res), deviceLabel(res)))
 		} else {
-			sb.WriteString(fmt.Sprintf("Line %d [%s]: %s -> MISSING\n", res.CSVLine, res.Column, res.IMEI14))
+			sb.WriteString(fmt.Sprintf("Line %d [%s]: %s -> MISSING%s\n", res.CSVLine, res.Column, imeiLabel(res), deviceLabel(res)))
 		}
 	}
 
 	return sb.String()
 }
+
+// imeiLabel is the value shown for a result: the 14-digit IMEI, the serial number or
+// the raw invalid value.
+func imeiLabel(res models.IMEIMatchResult) string {
+	if res.IMEI14 != "" {
+		return res.IMEI14
+	}
+	if res.Identifier != "" {
+		return res.RawValue
+	}
+	return fmt.Sprintf("%q", res.RawValue)
+}
+
+// sourceLabel renders the file, page and goods item a result was matched in.
+func sourceLabel(res models.IMEIMatchResult) string {
+	loc := fmt.Sprintf("p. %d", res.SourcePage)
+	if res.SourceFile != "" {
+		loc = res.SourceFile + ", " + loc
+	}
+	if res.GoodsItem > 0 {
+		loc += fmt.Sprintf(", item %d", res.GoodsItem)
+	}
+	return " (" + loc + ")"
+}
+
+// deviceLabel renders the TAC-resolved device of a result, if any.
+func deviceLabel(res models.IMEIMatchResult) string {
+	device := strings.TrimSpace(res.DeviceManufacturer + " " + res.DeviceModel)
+	if device == "" {
+		return ""
+	}
+	return " [" + device + "]"
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"ats-verify/internal/models"
 )
 
 func TestIMEIServiceAnalyze_Success(t *testing.T) {
@@ -64,3 +68,248 @@ func TestIMEIServiceAnalyze_NoIMEIColumn(t *testing.T) {
 		t.Fatal("expected error for missing IMEI column")
 	}
 }
+
+func TestClassifyIMEI(t *testing.T) {
+	cases := []struct {
+		raw      string
+		validity models.IMEIValidity
+		expected string
+	}{
+		{"490154203237518", models.IMEIValid15, "490154203237518"},
+		{"490154203237519", models.IMEIBadLuhn, "490154203237518"},
+		{"49015420323751", models.IMEIValid14, "490154203237518"},
+		{"4901542032375", models.IMEIWrongLength, ""},
+		{"4901542032375181", models.IMEIWrongLength, ""},
+		{"49015420323751A", models.IMEINonNumeric, ""},
+	}
+
+	for _, c := range cases {
+		res := classifyIMEI(c.raw)
+		if res.Validity != c.validity || res.ExpectedIMEI != c.expected {
+			t.Errorf("%s: expected %s/%q, got %s/%q", c.raw, c.validity, c.expected, res.Validity, res.ExpectedIMEI)
+		}
+	}
+}
+
+func TestIMEIServiceAnalyze_InvalidValues(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := `imei1,imei2
+490154203237519,12345
+N/A,11111111111111`
+
+	pdfText := `490154203237518 111111111111110`
+
+	report, err := svc.Analyze(strings.NewReader(csvContent), pdfText)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.TotalIMEIs != 4 || report.TotalFound != 2 || report.TotalMissing != 2 || report.TotalInvalid != 3 {
+		t.Errorf("expected 4 total / 2 found / 2 missing / 3 invalid, got %d / %d / %d / %d",
+			report.TotalIMEIs, report.TotalFound, report.TotalMissing, report.TotalInvalid)
+	}
+	if report.ColumnStats[0].Column != "imei1" || report.ColumnStats[0].Invalid != 2 || report.ColumnStats[1].Invalid != 1 {
+		t.Errorf("unexpected column stats: %+v", report.ColumnStats)
+	}
+
+	for _, res := range report.Results {
+		switch res.RawValue {
+		case "490154203237519":
+			// Bad Luhn in CSV is still matched by prefix; the PDF value has the right check digit.
+			if !res.Found || res.PDFCheckDigitOK == nil || !*res.PDFCheckDigitOK {
+				t.Errorf("expected bad-Luhn value to match a correct PDF IMEI, got %+v", res)
+			}
+		case "11111111111111":
+			if !res.Found || res.PDFCheckDigitOK == nil || *res.PDFCheckDigitOK {
+				t.Errorf("expected PDF check digit mismatch, got %+v", res)
+			}
+		}
+	}
+
+	for _, want := range []string{"--- INVALID IMEI VALUES ---", `"N/A" (non_numeric)`, `"12345" (wrong_length)`, "expected 111111111111119"} {
+		if !strings.Contains(report.TextReport, want) {
+			t.Errorf("expected text report to contain %q", want)
+		}
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_AttributesFileAndPage(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "imei1,imei2\n49015420323751,35332811000000\n86012345000000,\n"
+	docs := []PDFDocument{
+		{FileName: "part1.pdf", Pages: []string{"Графа 31", "IMEI 490154203237518"}},
+		{FileName: "part2.pdf", Pages: []string{"IMEI 353328110000007 and 888888888888888"}},
+	}
+
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.TotalFound != 2 || report.TotalMissing != 1 {
+		t.Fatalf("expected 2 found / 1 missing, got %d / %d", report.TotalFound, report.TotalMissing)
+	}
+
+	first := report.Results[0]
+	if first.SourceFile != "part1.pdf" || first.SourcePage != 2 || first.MatchedIMEI != "490154203237518" {
+		t.Errorf("expected match on part1.pdf page 2, got %+v", first)
+	}
+	second := report.Results[1]
+	if second.SourceFile != "part2.pdf" || second.SourcePage != 1 {
+		t.Errorf("expected match on part2.pdf page 1, got %+v", second)
+	}
+
+	if len(report.FileStats) != 2 {
+		t.Fatalf("expected 2 file stats, got %d", len(report.FileStats))
+	}
+	if got := report.FileStats[0]; got.Pages != 2 || got.IMEISequences != 1 || got.Matched != 1 {
+		t.Errorf("unexpected part1 stats: %+v", got)
+	}
+	if got := report.FileStats[1]; got.Pages != 1 || got.IMEISequences != 2 || got.Matched != 1 {
+		t.Errorf("unexpected part2 stats: %+v", got)
+	}
+	if !strings.Contains(report.TextReport, "--- STATISTICS BY FILE ---") || !strings.Contains(report.TextReport, "(part2.pdf, p. 1)") {
+		t.Errorf("expected file stats and source in text report, got:\n%s", report.TextReport)
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_MatchesInsideDigitRuns(t *testing.T) {
+	svc := NewIMEIService()
+
+	// The IMEI is part of a longer number on page 1 and a proper 15-digit sequence on
+	// page 2; the page with the full sequence wins.
+	csvContent := "imei1\n35332811000000\n"
+	docs := []PDFDocument{{
+		FileName: "decl.pdf",
+		Pages:    []string{"Рег. № 9935332811000000712", "IMEI 353328110000005"},
+		Items:    []models.DeclarationItem{{Number: 1, Description: "Телефон 99353328110000005"}},
+	}}
+
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	res := report.Results[0]
+	if !res.Found || res.SourcePage != 2 || res.MatchedIMEI != "353328110000005" || res.GoodsItem != 1 {
+		t.Errorf("unexpected match %+v", res)
+	}
+
+	// Only a bare window: the first page wins, like a substring search.
+	docs[0].Pages[1] = "Без номера"
+	report, err = svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res := report.Results[0]; res.SourcePage != 1 || res.MatchedIMEI != "(prefix matched in text)" {
+		t.Errorf("unexpected match %+v", res)
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_SamePrefixOnTwoPages(t *testing.T) {
+	svc := NewIMEIService()
+
+	// Both pages hold a 15-digit sequence with the same 14-digit prefix; the one with
+	// the expected check digit is reported.
+	csvContent := "imei1\n353328110000005\n"
+	docs := []PDFDocument{{
+		FileName: "decl.pdf",
+		Pages:    []string{"IMEI 353328110000001", "IMEI 353328110000005"},
+	}}
+
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	res := report.Results[0]
+	if !res.Found || res.SourcePage != 2 || res.MatchedIMEI != "353328110000005" || res.PDFCheckDigitOK == nil || !*res.PDFCheckDigitOK {
+		t.Errorf("expected the matching sequence on page 2, got %+v", res)
+	}
+}
+
+func TestIMEIServiceAnalyzeDocuments_UnexpectedInPDF(t *testing.T) {
+	svc := NewIMEIService()
+
+	csvContent := "imei1\n49015420323751\n"
+	docs := []PDFDocument{
+		{FileName: "part1.pdf", Pages: []string{"Товар 1\nIMEI 490154203237518\nIMEI 353328110000005 (не в CSV)"}},
+		{FileName: "part2.pdf", Pages: []string{"Код 123456789012345\n353328110000005\n356938035643809"}},
+	}
+
+	report, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 123456789012345 fails the Luhn check; 353328110000005 is reported once.
+	if report.TotalUnexpected != 2 || len(report.UnexpectedInPDF) != 2 {
+		t.Fatalf("expected 2 unexpected IMEIs, got %+v", report.UnexpectedInPDF)
+	}
+	first := report.UnexpectedInPDF[0]
+	if first.IMEI != "353328110000005" || first.SourceFile != "part1.pdf" || first.SourcePage != 1 || first.Line != 3 {
+		t.Errorf("unexpected first entry: %+v", first)
+	}
+	if first.Context != "IMEI 353328110000005 (не в CSV)" {
+		t.Errorf("expected the PDF line as context, got %q", first.Context)
+	}
+	if second := report.UnexpectedInPDF[1]; second.IMEI != "356938035643809" || second.SourceFile != "part2.pdf" || second.Line != 3 {
+		t.Errorf("unexpected second entry: %+v", second)
+	}
+	if report.FileStats[0].Unexpected != 1 || report.FileStats[1].Unexpected != 1 {
+		t.Errorf("expected one unexpected IMEI per file, got %+v", report.FileStats)
+	}
+	if !strings.Contains(report.TextReport, "--- UNEXPECTED IN PDF (NOT IN CSV) ---") {
+		t.Errorf("expected unexpected section in text report, got:\n%s", report.TextReport)
+	}
+}
+
+func TestTextContext_LongLine(t *testing.T) {
+	text := strings.Repeat("я", 100) + " 356938035643809 " + strings.Repeat("b", 100)
+	start := strings.Index(text, "356938035643809")
+	got := textContext(text, start, start+15)
+	if !strings.Contains(got, "356938035643809") || len(got) > 15+2*contextRadius+4 {
+		t.Errorf("expected a short window around the IMEI, got %q", got)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("expected valid UTF-8, got %q", got)
+	}
+}
+
+// benchmarkIMEIDocuments builds a CSV of n devices and a declaration listing every
+// device, 50 per page, with every tenth device left out.
+func benchmarkIMEIDocuments(n int) (string, []PDFDocument) {
+	var csvSB, pageSB strings.Builder
+	csvSB.WriteString("imei1\n")
+	var pages []string
+	for i := 0; i < n; i++ {
+		imei14 := fmt.Sprintf("35%012d", i)
+		csvSB.WriteString(imei14 + "\n")
+		if i%10 != 0 {
+			fmt.Fprintf(&pageSB, "Смартфон IMEI %s%c\n", imei14, luhnCheckDigit(imei14))
+		}
+		if (i+1)%50 == 0 {
+			pages = append(pages, pageSB.String())
+			pageSB.Reset()
+		}
+	}
+	pages = append(pages, pageSB.String())
+	return csvSB.String(), []PDFDocument{{FileName: "decl.pdf", Pages: pages}}
+}
+
+// BenchmarkIMEIServiceAnalyzeDocuments reports ns per IMEI, which stays flat as the
+// declaration grows (linear scaling).
+func BenchmarkIMEIServiceAnalyzeDocuments(b *testing.B) {
+	svc := NewIMEIService()
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		csvContent, docs := benchmarkIMEIDocuments(n)
+		b.Run(fmt.Sprintf("imeis=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := svc.AnalyzeDocuments(strings.NewReader(csvContent), docs); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/imei")
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// NotificationService delivers in-app notifications to users, roles and marketplaces.
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	userRepo         *repository.UserRepository
+}
+
+// NewNotificationService creates a new NotificationService.
+func NewNotificationService(notificationRepo *repository.NotificationRepository, userRepo *repository.UserRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo, userRepo: userRepo}
+}
+
+// NotifyUser stores a notification for a single user.
+func (s *NotificationService) NotifyUser(ctx context.Context, userID uuid.UUID, n models.Notification) error {
+	n.UserID = userID
+	if _, err := s.notificationRepo.Create(ctx, &n); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NotifyRole stores a copy of the notification for every approved user with the given role.
+func (s *NotificationService) NotifyRole(ctx context.Context, role models.UserRole, n models.Notification) (int, error) {
+	users, err := s.userRepo.ListApprovedByRole(ctx, role)
+	if err != nil {
+		return 0, err
+	}
+	return s.notifyUsers(ctx, users, n)
+}
+
+// NotifyMarketplace stores a copy of the notification for every marketplace user
+// whose prefix resolves to the given marketplace name (e.g. "wb" → "Wildberries").
+func (s *NotificationService) NotifyMarketplace(ctx context.Context, marketplace string, n models.Notification) (int, error) {
+	users, err := s.userRepo.ListApprovedByRole(ctx, models.RoleMarketplace)
+	if err != nil {
+		return 0, err
+	}
+
+	var recipients []models.User
+	for _, u := range users {
+		if u.MarketplacePrefix != nil && marketplaceMatchesPrefix(marketplace, *u.MarketplacePrefix) {
+			recipients = append(recipients, u)
+		}
+	}
+	return s.notifyUsers(ctx, recipients, n)
+}
+
+func (s *NotificationService) notifyUsers(ctx context.Context, users []models.User, n models.Notification) (int, error) {
+	sent := 0
+	for _, u := range users {
+		if err := s.NotifyUser(ctx, u.ID, n); err != nil {
+			return sent, fmt.Errorf("notifying %s: %w", u.Username, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// NotificationList is the response for a user's notification feed.
+type NotificationList struct {
+	Notifications []models.Notification `json:"notifications"`
+	Unread        int                   `json:"unread"`
+}
+
+// List returns the latest notifications of a user.
+func (s *NotificationService) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) (*NotificationList, error) {
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	notifications, err := s.notificationRepo.ListByUser(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationList{Notifications: notifications, Unread: unread}, nil
+}
+
+// MarkRead marks a notification of the user as read.
+func (s *NotificationService) MarkRead(ctx context.Context, id, userID uuid.UUID) error {
+	return s.notificationRepo.MarkRead(ctx, id, userID)
+}
+
+// marketplaceMatchesPrefix reports whether a user's marketplace prefix belongs to the
+// marketplace name stored on parcels. Upload handlers store either the mapped name or the raw prefix.
+func marketplaceMatchesPrefix(marketplace, prefix string) bool {
+	marketplace = strings.TrimSpace(marketplace)
+	if marketplace == "" {
+		return false
+	}
+	if name, ok := models.MarketplacePrefixMap[prefix]; ok && strings.EqualFold(name, marketplace) {
+		return true
+	}
+	return strings.EqualFold(prefix, marketplace)
+}
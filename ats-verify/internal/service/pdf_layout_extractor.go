@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	rscpdf "rsc.io/pdf"
+)
+
+// LayoutPDFExtractor is a pure-Go TextExtractor on top of rsc.io/pdf. It rebuilds
+// lines from glyph positions instead of content-stream order, which helps with
+// multi-column declaration layouts where ledongthuc/pdf interleaves columns.
+type LayoutPDFExtractor struct{}
+
+// NewLayoutPDFExtractor creates a new LayoutPDFExtractor.
+func NewLayoutPDFExtractor() *LayoutPDFExtractor {
+	return &LayoutPDFExtractor{}
+}
+
+// Name implements TextExtractor.
+func (e *LayoutPDFExtractor) Name() string {
+	return "rscpdf"
+}
+
+// Extract implements TextExtractor.
+func (e *LayoutPDFExtractor) Extract(_ context.Context, data []byte, password string) (text *ExtractedText, err error) {
+	defer recoverPDFPanic(&err)
+
+	reader, err := rscpdf.NewReaderEncrypted(bytes.NewReader(data), int64(len(data)), pdfPassword(password))
+	if err != nil {
+		return nil, fmt.Errorf("opening PDF: %w", classifyOpenError(err, password))
+	}
+
+	text = &ExtractedText{Pages: make([]string, reader.NumPage())}
+	for i := range text.Pages {
+		page := reader.Page(i + 1)
+		if page.V.IsNull() {
+			continue
+		}
+		var glyphs []glyph
+		for _, t := range page.Content().Text {
+			glyphs = append(glyphs, glyph{x: t.X, y: t.Y, w: t.W, size: t.FontSize, s: t.S})
+		}
+		fragments := mergeGlyphs(i+1, glyphs)
+		text.Fragments = append(text.Fragments, fragments...)
+		text.Pages[i] = layoutPageText(fragments)
+	}
+	return text, nil
+}
+
+// glyph is a single drawn character.
+type glyph struct {
+	x, y, w, size float64
+	s             string
+}
+
+// Glyph gaps relative to the font size: a word space and a column break.
+const (
+	wordGapRatio   = 0.25
+	columnGapRatio = 2.0
+)
+
+// mergeGlyphs groups glyphs into lines and lines into fragments. A gap wider than a
+// word space inserts a space; a gap wider than columnGapRatio starts a new fragment.
+func mergeGlyphs(page int, glyphs []glyph) []TextFragment {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if glyphs[i].y != glyphs[j].y {
+			return glyphs[i].y > glyphs[j].y
+		}
+		return glyphs[i].x < glyphs[j].x
+	})
+
+	// Group into lines by baseline.
+	var lines [][]glyph
+	for _, g := range glyphs {
+		n := len(lines)
+		if n > 0 && lines[n-1][0].y-g.y <= lineTolerance {
+			lines[n-1] = append(lines[n-1], g)
+			continue
+		}
+		lines = append(lines, []glyph{g})
+	}
+
+	var fragments []TextFragment
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].x < line[j].x })
+
+		var sb strings.Builder
+		start := line[0]
+		end := start.x + start.w
+		sb.WriteString(start.s)
+		for _, g := range line[1:] {
+			size := g.size
+			if size <= 0 {
+				size = 10
+			}
+			gap := g.x - end
+			switch {
+			case gap > columnGapRatio*size:
+				fragments = append(fragments, TextFragment{Page: page, X: start.x, Y: start.y, S: sb.String()})
+				sb.Reset()
+				start = g
+			case gap > wordGapRatio*size:
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(g.s)
+			end = g.x + g.w
+		}
+		fragments = append(fragments, TextFragment{Page: page, X: start.x, Y: start.y, S: sb.String()})
+	}
+	return fragments
+}
+
+// layoutPageText renders fragments as lines of text, top to bottom.
+func layoutPageText(fragments []TextFragment) string {
+	var sb strings.Builder
+	for _, line := range groupLayoutLines(fragments) {
+		for i, f := range line.fragments {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(f.S)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
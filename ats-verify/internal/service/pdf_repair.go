@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// reObjHeader matches an indirect object header, e.g. "12 0 obj".
+	reObjHeader = regexp.MustCompile(`(?:^|\s)(\d{1,7})\s+(\d{1,5})\s+obj\b`)
+	// reTrailerRef matches a trailer entry that references an object, e.g. "/Root 1 0 R".
+	reTrailerRef = regexp.MustCompile(`/(Root|Info|Encrypt)\s+(\d+\s+\d+\s+R)\b`)
+	// reTrailerID matches the file identifier array of a trailer.
+	reTrailerID = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	// reCatalog matches the type entry of the document catalog.
+	reCatalog = regexp.MustCompile(`/Type\s*/Catalog\b`)
+)
+
+// maxRepairObjects caps the object numbers of a rebuilt cross-reference table.
+const maxRepairObjects = 1 << 20
+
+// repairedObject is the position of an object found by scanning the file.
+type repairedObject struct {
+	offset int
+	gen    int
+}
+
+// repairPDFXref rebuilds the cross-reference table of a PDF whose table is missing,
+// points to wrong offsets or was cut off with the end of the file. It scans the file
+// for object headers (the last definition of an object wins, as with incremental
+// updates), takes /Root, /Info, /Encrypt and /ID from the last trailer and appends a
+// new table, trailer and %%EOF. It reports false when the file cannot be repaired this
+// way, e.g. when objects are packed into object streams.
+func repairPDFXref(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || bytes.Contains(data, []byte("/ObjStm")) {
+		return nil, false
+	}
+
+	objects := make(map[int]repairedObject)
+	headers := reObjHeader.FindAllSubmatchIndex(data, -1)
+	maxNum := 0
+	for _, m := range headers {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(data[m[4]:m[5]]))
+		if num == 0 || num >= maxRepairObjects {
+			continue
+		}
+		objects[num] = repairedObject{offset: m[2], gen: gen}
+		maxNum = max(maxNum, num)
+	}
+	if len(objects) == 0 {
+		return nil, false
+	}
+
+	refs := make(map[string]string)
+	for _, m := range reTrailerRef.FindAllSubmatch(data, -1) {
+		refs[string(m[1])] = string(m[2])
+	}
+	if refs["Root"] == "" {
+		// No trailer survived: the catalog is the object that contains /Type /Catalog.
+		loc := reCatalog.FindIndex(data)
+		if loc == nil {
+			return nil, false
+		}
+		catalog, best := 0, -1
+		for num, obj := range objects {
+			if obj.offset < loc[0] && obj.offset > best {
+				catalog, best = num, obj.offset
+			}
+		}
+		if catalog == 0 {
+			return nil, false
+		}
+		refs["Root"] = fmt.Sprintf("%d %d R", catalog, objects[catalog].gen)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	xrefOffset := buf.Len()
+	size := maxNum + 1
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		if obj, ok := objects[num]; ok {
+			fmt.Fprintf(&buf, "%010d %05d n \n", obj.offset, obj.gen)
+		} else {
+			buf.WriteString("0000000000 00000 f \n")
+		}
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %s", size, refs["Root"])
+	for _, key := range []string{"Info", "Encrypt"} {
+		if ref := refs[key]; ref != "" {
+			fmt.Fprintf(&buf, " /%s %s", key, ref)
+		}
+	}
+	if ids := reTrailerID.FindAll(data, -1); len(ids) > 0 {
+		buf.WriteByte(' ')
+		buf.Write(ids[len(ids)-1])
+	}
+	fmt.Fprintf(&buf, " >>\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes(), true
+}
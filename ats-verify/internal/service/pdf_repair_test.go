@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// testPDF renders a one-page PDF with the given text; an empty text gives a blank page.
+func testPDF(t *testing.T, text string) []byte {
+	t.Helper()
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.AddPage()
+	if text != "" {
+		doc.SetFont("Helvetica", "", 12)
+		doc.Cell(0, 10, text)
+	}
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testEncryptedPDF writes a one-page PDF encrypted with the standard security handler
+// (RC4, 128-bit key, revision 3). fpdf's own protection also encrypts strings inside
+// content streams, which no reader undoes.
+func testEncryptedPDF(t *testing.T, text, password string) []byte {
+	t.Helper()
+	padding := []byte("\x28\xbf\x4e\x5e\x4e\x75\x8a\x41\x64\x00\x4e\x56\xff\xfa\x01\x08" +
+		"\x2e\x2e\x00\xb6\xd0\x68\x3e\x80\x2f\x0c\xa9\xfe\x64\x53\x69\x7a")
+	crypt := func(key, data []byte) []byte {
+		c, err := rc4.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(data))
+		c.XORKeyStream(out, data)
+		return out
+	}
+
+	perms := int32(-60)
+	id := []byte("0123456789abcdef")
+	o := bytes.Repeat([]byte{'O'}, 32) // Only checked when opening with the owner password
+	sum := md5.Sum(bytes.Join([][]byte{append([]byte(password), padding...)[:32], o, binary.LittleEndian.AppendUint32(nil, uint32(perms)), id}, nil))
+	for range 50 {
+		sum = md5.Sum(sum[:])
+	}
+	key := sum[:]
+	check := md5.Sum(append(bytes.Clone(padding), id...))
+	u := check[:]
+	for i := range 20 {
+		k := bytes.Clone(key)
+		for j := range k {
+			k[j] ^= byte(i)
+		}
+		u = crypt(k, u)
+	}
+	u = append(u, make([]byte, 16)...)
+	objectKey := md5.Sum(append(bytes.Clone(key), 5, 0, 0, 0, 0)) // object 5, generation 0
+	content := crypt(objectKey[:], []byte(fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)))
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		fmt.Sprintf("<< /Filter /Standard /V 2 /R 3 /Length 128 /O <%x> /U <%x> /P %d >>", o, u, perms),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Encrypt 6 0 R /ID [<%x> <%x>] >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, id, id, xref)
+	return buf.Bytes()
+}
+
+func testPDFExtractor() *MultiExtractor {
+	return NewMultiExtractor([]TextExtractor{NewPDFExtractor(), NewLayoutPDFExtractor()})
+}
+
+func TestMultiExtractor_RepairsDamagedXref(t *testing.T) {
+	data := testPDF(t, "IMEI 490154203237518")
+	xref := bytes.LastIndex(data, []byte("\nxref"))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		// The end of the file with the table and the trailer was lost.
+		{"truncated", data[:xref+1]},
+		// startxref points into the middle of an object.
+		{"wrong startxref", regexp.MustCompile(`startxref\s+\d+`).ReplaceAll(bytes.Clone(data), []byte("startxref\n20"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := testPDFExtractor().ExtractDocument(context.Background(), "dt.pdf", tt.data, "")
+			if err != nil {
+				t.Fatalf("expected repaired PDF to be read, got %v", err)
+			}
+			if !doc.Repaired || !strings.Contains(strings.Join(doc.Pages, ""), "490154203237518") {
+				t.Errorf("expected IMEI from the repaired PDF, got %+v", doc)
+			}
+		})
+	}
+
+	if _, ok := repairPDFXref([]byte("not a PDF")); ok {
+		t.Error("expected non-PDF data not to be repaired")
+	}
+}
+
+func TestMultiExtractor_EncryptedPDF(t *testing.T) {
+	data := testEncryptedPDF(t, "IMEI 490154203237518", "secret")
+	m := testPDFExtractor()
+
+	for password, want := range map[string]string{"": "password is required", "wrong": "wrong password"} {
+		_, err := m.ExtractDocument(context.Background(), "dt.pdf", data, password)
+		if !errors.Is(err, ErrPDFEncrypted) || !strings.Contains(err.Error(), want) {
+			t.Errorf("password %q: expected %q error, got %v", password, want, err)
+		}
+	}
+
+	doc, err := m.ExtractDocument(context.Background(), "dt.pdf", data, "secret")
+	if err != nil {
+		t.Fatalf("expected no error with the password, got %v", err)
+	}
+	if !strings.Contains(strings.Join(doc.Pages, ""), "490154203237518") {
+		t.Errorf("expected decrypted text, got %q", doc.Pages)
+	}
+}
+
+func TestMultiExtractor_PDFErrorCodes(t *testing.T) {
+	m := testPDFExtractor()
+	ctx := context.Background()
+
+	_, err := m.ExtractDocument(ctx, "scan.pdf", testPDF(t, ""), "")
+	if PDFErrorCode(err) != PDFErrorNoTextLayer || !strings.Contains(err.Error(), "scanned image") {
+		t.Errorf("expected no_text_layer error, got %v", err)
+	}
+
+	_, err = m.ExtractDocument(ctx, "junk.pdf", []byte("%PDF-1.4\ngarbage"), "")
+	if PDFErrorCode(err) != PDFErrorCorrupt {
+		t.Errorf("expected corrupt error, got %v", err)
+	}
+
+	_, err = m.ExtractDocument(ctx, "big.pdf", make([]byte, MaxPDFBytes+1), "")
+	if PDFErrorCode(err) != PDFErrorTooLarge {
+		t.Errorf("expected too_large error, got %v", err)
+	}
+}
@@ -2,17 +2,84 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/ledongthuc/pdf"
+	rscpdf "rsc.io/pdf"
 )
 
+// MaxPDFBytes is the largest PDF accepted for text extraction.
+const MaxPDFBytes = 50 << 20
+
+// PDF extraction failures. Backends wrap them so that callers can tell the user what
+// to do with the file; PDFErrorCode maps them to API error codes.
+var (
+	ErrPDFEncrypted   = errors.New("PDF is encrypted")
+	ErrPDFNoTextLayer = errors.New("PDF has no text layer")
+	ErrPDFCorrupt     = errors.New("PDF is damaged")
+	ErrPDFTooLarge    = errors.New("PDF is too large")
+)
+
+// PDF error codes returned by the API.
+const (
+	PDFErrorEncrypted   = "encrypted"
+	PDFErrorNoTextLayer = "no_text_layer"
+	PDFErrorCorrupt     = "corrupt"
+	PDFErrorTooLarge    = "too_large"
+)
+
+// PDFErrorCode returns the API error code of a PDF extraction error, or "" when the
+// error is not one of the PDF failures.
+func PDFErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrPDFTooLarge):
+		return PDFErrorTooLarge
+	case errors.Is(err, ErrPDFEncrypted):
+		return PDFErrorEncrypted
+	case errors.Is(err, ErrPDFNoTextLayer):
+		return PDFErrorNoTextLayer
+	case errors.Is(err, ErrPDFCorrupt):
+		return PDFErrorCorrupt
+	}
+	return ""
+}
+
+// classifyOpenError wraps an error of opening a PDF with ErrPDFEncrypted or ErrPDFCorrupt.
+func classifyOpenError(err error, password string) error {
+	switch {
+	case errors.Is(err, pdf.ErrInvalidPassword) || errors.Is(err, rscpdf.ErrInvalidPassword):
+		if password == "" {
+			return fmt.Errorf("%w: a password is required (pdf_password)", ErrPDFEncrypted)
+		}
+		return fmt.Errorf("%w: wrong password", ErrPDFEncrypted)
+	case strings.Contains(err.Error(), "encryption"):
+		// Unsupported encryption (e.g. AES-256) or broken encryption parameters.
+		return fmt.Errorf("%w: %v", ErrPDFEncrypted, err)
+	}
+	return fmt.Errorf("%w: %v", ErrPDFCorrupt, err)
+}
+
+// pdfPassword returns a password callback for NewReaderEncrypted that offers password
+// once; nil when there is no password.
+func pdfPassword(password string) func() string {
+	if password == "" {
+		return nil
+	}
+	return func() string {
+		pw := password
+		password = ""
+		return pw
+	}
+}
+
 // PDFExtractor extracts plain text from PDF files.
-// Primary implementation uses ledongthuc/pdf.
-// If extraction quality is insufficient for complex layouts (Graph 31),
-// fallback to Python PyMuPDF sidecar (see findings.md).
+// Primary implementation uses ledongthuc/pdf. It is one TextExtractor backend;
+// MultiExtractor compares it with the others and falls back to the PyMuPDF sidecar.
 type PDFExtractor struct{}
 
 // NewPDFExtractor creates a new PDFExtractor.
@@ -24,7 +91,7 @@ func NewPDFExtractor() *PDFExtractor {
 func (e *PDFExtractor) ExtractTextFromFile(filePath string) (string, error) {
 	f, reader, err := pdf.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("opening PDF %s: %w", filePath, err)
+		return "", fmt.Errorf("opening PDF %s: %w", filePath, classifyOpenError(err, ""))
 	}
 	defer f.Close()
 
@@ -49,6 +116,77 @@ func (e *PDFExtractor) ExtractTextFromReader(r io.Reader) (string, error) {
 	return e.ExtractTextFromFile(tmpFile.Name())
 }
 
+// Name implements TextExtractor.
+func (e *PDFExtractor) Name() string {
+	return "ledongthuc"
+}
+
+// Extract implements TextExtractor. Positioned text is best-effort: when layout
+// extraction fails only the page text is returned.
+func (e *PDFExtractor) Extract(_ context.Context, data []byte, password string) (text *ExtractedText, err error) {
+	defer recoverPDFPanic(&err)
+
+	reader, err := pdf.NewReaderEncrypted(bytes.NewReader(data), int64(len(data)), pdfPassword(password))
+	if err != nil {
+		return nil, fmt.Errorf("opening PDF: %w", classifyOpenError(err, password))
+	}
+
+	pages, err := extractPages(reader)
+	if err != nil {
+		return nil, err
+	}
+	text = &ExtractedText{Pages: pages}
+	if fragments, err := extractFragments(reader); err == nil {
+		text.Fragments = fragments
+	}
+	return text, nil
+}
+
+// extractPages reads the plain text of every page.
+func extractPages(reader *pdf.Reader) ([]string, error) {
+	fonts := make(map[string]*pdf.Font)
+	pages := make([]string, reader.NumPage())
+	for i := range pages {
+		page := reader.Page(i + 1)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("extracting text of page %d: %w", i+1, err)
+		}
+		pages[i] = text
+	}
+	return pages, nil
+}
+
+// extractFragments reads positioned text of every page.
+func extractFragments(reader *pdf.Reader) ([]TextFragment, error) {
+	var fragments []TextFragment
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("extracting layout of page %d: %w", i, err)
+		}
+		for _, row := range rows {
+			for _, t := range row.Content {
+				fragments = append(fragments, TextFragment{Page: i, X: t.X, Y: t.Y, S: t.S})
+			}
+		}
+	}
+	return fragments, nil
+}
+
 // extractText reads all plain text from a pdf.Reader.
 func extractText(reader *pdf.Reader) (string, error) {
 	textReader, err := reader.GetPlainText()
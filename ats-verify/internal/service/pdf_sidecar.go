@@ -0,0 +1,83 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SidecarExtractor is a TextExtractor backed by the PyMuPDF sidecar over HTTP.
+//
+// Protocol: POST {baseURL}/extract with the raw PDF (Content-Type: application/pdf) and,
+// for encrypted PDFs, the password in the X-PDF-Password header.
+// Response: {"pages": ["..."], "fragments": [{"page": 1, "x": 0, "y": 0, "text": "..."}]}.
+// Fragment coordinates are in PDF user space (points, origin at the bottom-left corner).
+type SidecarExtractor struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewSidecarExtractor creates a new SidecarExtractor. Returns nil when baseURL is empty (sidecar disabled).
+func NewSidecarExtractor(baseURL string, timeout time.Duration) *SidecarExtractor {
+	if baseURL == "" {
+		return nil
+	}
+	return &SidecarExtractor{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements TextExtractor.
+func (e *SidecarExtractor) Name() string {
+	return "sidecar"
+}
+
+type sidecarResponse struct {
+	Pages     []string `json:"pages"`
+	Fragments []struct {
+		Page int     `json:"page"`
+		X    float64 `json:"x"`
+		Y    float64 `json:"y"`
+		Text string  `json:"text"`
+	} `json:"fragments"`
+}
+
+// Extract implements TextExtractor.
+func (e *SidecarExtractor) Extract(ctx context.Context, data []byte, password string) (*ExtractedText, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/extract", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("sidecar: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	if password != "" {
+		req.Header.Set("X-PDF-Password", password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("sidecar: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out sidecarResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("sidecar: decoding response: %w", err)
+	}
+
+	text := &ExtractedText{Pages: out.Pages}
+	for _, f := range out.Fragments {
+		text.Fragments = append(text.Fragments, TextFragment{Page: f.Page, X: f.X, Y: f.Y, S: f.Text})
+	}
+	return text, nil
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// providerStatusError is returned by trackers when a provider answers with a non-200 status.
+type providerStatusError struct {
+	Provider   string
+	StatusCode int
+}
+
+func (e *providerStatusError) Error() string {
+	return fmt.Sprintf("%s: status %d", e.Provider, e.StatusCode)
+}
+
+// isProviderFailure reports whether err means the provider itself is degraded: a
+// network error, a timeout, a cut-off response, or a 5xx/429 status. Client errors such
+// as 404 for an unknown track and unparseable responses are not held against the
+// provider; retrying them cannot help.
+func isProviderFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *providerStatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ResilienceOptions configures the protection applied to a single provider.
+type ResilienceOptions struct {
+	RatePerSecond    float64       // Token bucket refill rate
+	Burst            int           // Token bucket capacity
+	MaxRetries       int           // Retries after the first attempt (provider failures only)
+	RetryBaseDelay   time.Duration // Base delay for exponential backoff with full jitter
+	RetryMaxDelay    time.Duration // Upper bound for a single backoff delay
+	FailureThreshold int           // Consecutive failures that open the breaker
+	OpenTimeout      time.Duration // Time the breaker stays open before a half-open probe
+	MaxConcurrent    int           // Maximum in-flight calls to the provider (0 = unlimited)
+}
+
+// DefaultResilienceOptions returns conservative defaults for public carrier APIs.
+func DefaultResilienceOptions() ResilienceOptions {
+	return ResilienceOptions{
+		RatePerSecond:    5,
+		Burst:            10,
+		MaxRetries:       2,
+		RetryBaseDelay:   200 * time.Millisecond,
+		RetryMaxDelay:    2 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		MaxConcurrent:    4,
+	}
+}
+
+// TrackerHealth is a point-in-time health snapshot of one provider.
+type TrackerHealth struct {
+	Provider            string     `json:"provider"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	WindowRequests      int        `json:"window_requests"` // Calls in the sliding window used for the stats below
+	ErrorRate           float64    `json:"error_rate"`
+	LatencyP50Ms        int64      `json:"latency_p50_ms"`
+	LatencyP90Ms        int64      `json:"latency_p90_ms"`
+	LatencyP99Ms        int64      `json:"latency_p99_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// HealthReporter is implemented by trackers that expose health statistics.
+type HealthReporter interface {
+	Health() TrackerHealth
+}
+
+// healthWindowSize is the number of recent calls kept for error rate and latency stats.
+const healthWindowSize = 200
+
+type callOutcome struct {
+	duration time.Duration
+	failed   bool
+}
+
+// ResilientTracker wraps a Tracker with a token-bucket rate limit, a concurrency cap,
+// retries with jittered exponential backoff and a circuit breaker. It implements Tracker itself.
+type ResilientTracker struct {
+	next Tracker
+	opts ResilienceOptions
+	now  func() time.Time
+	sem  chan struct{} // nil when concurrency is unlimited
+
+	mu sync.Mutex
+
+	// Token bucket.
+	tokens     float64
+	lastRefill time.Time
+
+	// Circuit breaker.
+	state         string
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+
+	// Sliding window of recent calls.
+	window      []callOutcome
+	windowNext  int
+	lastErr     string
+	lastErrTime time.Time
+}
+
+// NewResilientTracker wraps next with the given protection options.
+func NewResilientTracker(next Tracker, opts ResilienceOptions) *ResilientTracker {
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+	t := &ResilientTracker{
+		next:       next,
+		opts:       opts,
+		now:        time.Now,
+		tokens:     float64(opts.Burst),
+		lastRefill: time.Now(),
+		state:      BreakerClosed,
+	}
+	if opts.MaxConcurrent > 0 {
+		t.sem = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return t
+}
+
+func (t *ResilientTracker) Provider() string { return t.next.Provider() }
+
+func (t *ResilientTracker) Track(ctx context.Context, trackNumber string) ([]models.TrackingEvent, error) {
+	var lastErr error
+	for attempt := 0; attempt <= t.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, t.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := t.allow(); err != nil {
+			return nil, err
+		}
+		if err := t.acquire(ctx); err != nil {
+			t.release()
+			return nil, err
+		}
+		if err := t.waitToken(ctx); err != nil {
+			t.unacquire()
+			t.release()
+			return nil, err
+		}
+
+		start := t.now()
+		events, err := t.next.Track(ctx, trackNumber)
+		t.record(t.now().Sub(start), err)
+		t.unacquire()
+
+		if !isProviderFailure(err) {
+			return events, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// allow checks the breaker and reserves the half-open probe slot if needed.
+func (t *ResilientTracker) allow() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case BreakerOpen:
+		if t.now().Sub(t.openedAt) < t.opts.OpenTimeout {
+			return fmt.Errorf("%s: %w", t.next.Provider(), ErrCircuitOpen)
+		}
+		t.state = BreakerHalfOpen
+		t.probeInFlight = true
+		return nil
+	case BreakerHalfOpen:
+		if t.probeInFlight {
+			return fmt.Errorf("%s: %w", t.next.Provider(), ErrCircuitOpen)
+		}
+		t.probeInFlight = true
+	}
+	return nil
+}
+
+// release gives back a half-open probe slot that was reserved but not used.
+func (t *ResilientTracker) release() {
+	t.mu.Lock()
+	t.probeInFlight = false
+	t.mu.Unlock()
+}
+
+// record updates the breaker and the sliding window with the outcome of a call.
+func (t *ResilientTracker) record(d time.Duration, err error) {
+	failed := isProviderFailure(err)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	outcome := callOutcome{duration: d, failed: failed}
+	if len(t.window) < healthWindowSize {
+		t.window = append(t.window, outcome)
+	} else {
+		t.window[t.windowNext] = outcome
+	}
+	t.windowNext = (t.windowNext + 1) % healthWindowSize
+
+	t.probeInFlight = false
+	if !failed {
+		t.failures = 0
+		t.state = BreakerClosed
+		return
+	}
+
+	t.failures++
+	t.lastErr = err.Error()
+	t.lastErrTime = t.now()
+	if t.state == BreakerHalfOpen || t.failures >= t.opts.FailureThreshold {
+		t.state = BreakerOpen
+		t.openedAt = t.now()
+	}
+}
+
+// acquire takes a concurrency slot, waiting until one is free or ctx is done.
+func (t *ResilientTracker) acquire(ctx context.Context) error {
+	if t.sem == nil {
+		return nil
+	}
+	select {
+	case t.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unacquire frees a concurrency slot taken by acquire.
+func (t *ResilientTracker) unacquire() {
+	if t.sem != nil {
+		<-t.sem
+	}
+}
+
+// waitToken blocks until the token bucket has a token or ctx is done.
+func (t *ResilientTracker) waitToken(ctx context.Context) error {
+	if t.opts.RatePerSecond <= 0 {
+		return nil
+	}
+	for {
+		t.mu.Lock()
+		now := t.now()
+		t.tokens = math.Min(float64(t.opts.Burst), t.tokens+now.Sub(t.lastRefill).Seconds()*t.opts.RatePerSecond)
+		t.lastRefill = now
+		if t.tokens >= 1 {
+			t.tokens--
+			t.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - t.tokens) / t.opts.RatePerSecond * float64(time.Second))
+		t.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns a full-jitter exponential delay for the given retry attempt (1-based).
+func (t *ResilientTracker) backoff(attempt int) time.Duration {
+	ceiling := t.opts.RetryBaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > t.opts.RetryMaxDelay {
+		ceiling = t.opts.RetryMaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Health returns the provider's breaker state, error rate and latency percentiles.
+func (t *ResilientTracker) Health() TrackerHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := TrackerHealth{
+		Provider:            t.next.Provider(),
+		State:               t.state,
+		ConsecutiveFailures: t.failures,
+		WindowRequests:      len(t.window),
+		LastError:           t.lastErr,
+	}
+	if !t.lastErrTime.IsZero() {
+		at := t.lastErrTime
+		h.LastErrorAt = &at
+	}
+	if t.state == BreakerOpen {
+		until := t.openedAt.Add(t.opts.OpenTimeout)
+		h.OpenUntil = &until
+	}
+
+	if len(t.window) == 0 {
+		return h
+	}
+
+	durations := make([]time.Duration, 0, len(t.window))
+	failed := 0
+	for _, o := range t.window {
+		durations = append(durations, o.duration)
+		if o.failed {
+			failed++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	h.ErrorRate = math.Round(float64(failed)/float64(len(t.window))*1000) / 1000
+	h.LatencyP50Ms = percentileDuration(durations, 0.50).Milliseconds()
+	h.LatencyP90Ms = percentileDuration(durations, 0.90).Milliseconds()
+	h.LatencyP99Ms = percentileDuration(durations, 0.99).Milliseconds()
+	return h
+}
+
+// percentileDuration returns the nearest-rank percentile of sorted durations.
+func percentileDuration(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// sleepContext sleeps for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
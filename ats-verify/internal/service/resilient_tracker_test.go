@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+// fakeTracker returns the queued errors in order, then succeeds.
+type fakeTracker struct {
+	name  string
+	errs  []error
+	calls int
+}
+
+func (f *fakeTracker) Provider() string { return f.name }
+
+func (f *fakeTracker) Track(ctx context.Context, trackNumber string) ([]models.TrackingEvent, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return []models.TrackingEvent{{StatusCode: "HAND", Source: f.name}}, nil
+}
+
+func testResilienceOptions() ResilienceOptions {
+	return ResilienceOptions{
+		RatePerSecond:    0, // unlimited
+		Burst:            1,
+		MaxRetries:       0,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	}
+}
+
+func TestResilientTracker_RetriesProviderFailures(t *testing.T) {
+	inner := &fakeTracker{name: "Kazpost", errs: []error{
+		&providerStatusError{Provider: "kazpost", StatusCode: http.StatusBadGateway},
+	}}
+	opts := testResilienceOptions()
+	opts.MaxRetries = 2
+	rt := NewResilientTracker(inner, opts)
+
+	events, err := rt.Track(context.Background(), "CN123")
+	if err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if len(events) != 1 || inner.calls != 2 {
+		t.Errorf("expected 1 event after 2 calls, got %d events / %d calls", len(events), inner.calls)
+	}
+}
+
+func TestResilientTracker_DoesNotRetryClientErrors(t *testing.T) {
+	inner := &fakeTracker{name: "Kazpost", errs: []error{
+		&providerStatusError{Provider: "kazpost", StatusCode: http.StatusNotFound},
+	}}
+	opts := testResilienceOptions()
+	opts.MaxRetries = 3
+	rt := NewResilientTracker(inner, opts)
+
+	if _, err := rt.Track(context.Background(), "CN123"); err == nil {
+		t.Fatal("expected the 404 error to be returned")
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected a single call for a 404, got %d", inner.calls)
+	}
+	if h := rt.Health(); h.State != BreakerClosed || h.ErrorRate != 0 {
+		t.Errorf("expected 404 not to count as a provider failure, got %+v", h)
+	}
+}
+
+func TestResilientTracker_BreakerOpensAndRecovers(t *testing.T) {
+	failure := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	inner := &fakeTracker{name: "CDEK", errs: []error{failure, failure}}
+	rt := NewResilientTracker(inner, testResilienceOptions())
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	rt.now = func() time.Time { return now }
+
+	rt.Track(context.Background(), "1")
+	rt.Track(context.Background(), "1")
+	if h := rt.Health(); h.State != BreakerOpen {
+		t.Fatalf("expected breaker to open after 2 failures, got %s", h.State)
+	}
+
+	if _, err := rt.Track(context.Background(), "1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen while open, got %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected no provider call while open, got %d calls", inner.calls)
+	}
+
+	// After the open timeout a half-open probe goes through and closes the breaker.
+	now = now.Add(2 * time.Minute)
+	if _, err := rt.Track(context.Background(), "1"); err != nil {
+		t.Fatalf("expected half-open probe to succeed, got %v", err)
+	}
+
+	h := rt.Health()
+	if h.State != BreakerClosed {
+		t.Errorf("expected breaker to close after successful probe, got %s", h.State)
+	}
+	if h.WindowRequests != 3 || h.ErrorRate < 0.66 || h.ErrorRate > 0.67 {
+		t.Errorf("expected 3 calls with 2/3 error rate, got %d calls / %.3f", h.WindowRequests, h.ErrorRate)
+	}
+}
+
+func TestResilientTracker_MalformedBodyIsNotProviderFailure(t *testing.T) {
+	malformed := fmt.Errorf("kazpost: parsing json: %w", json.Unmarshal([]byte("<html>"), new(struct{})))
+	inner := &fakeTracker{name: "Kazpost", errs: []error{malformed, malformed, malformed}}
+	opts := testResilienceOptions()
+	opts.MaxRetries = 2
+	rt := NewResilientTracker(inner, opts)
+
+	for range 3 {
+		if _, err := rt.Track(context.Background(), "CN123"); err == nil {
+			t.Fatal("expected the parse error to be returned")
+		}
+	}
+	if inner.calls != 3 {
+		t.Errorf("expected no retries for a malformed body, got %d calls", inner.calls)
+	}
+	if h := rt.Health(); h.State != BreakerClosed || h.ConsecutiveFailures != 0 {
+		t.Errorf("expected malformed bodies not to open the breaker, got %+v", h)
+	}
+}
+
+func TestResilientTracker_RateLimitHonoursContext(t *testing.T) {
+	inner := &fakeTracker{name: "Kazpost"}
+	opts := testResilienceOptions()
+	opts.RatePerSecond = 0.01 // one token every 100s
+	rt := NewResilientTracker(inner, opts)
+
+	if _, err := rt.Track(context.Background(), "1"); err != nil {
+		t.Fatalf("expected burst token to be available, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rt.Track(ctx, "2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected rate limiter to wait until the deadline, got %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected the limited call not to reach the provider, got %d calls", inner.calls)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+var (
+	// ErrShareLinkInvalid is returned for malformed or tampered share tokens.
+	ErrShareLinkInvalid = errors.New("invalid share link")
+	// ErrShareLinkExpired is returned for correctly signed tokens past their expiry.
+	ErrShareLinkExpired = errors.New("share link has expired")
+)
+
+// ShareLink is a signed, expiring public tracking link.
+type ShareLink struct {
+	TrackNumber string    `json:"track_number"`
+	Token       string    `json:"token"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ShareLinkService issues and verifies HMAC-signed public tracking tokens.
+// Tokens are stateless: "<base64url(track|expiry)>.<base64url(hmac-sha256)>".
+type ShareLinkService struct {
+	secret     []byte
+	baseURL    string
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	now        func() time.Time
+}
+
+// NewShareLinkService creates a new ShareLinkService.
+// baseURL is the public origin used to build links (e.g. "https://verify.example.kz").
+func NewShareLinkService(secret, baseURL string, defaultTTL, maxTTL time.Duration) *ShareLinkService {
+	return &ShareLinkService{
+		secret:     []byte(secret),
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+		now:        time.Now,
+	}
+}
+
+// Create signs a link for the track. A zero ttl uses the default; ttl is capped at the maximum.
+func (s *ShareLinkService) Create(trackNumber string, ttl time.Duration) (*ShareLink, error) {
+	trackNumber = strings.TrimSpace(trackNumber)
+	if trackNumber == "" {
+		return nil, fmt.Errorf("track number is required")
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	if ttl == 0 {
+		ttl = s.defaultTTL
+	}
+	if ttl > s.maxTTL {
+		ttl = s.maxTTL
+	}
+
+	expiresAt := s.now().Add(ttl).UTC().Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(trackNumber + "|" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	token := payload + "." + s.sign(payload)
+
+	return &ShareLink{
+		TrackNumber: trackNumber,
+		Token:       token,
+		URL:         s.baseURL + "/api/v1/public/tracking/" + token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// Verify checks the signature and expiry of a token and returns its track number.
+func (s *ShareLinkService) Verify(token string) (string, time.Time, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return "", time.Time{}, ErrShareLinkInvalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", time.Time{}, ErrShareLinkInvalid
+	}
+	track, expiry, ok := strings.Cut(string(raw), "|")
+	if !ok || track == "" {
+		return "", time.Time{}, ErrShareLinkInvalid
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrShareLinkInvalid
+	}
+
+	expiresAt := time.Unix(unix, 0).UTC()
+	if !s.now().Before(expiresAt) {
+		return "", time.Time{}, ErrShareLinkExpired
+	}
+	return track, expiresAt, nil
+}
+
+func (s *ShareLinkService) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PublicTrackingEvent is a tracking event without internal identifiers.
+type PublicTrackingEvent struct {
+	Status      models.TrackingStatus `json:"status"`
+	Description string                `json:"description"`
+	Location    string                `json:"location,omitempty"`
+	EventTime   time.Time             `json:"event_time"`
+}
+
+// PublicTracking is the redacted tracking view served by share links.
+// It omits uploader, parcel and event IDs, SNT and product details.
+type PublicTracking struct {
+	TrackNumber string                `json:"track_number"`
+	Status      models.TrackingStatus `json:"status"`
+	Provider    string                `json:"provider,omitempty"`
+	ExternalURL string                `json:"external_url,omitempty"`
+	Events      []PublicTrackingEvent `json:"events"`
+	Registered  bool                  `json:"registered"`            // Parcel exists in our database
+	IsUsed      *bool                 `json:"is_used,omitempty"`     // Used/unused status, only for registered parcels
+	Marketplace string                `json:"marketplace,omitempty"` // Only for registered parcels
+	ExpiresAt   time.Time             `json:"link_expires_at"`
+}
+
+// RedactTracking builds the public view of a tracking result and optional parcel.
+func RedactTracking(trackNumber string, result *TrackingResult, parcel *models.Parcel, expiresAt time.Time) *PublicTracking {
+	view := &PublicTracking{
+		TrackNumber: trackNumber,
+		Status:      models.TrackingStatusUnknown,
+		Events:      []PublicTrackingEvent{},
+		ExpiresAt:   expiresAt,
+	}
+
+	if result != nil {
+		view.Status = result.Status
+		view.Provider = result.Provider
+		view.ExternalURL = result.ExternalURL
+		for _, e := range result.Events {
+			view.Events = append(view.Events, PublicTrackingEvent{
+				Status:      e.NormalizedStatus,
+				Description: e.Description,
+				Location:    e.Location,
+				EventTime:   e.EventTime,
+			})
+		}
+	}
+
+	if parcel != nil {
+		isUsed := parcel.IsUsed
+		view.Registered = true
+		view.IsUsed = &isUsed
+		view.Marketplace = parcel.Marketplace
+		if result == nil {
+			view.Status = parcel.TrackingStatus
+		}
+	}
+
+	return view
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+func TestShareLinkService_RoundTrip(t *testing.T) {
+	svc := NewShareLinkService("secret", "https://verify.example.kz/", 72*time.Hour, 720*time.Hour)
+
+	link, err := svc.Create(" CN001KZ ", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(link.URL, "https://verify.example.kz/api/v1/public/tracking/") {
+		t.Errorf("unexpected link URL: %s", link.URL)
+	}
+
+	track, expiresAt, err := svc.Verify(link.Token)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if track != "CN001KZ" || !expiresAt.Equal(link.ExpiresAt) {
+		t.Errorf("unexpected token contents: %s %v", track, expiresAt)
+	}
+
+	// Requested TTL is capped at the maximum.
+	long, _ := svc.Create("CN001KZ", 10000*time.Hour)
+	if long.ExpiresAt.After(time.Now().Add(721 * time.Hour)) {
+		t.Errorf("expected ttl to be capped, got %v", long.ExpiresAt)
+	}
+}
+
+func TestShareLinkService_RejectsTamperedAndExpired(t *testing.T) {
+	svc := NewShareLinkService("secret", "", time.Hour, time.Hour)
+	link, _ := svc.Create("CN001KZ", 0)
+
+	other := NewShareLinkService("other-secret", "", time.Hour, time.Hour)
+	if _, _, err := other.Verify(link.Token); !errors.Is(err, ErrShareLinkInvalid) {
+		t.Errorf("expected token signed with another key to be invalid, got %v", err)
+	}
+
+	payload, sig, _ := strings.Cut(link.Token, ".")
+	forged := strings.TrimSuffix(payload, "A") + "B." + sig
+	if _, _, err := svc.Verify(forged); !errors.Is(err, ErrShareLinkInvalid) {
+		t.Errorf("expected modified payload to be invalid, got %v", err)
+	}
+	if _, _, err := svc.Verify("garbage"); !errors.Is(err, ErrShareLinkInvalid) {
+		t.Errorf("expected garbage to be invalid, got %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := svc.Verify(link.Token); !errors.Is(err, ErrShareLinkExpired) {
+		t.Errorf("expected expired token, got %v", err)
+	}
+}
+
+func TestRedactTracking_OmitsInternalFields(t *testing.T) {
+	parcel := &models.Parcel{
+		ID:          uuid.New(),
+		TrackNumber: "CN001KZ",
+		Marketplace: "Ozon",
+		SNT:         "SNT-1",
+		IsUsed:      true,
+		UploadedBy:  uuid.New(),
+	}
+	result := &TrackingResult{
+		Status:   models.TrackingStatusDelivered,
+		Provider: "Kazpost",
+		Events: []models.TrackingEvent{
+			{ID: uuid.New(), ParcelID: parcel.ID, StatusCode: "HAND", NormalizedStatus: models.TrackingStatusDelivered, Description: "Вручено"},
+		},
+	}
+
+	view := RedactTracking("CN001KZ", result, parcel, time.Now())
+	if !view.Registered || view.IsUsed == nil || !*view.IsUsed || view.Marketplace != "Ozon" {
+		t.Errorf("expected registration and used status, got %+v", view)
+	}
+	if len(view.Events) != 1 || view.Events[0].Status != models.TrackingStatusDelivered {
+		t.Errorf("unexpected events: %+v", view.Events)
+	}
+
+	unknown := RedactTracking("X1", result, nil, time.Now())
+	if unknown.Registered || unknown.IsUsed != nil {
+		t.Errorf("expected unregistered view without used status, got %+v", unknown)
+	}
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+// TACService resolves IMEI Type Allocation Codes to devices and cross-checks them
+// against declared brands. IMEIService stays free of database dependencies; reports
+// are annotated after analysis.
+type TACService struct {
+	tacRepo    *repository.TACRepository
+	parcelRepo *repository.ParcelRepository
+}
+
+// NewTACService creates a new TACService.
+func NewTACService(tacRepo *repository.TACRepository, parcelRepo *repository.ParcelRepository) *TACService {
+	return &TACService{tacRepo: tacRepo, parcelRepo: parcelRepo}
+}
+
+// tacColumnAliases maps TAC dump columns to accepted header names.
+var tacColumnAliases = map[string][]string{
+	"tac":          {"tac", "tac_code", "type_allocation_code"},
+	"manufacturer": {"manufacturer", "brand", "vendor", "make", "name1"},
+	"model":        {"model", "marketing_name", "model_name", "name", "name2"},
+}
+
+// brandAliases maps canonical brands to lowercase markers found in manufacturer
+// names, declared brands or model names. Order matters: the first match wins.
+var brandAliases = []struct {
+	brand   string
+	aliases []string
+}{
+	{"apple", []string{"apple", "iphone", "ipad"}},
+	{"samsung", []string{"samsung", "galaxy"}},
+	{"xiaomi", []string{"xiaomi", "redmi", "poco"}},
+	{"huawei", []string{"huawei"}},
+	{"honor", []string{"honor"}},
+	{"google", []string{"google", "pixel"}},
+	{"oppo", []string{"oppo"}},
+	{"realme", []string{"realme"}},
+	{"vivo", []string{"vivo"}},
+	{"oneplus", []string{"oneplus", "one plus"}},
+	{"motorola", []string{"motorola", "moto "}},
+	{"nokia", []string{"nokia", "hmd global"}},
+	{"sony", []string{"sony"}},
+	{"tecno", []string{"tecno"}},
+	{"infinix", []string{"infinix"}},
+	{"zte", []string{"zte"}},
+}
+
+// ParseTACCSV reads a TAC dump with tac, manufacturer and model columns.
+// Rows without an 8-digit TAC are skipped; later duplicates override earlier ones.
+func ParseTACCSV(r io.Reader) ([]models.TACEntry, error) {
+	reader, err := NewRobustCSVReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading TAC CSV: %w", err)
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading TAC CSV header: %w", err)
+	}
+
+	cols := make(map[string]int)
+	for i, col := range header {
+		lower := strings.ToLower(strings.TrimSpace(col))
+		for field, aliases := range tacColumnAliases {
+			if _, ok := cols[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if lower == alias {
+					cols[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := cols["tac"]; !ok {
+		return nil, fmt.Errorf("missing required column: tac")
+	}
+	if _, ok := cols["manufacturer"]; !ok {
+		return nil, fmt.Errorf("missing required column: manufacturer")
+	}
+
+	index := make(map[string]int)
+	var entries []models.TACEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		tac := recordField(record, cols, "tac")
+		if !isTAC(tac) {
+			continue
+		}
+		entry := models.TACEntry{
+			TAC:          tac,
+			Manufacturer: recordField(record, cols, "manufacturer"),
+			Model:        recordField(record, cols, "model"),
+		}
+		if i, ok := index[tac]; ok {
+			entries[i] = entry
+			continue
+		}
+		index[tac] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no valid data: TAC CSV has no rows with 8-digit TACs")
+	}
+	return entries, nil
+}
+
+// tacPairColumnAliases maps TAC pair list columns to accepted header names.
+var tacPairColumnAliases = map[string][]string{
+	"tac_a": {"tac_a", "tac1", "tac_1", "imei1_tac"},
+	"tac_b": {"tac_b", "tac2", "tac_2", "imei2_tac"},
+	"note":  {"note", "comment", "model", "device"},
+}
+
+// ParseTACPairsCSV reads a list of dual-SIM TAC pairs (tac_a, tac_b, optional note).
+// Rows without two different 8-digit TACs are skipped; pairs are returned ordered and unique.
+func ParseTACPairsCSV(r io.Reader) ([]models.TACPair, error) {
+	reader, err := NewRobustCSVReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading TAC pairs CSV: %w", err)
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading TAC pairs CSV header: %w", err)
+	}
+
+	cols := make(map[string]int)
+	for i, col := range header {
+		lower := strings.ToLower(strings.TrimSpace(col))
+		for field, aliases := range tacPairColumnAliases {
+			if _, ok := cols[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if lower == alias {
+					cols[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := cols["tac_a"]; !ok {
+		return nil, fmt.Errorf("missing required column: tac_a")
+	}
+	if _, ok := cols["tac_b"]; !ok {
+		return nil, fmt.Errorf("missing required column: tac_b")
+	}
+
+	seen := make(map[[2]string]bool)
+	var pairs []models.TACPair
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		a, b := recordField(record, cols, "tac_a"), recordField(record, cols, "tac_b")
+		if !isTAC(a) || !isTAC(b) || a == b {
+			continue
+		}
+		key := tacPairKey(a, b)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		pairs = append(pairs, models.TACPair{TACA: key[0], TACB: key[1], Note: recordField(record, cols, "note")})
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no valid data: TAC pairs CSV has no rows with two different 8-digit TACs")
+	}
+	return pairs, nil
+}
+
+// isTAC reports whether s is an 8-digit TAC.
+func isTAC(s string) bool {
+	return len(s) == 8 && strings.Trim(s, "0123456789") == ""
+}
+
+// ImportPairs parses a dual-SIM TAC pair list and upserts it.
+func (s *TACService) ImportPairs(ctx context.Context, r io.Reader) (int, error) {
+	pairs, err := ParseTACPairsCSV(r)
+	if err != nil {
+		return 0, err
+	}
+	return s.tacRepo.ImportPairs(ctx, pairs)
+}
+
+// Import parses a TAC dump and upserts it into the local table.
+func (s *TACService) Import(ctx context.Context, r io.Reader) (int, error) {
+	entries, err := ParseTACCSV(r)
+	if err != nil {
+		return 0, err
+	}
+	return s.tacRepo.Import(ctx, entries)
+}
+
+// Annotate resolves devices for every matched IMEI, flags brand mismatches against the
+// declared brand/model (or Parcel.Brand of the row's track number), re-checks dual-SIM
+// pairing with known TAC pairs and regenerates the text report.
+func (s *TACService) Annotate(ctx context.Context, report *models.IMEIVerificationReport) error {
+	var tacs, tracks []string
+	seenTAC := make(map[string]bool)
+	seenTrack := make(map[string]bool)
+	for _, res := range report.Results {
+		if len(res.IMEI14) >= 8 && !seenTAC[res.IMEI14[:8]] {
+			seenTAC[res.IMEI14[:8]] = true
+			tacs = append(tacs, res.IMEI14[:8])
+		}
+		if res.DeclaredBrand == "" && res.DeclaredModel == "" && res.TrackNumber != "" && !seenTrack[res.TrackNumber] {
+			seenTrack[res.TrackNumber] = true
+			tracks = append(tracks, res.TrackNumber)
+		}
+	}
+
+	devices, err := s.tacRepo.LookupMany(ctx, tacs)
+	if err != nil {
+		return err
+	}
+
+	parcelBrands := make(map[string]string)
+	if len(tracks) > 0 && s.parcelRepo != nil {
+		parcels, err := s.parcelRepo.BulkLookup(ctx, tracks)
+		if err != nil {
+			return err
+		}
+		for _, p := range parcels {
+			parcelBrands[p.TrackNumber] = p.Brand
+		}
+	}
+
+	pairs, err := s.tacRepo.PairsAmong(ctx, tacs)
+	if err != nil {
+		return err
+	}
+
+	annotateTAC(report, devices, parcelBrands)
+	markDualSIMPairs(report, knownTACPairing(devices, pairs))
+	report.TextReport = generateTextReport(report)
+	return nil
+}
+
+// knownTACPairing treats two TACs as one device when they are listed in tac_pairs or
+// resolve to the same manufacturer and model.
+func knownTACPairing(devices map[string]models.TACEntry, pairs []models.TACPair) tacPairing {
+	listed := make(map[[2]string]bool, len(pairs))
+	for _, p := range pairs {
+		listed[tacPairKey(p.TACA, p.TACB)] = true
+	}
+	return tacPairing{
+		paired: func(a, b string) bool {
+			if listed[tacPairKey(a, b)] {
+				return true
+			}
+			da, okA := devices[a]
+			db, okB := devices[b]
+			return okA && okB && db.Model != "" &&
+				strings.EqualFold(strings.TrimSpace(da.Manufacturer), strings.TrimSpace(db.Manufacturer)) &&
+				strings.EqualFold(strings.TrimSpace(da.Model), strings.TrimSpace(db.Model))
+		},
+		describe: func(tac string) string {
+			d, ok := devices[tac]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(d.Manufacturer + " " + d.Model)
+		},
+	}
+}
+
+// annotateTAC applies TAC lookups and brand checks to the report results.
+func annotateTAC(report *models.IMEIVerificationReport, devices map[string]models.TACEntry, parcelBrands map[string]string) {
+	report.TotalTACResolved = 0
+	report.TotalBrandMismatches = 0
+
+	for i := range report.Results {
+		res := &report.Results[i]
+		if len(res.IMEI14) < 8 {
+			continue
+		}
+		device, ok := devices[res.IMEI14[:8]]
+		if !ok {
+			continue
+		}
+		res.DeviceManufacturer = device.Manufacturer
+		res.DeviceModel = device.Model
+		report.TotalTACResolved++
+
+		declared, source := res.DeclaredBrand, "declared brand"
+		if declared == "" {
+			declared, source = res.DeclaredModel, "declared model"
+		}
+		if declared == "" {
+			declared, source = parcelBrands[res.TrackNumber], "parcel brand"
+		}
+		if declared == "" {
+			continue
+		}
+
+		// Free-text model names are only compared when both sides name a known brand.
+		if brandsConflict(declared, device, source != "declared model") {
+			res.BrandMismatch = true
+			res.MismatchReason = fmt.Sprintf("%s %q, TAC %s is %s", source, declared, res.IMEI14[:8],
+				strings.TrimSpace(device.Manufacturer+" "+device.Model))
+			report.TotalBrandMismatches++
+		}
+	}
+}
+
+// brandsConflict reports whether a declared brand/model clearly belongs to another
+// manufacturer than the TAC device. With compareRaw, brands unknown to brandAliases
+// are compared to the TAC manufacturer by substring.
+func brandsConflict(declared string, device models.TACEntry, compareRaw bool) bool {
+	declaredBrand := canonicalBrand(declared)
+	deviceBrand := canonicalBrand(device.Manufacturer + " " + device.Model)
+
+	if declaredBrand != "" && deviceBrand != "" {
+		return declaredBrand != deviceBrand
+	}
+	if compareRaw {
+		// At most one side is a known brand; fall back to comparing raw manufacturer names.
+		d := strings.ToLower(strings.TrimSpace(declared))
+		m := strings.ToLower(strings.TrimSpace(device.Manufacturer))
+		if d == "" || m == "" {
+			return false
+		}
+		return !strings.Contains(d, m) && !strings.Contains(m, d)
+	}
+	return false
+}
+
+// canonicalBrand returns the canonical brand mentioned in s, or "" if none is recognized.
+func canonicalBrand(s string) string {
+	lower := " " + strings.ToLower(s) + " "
+	for _, b := range brandAliases {
+		for _, alias := range b.aliases {
+			if strings.Contains(lower, alias) {
+				return b.brand
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"ats-verify/internal/models"
+)
+
+func TestParseTACCSV(t *testing.T) {
+	csvContent := "\xef\xbb\xbfTAC;Brand;Marketing_Name\n" +
+		"35332811;Apple;iPhone 15\n" +
+		"86012345;Xiaomi;Redmi Note 12\n" +
+		"bad;Nobody;Nothing\n" +
+		"35332811;Apple;iPhone 15 Pro\n"
+
+	entries, err := ParseTACCSV(strings.NewReader(csvContent))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Model != "iPhone 15 Pro" {
+		t.Errorf("expected later duplicate to override, got %+v", entries[0])
+	}
+
+	if _, err := ParseTACCSV(strings.NewReader("code,brand\n1,2\n")); err == nil {
+		t.Error("expected error for missing tac column")
+	}
+}
+
+func TestAnnotateTAC_FlagsBrandMismatches(t *testing.T) {
+	report := &models.IMEIVerificationReport{Results: []models.IMEIMatchResult{
+		{CSVLine: 2, IMEI14: "35332811000000", DeclaredBrand: "Apple"},
+		{CSVLine: 3, IMEI14: "86012345000000", DeclaredBrand: "Apple"},
+		{CSVLine: 4, IMEI14: "86012345000001", DeclaredModel: "Смартфон 128GB"},
+		{CSVLine: 5, IMEI14: "86012345000002", TrackNumber: "CN001KZ"},
+		{CSVLine: 6, IMEI14: "99999999000000", DeclaredBrand: "Apple"},
+	}}
+	devices := map[string]models.TACEntry{
+		"35332811": {TAC: "35332811", Manufacturer: "Apple", Model: "iPhone 15"},
+		"86012345": {TAC: "86012345", Manufacturer: "Xiaomi", Model: "Redmi Note 12"},
+	}
+
+	annotateTAC(report, devices, map[string]string{"CN001KZ": "Samsung"})
+
+	if report.TotalTACResolved != 4 || report.TotalBrandMismatches != 2 {
+		t.Fatalf("expected 4 resolved / 2 mismatches, got %d / %d", report.TotalTACResolved, report.TotalBrandMismatches)
+	}
+	if report.Results[0].BrandMismatch || report.Results[0].DeviceModel != "iPhone 15" {
+		t.Errorf("expected Apple TAC to match Apple declaration, got %+v", report.Results[0])
+	}
+	if !report.Results[1].BrandMismatch || !strings.Contains(report.Results[1].MismatchReason, "Xiaomi") {
+		t.Errorf("expected Xiaomi TAC in Apple declaration to be flagged, got %+v", report.Results[1])
+	}
+	if report.Results[2].BrandMismatch {
+		t.Errorf("expected generic model text not to be flagged, got %+v", report.Results[2])
+	}
+	if !report.Results[3].BrandMismatch || !strings.HasPrefix(report.Results[3].MismatchReason, "parcel brand") {
+		t.Errorf("expected parcel brand mismatch, got %+v", report.Results[3])
+	}
+
+	text := generateTextReport(report)
+	if !strings.Contains(text, "--- BRAND MISMATCHES ---") || !strings.Contains(text, "[Apple iPhone 15]") {
+		t.Errorf("expected text report to list devices and mismatches, got:\n%s", text)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExtractedText is the output of a PDF text extraction backend.
+type ExtractedText struct {
+	Pages     []string       // Plain text per page
+	Fragments []TextFragment // Positioned text for layout parsing (optional)
+}
+
+// TextExtractor extracts text from PDF bytes.
+type TextExtractor interface {
+	Name() string
+	Extract(ctx context.Context, data []byte, password string) (*ExtractedText, error) // password is empty for unencrypted PDFs
+}
+
+// Garbage ratio thresholds for extracted text.
+const (
+	maxUsableGarbageRatio = 0.30 // Above this the text is treated as unreadable
+	maxCleanGarbageRatio  = 0.05 // At or below this (with IMEIs found) no fallback is needed
+)
+
+// ExtractionQuality scores extracted text: more 15-digit sequences and fewer
+// unprintable characters is better.
+type ExtractionQuality struct {
+	IMEISequences int     `json:"imei_sequences"`
+	GarbageRatio  float64 `json:"garbage_ratio"`
+	Runes         int     `json:"runes"`
+}
+
+// MeasureTextQuality computes the quality of per-page text. Replacement characters,
+// control characters and private-use glyphs (undecoded fonts) count as garbage.
+func MeasureTextQuality(pages []string) ExtractionQuality {
+	var q ExtractionQuality
+	garbage, visible := 0, 0
+	for _, page := range pages {
+		q.IMEISequences += len(regex15Digits.FindAllString(page, -1))
+		for _, r := range page {
+			q.Runes++
+			if unicode.IsSpace(r) {
+				continue
+			}
+			visible++
+			if r == utf8.RuneError || unicode.IsControl(r) || unicode.Is(unicode.Co, r) || !unicode.IsPrint(r) {
+				garbage++
+			}
+		}
+	}
+	if visible > 0 {
+		q.GarbageRatio = float64(garbage) / float64(visible)
+	}
+	return q
+}
+
+// usable reports whether the text is readable at all.
+func (q ExtractionQuality) usable() bool {
+	return q.Runes > 0 && q.GarbageRatio <= maxUsableGarbageRatio
+}
+
+// acceptable reports whether the text is good enough to skip fallback backends.
+func (q ExtractionQuality) acceptable() bool {
+	return q.usable() && q.IMEISequences > 0 && q.GarbageRatio <= maxCleanGarbageRatio
+}
+
+// better reports whether q beats other.
+func (q ExtractionQuality) better(other ExtractionQuality) bool {
+	if q.usable() != other.usable() {
+		return q.usable()
+	}
+	if q.IMEISequences != other.IMEISequences {
+		return q.IMEISequences > other.IMEISequences
+	}
+	if q.GarbageRatio != other.GarbageRatio {
+		return q.GarbageRatio < other.GarbageRatio
+	}
+	return q.Runes > other.Runes
+}
+
+// MultiExtractor runs all primary backends, keeps the best output by quality and
+// calls fallback backends (e.g. the PyMuPDF sidecar) only when that output is not acceptable.
+type MultiExtractor struct {
+	primary  []TextExtractor
+	fallback []TextExtractor
+}
+
+// NewMultiExtractor creates a new MultiExtractor.
+func NewMultiExtractor(primary []TextExtractor, fallback ...TextExtractor) *MultiExtractor {
+	return &MultiExtractor{primary: primary, fallback: fallback}
+}
+
+// extraction is the result of one backend.
+type extraction struct {
+	backend  string
+	text     *ExtractedText
+	quality  ExtractionQuality
+	repaired bool // Extracted from the PDF with a rebuilt cross-reference table
+}
+
+// ExtractDocument extracts a PDF with the best available backend and parses its goods
+// items. When the chosen backend has no positioned text, layout comes from another backend.
+// password opens encrypted PDFs. When every primary backend fails on a damaged file the
+// cross-reference table is rebuilt and the primaries are retried. Errors wrap one of
+// the ErrPDF* sentinels.
+func (m *MultiExtractor) ExtractDocument(ctx context.Context, fileName string, data []byte, password string) (PDFDocument, error) {
+	if len(data) > MaxPDFBytes {
+		return PDFDocument{}, fmt.Errorf("%w: %d MB (max %d MB)", ErrPDFTooLarge, len(data)>>20, MaxPDFBytes>>20)
+	}
+
+	var results []extraction
+	var errs []error
+
+	run := func(extractors []TextExtractor, data []byte, repaired bool) {
+		for _, e := range extractors {
+			text, err := e.Extract(ctx, data, password)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+				continue
+			}
+			results = append(results, extraction{backend: e.Name(), text: text, quality: MeasureTextQuality(text.Pages), repaired: repaired})
+		}
+	}
+
+	run(m.primary, data, false)
+	if len(results) == 0 && len(errs) > 0 && !errors.Is(errors.Join(errs...), ErrPDFEncrypted) {
+		if fixed, ok := repairPDFXref(data); ok {
+			log.Printf("pdf: %s could not be opened, retrying with a rebuilt cross-reference table", fileName)
+			run(m.primary, fixed, true)
+		}
+	}
+	best := bestExtraction(results)
+	if best == nil || !best.quality.acceptable() {
+		if len(m.fallback) > 0 && best != nil {
+			log.Printf("pdf: %s extracted by %s with low quality (%d IMEI sequences, %.0f%% garbage), trying fallback",
+				fileName, best.backend, best.quality.IMEISequences, best.quality.GarbageRatio*100)
+		}
+		run(m.fallback, data, false)
+		best = bestExtraction(results)
+	}
+	if best == nil {
+		err := errors.Join(errs...)
+		if PDFErrorCode(err) == "" {
+			err = fmt.Errorf("%w: %w", ErrPDFCorrupt, err)
+		}
+		return PDFDocument{}, err
+	}
+	if !hasText(best.text.Pages) {
+		return PDFDocument{}, fmt.Errorf("%w: none of its %d pages has extractable text, it is probably a scanned image and needs OCR",
+			ErrPDFNoTextLayer, len(best.text.Pages))
+	}
+
+	doc := PDFDocument{
+		FileName: fileName,
+		Pages:    best.text.Pages,
+		Backend:  best.backend,
+		Quality:  best.quality,
+		Repaired: best.repaired,
+	}
+	fragments := best.text.Fragments
+	for _, r := range results {
+		if len(fragments) > 0 {
+			break
+		}
+		fragments = r.text.Fragments
+	}
+	if len(fragments) > 0 {
+		doc.Items = ParseDeclarationItems(fragments)
+	}
+	return doc, nil
+}
+
+// hasText reports whether any page has a non-space character.
+func hasText(pages []string) bool {
+	for _, page := range pages {
+		if strings.TrimSpace(page) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bestExtraction returns the highest-quality result, preferring earlier backends on ties.
+func bestExtraction(results []extraction) *extraction {
+	var best *extraction
+	for i := range results {
+		if best == nil || results[i].quality.better(best.quality) {
+			best = &results[i]
+		}
+	}
+	return best
+}
+
+// recoverPDFPanic turns panics of PDF parsing libraries on malformed input into errors.
+func recoverPDFPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: malformed PDF: %v", ErrPDFCorrupt, r)
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeExtractor struct {
+	name  string
+	text  *ExtractedText
+	err   error
+	calls int
+}
+
+func (f *fakeExtractor) Name() string { return f.name }
+
+func (f *fakeExtractor) Extract(context.Context, []byte, string) (*ExtractedText, error) {
+	f.calls++
+	return f.text, f.err
+}
+
+func TestMeasureTextQuality(t *testing.T) {
+	clean := MeasureTextQuality([]string{"IMEI 490154203237518\nIMEI 353328110000007"})
+	if clean.IMEISequences != 2 || clean.GarbageRatio != 0 || !clean.acceptable() {
+		t.Errorf("expected clean text to be acceptable, got %+v", clean)
+	}
+
+	garbled := MeasureTextQuality([]string{"�� ab"})
+	if garbled.usable() {
+		t.Errorf("expected garbled text to be unusable, got %+v", garbled)
+	}
+	if !clean.better(garbled) || garbled.better(clean) {
+		t.Error("expected clean text to beat garbled text")
+	}
+}
+
+func TestMultiExtractor_PicksBestAndFallsBack(t *testing.T) {
+	ctx := context.Background()
+	garbled := &fakeExtractor{name: "ledongthuc", text: &ExtractedText{Pages: []string{"��� 12"}}}
+	partial := &fakeExtractor{name: "rscpdf", text: &ExtractedText{
+		Pages: []string{"490154203237518"},
+		Fragments: []TextFragment{
+			{Page: 1, X: 300, Y: 700, S: "Товар № 1"},
+			{Page: 1, X: 20, Y: 680, S: "490154203237518 1 шт"},
+		},
+	}}
+	sidecar := &fakeExtractor{name: "sidecar", text: &ExtractedText{Pages: []string{"490154203237518 353328110000007"}}}
+
+	// The primary output has IMEIs and clean text: the sidecar is not called.
+	m := NewMultiExtractor([]TextExtractor{garbled, partial}, sidecar)
+	doc, err := m.ExtractDocument(ctx, "dt.pdf", nil, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if doc.Backend != "rscpdf" || sidecar.calls != 0 || len(doc.Items) != 1 {
+		t.Errorf("expected rscpdf without fallback, got backend %q, %d sidecar calls, %d items", doc.Backend, sidecar.calls, len(doc.Items))
+	}
+
+	// Without any IMEIs from the primaries the sidecar is tried and wins; layout is
+	// still taken from the backend that provided positions.
+	noIMEIs := &fakeExtractor{name: "rscpdf", text: &ExtractedText{Pages: []string{"no numbers"}, Fragments: partial.text.Fragments}}
+	m = NewMultiExtractor([]TextExtractor{garbled, noIMEIs}, sidecar)
+	doc, err = m.ExtractDocument(ctx, "dt.pdf", nil, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if doc.Backend != "sidecar" || doc.Quality.IMEISequences != 2 || len(doc.Items) != 1 {
+		t.Errorf("expected sidecar output with parsed items, got %+v", doc)
+	}
+
+	// All backends failing returns their errors.
+	failing := &fakeExtractor{name: "ledongthuc", err: errors.New("broken xref")}
+	_, err = NewMultiExtractor([]TextExtractor{failing}).ExtractDocument(ctx, "dt.pdf", nil, "")
+	if err == nil || !strings.Contains(err.Error(), "broken xref") || PDFErrorCode(err) != PDFErrorCorrupt {
+		t.Errorf("expected corrupt backend error, got %v", err)
+	}
+}
+
+func TestMergeGlyphs(t *testing.T) {
+	glyphs := []glyph{
+		{x: 12, y: 700, w: 6, size: 10, s: "B"},
+		{x: 10, y: 700.5, w: 6, size: 10, s: "A"}, // overlaps A's box: same word
+		{x: 22, y: 700, w: 6, size: 10, s: "C"},   // word gap
+		{x: 100, y: 700, w: 6, size: 10, s: "D"},  // column gap
+		{x: 10, y: 680, w: 6, size: 10, s: "E"},
+	}
+
+	fragments := mergeGlyphs(1, glyphs)
+	if len(fragments) != 3 {
+		t.Fatalf("expected 3 fragments, got %+v", fragments)
+	}
+	if fragments[0].S != "AB C" || fragments[1].S != "D" || fragments[2].S != "E" {
+		t.Errorf("unexpected fragments: %+v", fragments)
+	}
+	if got := layoutPageText(fragments); got != "AB C D\nE\n" {
+		t.Errorf("unexpected page text %q", got)
+	}
+}
+
+func TestSidecarExtractor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/extract" || r.Header.Get("Content-Type") != "application/pdf" || string(body) != "%PDF-1.4" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"pages":["490154203237518"],"fragments":[{"page":1,"x":20,"y":700,"text":"490154203237518"}]}`))
+	}))
+	defer srv.Close()
+
+	if NewSidecarExtractor("", 0) != nil {
+		t.Error("expected nil extractor without URL")
+	}
+
+	text, err := NewSidecarExtractor(srv.URL+"/", 0).Extract(context.Background(), []byte("%PDF-1.4"), "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(text.Pages) != 1 || len(text.Fragments) != 1 || text.Fragments[0].S != "490154203237518" {
+		t.Errorf("unexpected sidecar output: %+v", text)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
 
 	"github.com/google/uuid"
 )
@@ -21,20 +22,30 @@ type Tracker interface {
 	Provider() string
 }
 
+// TrackingEventHook is notified after new events were stored for a parcel from our database.
+// parcel.TrackingStatus already reflects the newly stored events.
+type TrackingEventHook interface {
+	OnTrackingEvents(ctx context.Context, parcel *models.Parcel, newEvents []models.TrackingEvent)
+}
+
 // TrackingService aggregates multiple Tracker implementations and queries them in order.
 type TrackingService struct {
-	trackers []Tracker
+	trackers  []Tracker
+	eventRepo *repository.TrackingEventRepository
+	hooks     []TrackingEventHook
 }
 
 // NewTrackingService creates a TrackingService with the given tracker implementations.
-func NewTrackingService(trackers ...Tracker) *TrackingService {
-	return &TrackingService{trackers: trackers}
+// eventRepo is used to persist events of parcels that exist in our database.
+func NewTrackingService(eventRepo *repository.TrackingEventRepository, trackers ...Tracker) *TrackingService {
+	return &TrackingService{trackers: trackers, eventRepo: eventRepo}
 }
 
 // TrackingResult holds the combined result from all providers.
 type TrackingResult struct {
 	TrackNumber string                 `json:"track_number"`
 	Events      []models.TrackingEvent `json:"events"`
+	Status      models.TrackingStatus  `json:"status"` // Normalized status of the latest event
 	Provider    string                 `json:"provider"`
 	ExternalURL string                 `json:"external_url,omitempty"`
 }
@@ -53,6 +64,7 @@ func (s *TrackingService) Track(ctx context.Context, trackNumber string) (*Track
 			return &TrackingResult{
 				TrackNumber: trackNumber,
 				Events:      events,
+				Status:      CurrentStatus(events),
 				Provider:    t.Provider(),
 			}, nil
 		}
@@ -64,6 +76,7 @@ func (s *TrackingService) Track(ctx context.Context, trackNumber string) (*Track
 		return &TrackingResult{
 			TrackNumber: trackNumber,
 			Events:      nil,
+			Status:      models.TrackingStatusUnknown,
 			Provider:    "CDEK",
 			ExternalURL: fmt.Sprintf("https://www.cdek.ru/ru/tracking/?order_id=%s", trackNumber),
 		}, nil
@@ -72,6 +85,48 @@ func (s *TrackingService) Track(ctx context.Context, trackNumber string) (*Track
 	return nil, fmt.Errorf("no tracking data found for %s", trackNumber)
 }
 
+// AddEventHook registers a hook that runs after new events are recorded.
+func (s *TrackingService) AddEventHook(h TrackingEventHook) {
+	s.hooks = append(s.hooks, h)
+}
+
+// Health returns a health snapshot for every configured provider.
+// Providers that are not wrapped with a ResilientTracker are reported as "unmonitored".
+func (s *TrackingService) Health() []TrackerHealth {
+	health := make([]TrackerHealth, 0, len(s.trackers))
+	for _, t := range s.trackers {
+		if hr, ok := t.(HealthReporter); ok {
+			health = append(health, hr.Health())
+			continue
+		}
+		health = append(health, TrackerHealth{Provider: t.Provider(), State: "unmonitored"})
+	}
+	return health
+}
+
+// RecordEvents stores provider events for a parcel from our database and updates
+// the parcel's current normalized status. Already stored events are skipped.
+// Returns only the events that were not stored before.
+func (s *TrackingService) RecordEvents(ctx context.Context, parcel *models.Parcel, events []models.TrackingEvent) ([]models.TrackingEvent, error) {
+	if parcel == nil || len(events) == 0 {
+		return nil, nil
+	}
+
+	inserted, status, err := s.eventRepo.SaveEvents(ctx, parcel.ID, events)
+	if err != nil {
+		return nil, fmt.Errorf("recording tracking events for %s: %w", parcel.TrackNumber, err)
+	}
+	parcel.TrackingStatus = status
+
+	if len(inserted) > 0 {
+		for _, h := range s.hooks {
+			h.OnTrackingEvents(ctx, parcel, inserted)
+		}
+	}
+
+	return inserted, nil
+}
+
 // ─── Kazpost Real Client ────────────────────────────────────────────────
 
 // kazpostEventsResponse matches the JSON from post.kz/external-api/tracking/api/v2/{id}/events
@@ -128,7 +183,7 @@ func (k *KazpostTracker) Track(ctx context.Context, trackNumber string) ([]model
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("kazpost: status %d", resp.StatusCode)
+		return nil, &providerStatusError{Provider: "kazpost", StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -157,12 +212,13 @@ func (k *KazpostTracker) Track(ctx context.Context, trackNumber string) ([]model
 			}
 
 			events = append(events, models.TrackingEvent{
-				ID:          uuid.New(),
-				StatusCode:  strings.Join(act.Status, ","),
-				Description: statusDesc,
-				Location:    location,
-				EventTime:   eventTime,
-				Source:      "Kazpost",
+				ID:               uuid.New(),
+				StatusCode:       strings.Join(act.Status, ","),
+				NormalizedStatus: normalizeKazpostStatus(act.Status),
+				Description:      statusDesc,
+				Location:         location,
+				EventTime:        eventTime,
+				Source:           "Kazpost",
 			})
 		}
 	}
@@ -258,7 +314,7 @@ func (c *CDEKTracker) Track(ctx context.Context, trackNumber string) ([]models.T
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cdek: status %d", resp.StatusCode)
+		return nil, &providerStatusError{Provider: "cdek", StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -285,12 +341,13 @@ func (c *CDEKTracker) Track(ctx context.Context, trackNumber string) ([]models.T
 		}
 
 		events = append(events, models.TrackingEvent{
-			ID:          uuid.New(),
-			StatusCode:  s.Code,
-			Description: s.Name,
-			Location:    location,
-			EventTime:   eventTime,
-			Source:      "CDEK",
+			ID:               uuid.New(),
+			StatusCode:       s.Code,
+			NormalizedStatus: normalizeCDEKStatus(s.Code),
+			Description:      s.Name,
+			Location:         location,
+			EventTime:        eventTime,
+			Source:           "CDEK",
 		})
 	}
 
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"ats-verify/internal/models"
+)
+
+// kazpostStatusMap maps Kazpost activity codes to the normalized status taxonomy.
+var kazpostStatusMap = map[string]models.TrackingStatus{
+	"Registered":       models.TrackingStatusAccepted,
+	"EMA":              models.TrackingStatusAccepted,
+	"EMB":              models.TrackingStatusInTransit,
+	"EMC":              models.TrackingStatusInTransit,
+	"TRANSIT":          models.TrackingStatusInTransit,
+	"DISPATCH":         models.TrackingStatusInTransit,
+	"SORT":             models.TrackingStatusInTransit,
+	"SRT_CUSTOM":       models.TrackingStatusArrivedCountry,
+	"EMD":              models.TrackingStatusArrivedCountry,
+	"Checking":         models.TrackingStatusCustomsHold,
+	"DetainedByCustom": models.TrackingStatusCustomsHold,
+	"PaymentRequired":  models.TrackingStatusCustomsHold,
+	"CORRECT":          models.TrackingStatusCustomsReleased,
+	"RejectionRelease": models.TrackingStatusCustomsReleased,
+	"ARRIVE":           models.TrackingStatusReadyForPickup,
+	"DELIVERY":         models.TrackingStatusReadyForPickup,
+	"HAND":             models.TrackingStatusDelivered,
+	"EMI":              models.TrackingStatusDelivered,
+	"RETURN":           models.TrackingStatusReturned,
+}
+
+// cdekStatusMap maps CDEK order status codes to the normalized status taxonomy.
+var cdekStatusMap = map[string]models.TrackingStatus{
+	"CREATED":                                   models.TrackingStatusAccepted,
+	"ACCEPTED":                                  models.TrackingStatusAccepted,
+	"RECEIVED_AT_SHIPMENT_WAREHOUSE":            models.TrackingStatusAccepted,
+	"READY_FOR_SHIPMENT_IN_SENDER_CITY":         models.TrackingStatusInTransit,
+	"TAKEN_BY_TRANSPORTER_FROM_SENDER_CITY":     models.TrackingStatusInTransit,
+	"SENT_TO_TRANSIT_CITY":                      models.TrackingStatusInTransit,
+	"ACCEPTED_IN_TRANSIT_CITY":                  models.TrackingStatusInTransit,
+	"ACCEPTED_AT_TRANSIT_WAREHOUSE":             models.TrackingStatusInTransit,
+	"TAKEN_BY_TRANSPORTER_FROM_TRANSIT_CITY":    models.TrackingStatusInTransit,
+	"SENT_TO_RECIPIENT_CITY":                    models.TrackingStatusInTransit,
+	"PASSED_TO_CARRIER":                         models.TrackingStatusInTransit,
+	"PASSED_TO_TRANSIT_CARRIER":                 models.TrackingStatusInTransit,
+	"SHIPPED_TO_DESTINATION":                    models.TrackingStatusInTransit,
+	"ACCEPTED_IN_RECIPIENT_CITY":                models.TrackingStatusArrivedCountry,
+	"IN_CUSTOMS_INTERNATIONAL":                  models.TrackingStatusCustomsHold,
+	"IN_CUSTOMS_LOCAL":                          models.TrackingStatusCustomsHold,
+	"CUSTOMS_COMPLETE":                          models.TrackingStatusCustomsReleased,
+	"ACCEPTED_AT_RECIPIENT_CITY_WAREHOUSE":      models.TrackingStatusReadyForPickup,
+	"ACCEPTED_AT_PICK_UP_POINT":                 models.TrackingStatusReadyForPickup,
+	"POSTOMAT_POSTED":                           models.TrackingStatusReadyForPickup,
+	"TAKEN_BY_COURIER":                          models.TrackingStatusReadyForPickup,
+	"DELIVERED":                                 models.TrackingStatusDelivered,
+	"POSTOMAT_RECEIVED":                         models.TrackingStatusDelivered,
+	"NOT_DELIVERED":                             models.TrackingStatusReturned,
+	"RETURNED_TO_SENDER_CITY_WAREHOUSE":         models.TrackingStatusReturned,
+	"RETURNED_TO_RECIPIENT_CITY_WAREHOUSE":      models.TrackingStatusReturned,
+	"RETURNED_TO_TRANSIT_WAREHOUSE":             models.TrackingStatusReturned,
+	"READY_FOR_SHIPMENT_IN_TRANSIT_CITY_RETURN": models.TrackingStatusReturned,
+}
+
+// normalizeKazpostStatus maps a Kazpost activity (which may carry several codes)
+// to one normalized status. The first recognized code wins.
+func normalizeKazpostStatus(codes []string) models.TrackingStatus {
+	for _, c := range codes {
+		if st, ok := kazpostStatusMap[strings.TrimSpace(c)]; ok {
+			return st
+		}
+	}
+	return models.TrackingStatusUnknown
+}
+
+// normalizeCDEKStatus maps a CDEK status code to the normalized taxonomy.
+func normalizeCDEKStatus(code string) models.TrackingStatus {
+	if st, ok := cdekStatusMap[strings.ToUpper(strings.TrimSpace(code))]; ok {
+		return st
+	}
+	return models.TrackingStatusUnknown
+}
+
+// CurrentStatus returns the normalized status of the most recent event that
+// carries a known status. Events with an unknown status never override a known one.
+func CurrentStatus(events []models.TrackingEvent) models.TrackingStatus {
+	sorted := make([]models.TrackingEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].EventTime.Before(sorted[j].EventTime)
+	})
+
+	current := models.TrackingStatusUnknown
+	for _, e := range sorted {
+		if e.NormalizedStatus != "" && e.NormalizedStatus != models.TrackingStatusUnknown {
+			current = e.NormalizedStatus
+		}
+	}
+	return current
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+func TestNormalizeKazpostStatus(t *testing.T) {
+	cases := map[string]struct {
+		codes []string
+		want  models.TrackingStatus
+	}{
+		"customs hold":      {[]string{"DetainedByCustom"}, models.TrackingStatusCustomsHold},
+		"payment required":  {[]string{"PaymentRequired"}, models.TrackingStatusCustomsHold},
+		"handed over":       {[]string{"HAND"}, models.TrackingStatusDelivered},
+		"first known wins":  {[]string{"???", "RejectionRelease"}, models.TrackingStatusCustomsReleased},
+		"unrecognized code": {[]string{"SOMETHING_NEW"}, models.TrackingStatusUnknown},
+		"no codes":          {nil, models.TrackingStatusUnknown},
+	}
+
+	for name, tc := range cases {
+		if got := normalizeKazpostStatus(tc.codes); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", name, tc.want, got)
+		}
+	}
+}
+
+func TestNormalizeCDEKStatus(t *testing.T) {
+	if got := normalizeCDEKStatus("IN_CUSTOMS_INTERNATIONAL"); got != models.TrackingStatusCustomsHold {
+		t.Errorf("expected customs_hold, got %s", got)
+	}
+	if got := normalizeCDEKStatus("delivered"); got != models.TrackingStatusDelivered {
+		t.Errorf("expected delivered for lower-case code, got %s", got)
+	}
+	if got := normalizeCDEKStatus("UNKNOWN_CODE"); got != models.TrackingStatusUnknown {
+		t.Errorf("expected unknown, got %s", got)
+	}
+}
+
+func TestCurrentStatus_LatestKnownEventWins(t *testing.T) {
+	base := time.Date(2025, 9, 5, 10, 0, 0, 0, time.UTC)
+	events := []models.TrackingEvent{
+		{NormalizedStatus: models.TrackingStatusCustomsHold, EventTime: base.Add(2 * time.Hour)},
+		{NormalizedStatus: models.TrackingStatusAccepted, EventTime: base},
+		{NormalizedStatus: models.TrackingStatusUnknown, EventTime: base.Add(3 * time.Hour)},
+	}
+
+	if got := CurrentStatus(events); got != models.TrackingStatusCustomsHold {
+		t.Errorf("expected customs_hold, got %s", got)
+	}
+	if got := CurrentStatus(nil); got != models.TrackingStatusUnknown {
+		t.Errorf("expected unknown for no events, got %s", got)
+	}
+}
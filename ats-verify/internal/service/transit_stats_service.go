@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+const (
+	// transitFlagFactor is how many times slower than overall a marketplace's customs
+	// dwell (p50 or p90) must be to get flagged.
+	transitFlagFactor = 1.5
+	// transitFlagMinSamples is the minimum number of customs dwell samples required to flag.
+	transitFlagMinSamples = 10
+)
+
+// DurationStats holds percentile statistics of a transit segment, in hours.
+type DurationStats struct {
+	Samples  int     `json:"samples"`
+	P50Hours float64 `json:"p50_hours"`
+	P90Hours float64 `json:"p90_hours"`
+}
+
+// TransitGroupStats holds transit metrics for one marketplace, country or carrier.
+type TransitGroupStats struct {
+	Key                 string        `json:"key"`
+	Parcels             int           `json:"parcels"`
+	FirstScanToCustoms  DurationStats `json:"first_scan_to_customs"`
+	CustomsDwell        DurationStats `json:"customs_dwell"`
+	FirstScanToHandover DurationStats `json:"first_scan_to_handover"`
+	Flagged             bool          `json:"flagged,omitempty"`
+	FlagReason          string        `json:"flag_reason,omitempty"`
+}
+
+// TransitStats is the response of the transit analytics endpoint.
+type TransitStats struct {
+	From          time.Time           `json:"from"`
+	To            time.Time           `json:"to"`
+	Overall       TransitGroupStats   `json:"overall"`
+	ByMarketplace []TransitGroupStats `json:"by_marketplace"`
+	ByCountry     []TransitGroupStats `json:"by_country"`
+	ByCarrier     []TransitGroupStats `json:"by_carrier"`
+	Flagged       []string            `json:"flagged_marketplaces"` // Marketplaces with abnormal customs dwell
+}
+
+// TransitStatsService computes delivery SLA and transit-time analytics.
+type TransitStatsService struct {
+	repo *repository.TransitStatsRepository
+}
+
+// NewTransitStatsService creates a new TransitStatsService.
+func NewTransitStatsService(repo *repository.TransitStatsRepository) *TransitStatsService {
+	return &TransitStatsService{repo: repo}
+}
+
+// Transit returns transit metrics for parcels first scanned in [from, to).
+func (s *TransitStatsService) Transit(ctx context.Context, from, to time.Time) (*TransitStats, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("period end must be after period start")
+	}
+
+	transits, err := s.repo.ListParcelTransits(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return buildTransitStats(transits, from, to), nil
+}
+
+// transitSamples accumulates segment durations for a group.
+type transitSamples struct {
+	parcels  int
+	toCustom []time.Duration
+	dwell    []time.Duration
+	handover []time.Duration
+}
+
+func (t *transitSamples) add(p models.ParcelTransit) {
+	t.parcels++
+	if p.CustomsInAt != nil && !p.CustomsInAt.Before(p.FirstScanAt) {
+		t.toCustom = append(t.toCustom, p.CustomsInAt.Sub(p.FirstScanAt))
+		if p.CustomsOutAt != nil && p.CustomsOutAt.After(*p.CustomsInAt) {
+			t.dwell = append(t.dwell, p.CustomsOutAt.Sub(*p.CustomsInAt))
+		}
+	}
+	if p.DeliveredAt != nil && !p.DeliveredAt.Before(p.FirstScanAt) {
+		t.handover = append(t.handover, p.DeliveredAt.Sub(p.FirstScanAt))
+	}
+}
+
+func (t *transitSamples) stats(key string) TransitGroupStats {
+	return TransitGroupStats{
+		Key:                 key,
+		Parcels:             t.parcels,
+		FirstScanToCustoms:  durationStats(t.toCustom),
+		CustomsDwell:        durationStats(t.dwell),
+		FirstScanToHandover: durationStats(t.handover),
+	}
+}
+
+func durationStats(d []time.Duration) DurationStats {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return DurationStats{
+		Samples:  len(d),
+		P50Hours: roundHours(percentileDuration(d, 0.50)),
+		P90Hours: roundHours(percentileDuration(d, 0.90)),
+	}
+}
+
+func roundHours(d time.Duration) float64 {
+	return float64(d.Round(6*time.Minute)) / float64(time.Hour)
+}
+
+// buildTransitStats groups parcel milestones and flags marketplaces whose customs
+// dwell is abnormally long compared to the overall figure.
+func buildTransitStats(transits []models.ParcelTransit, from, to time.Time) *TransitStats {
+	var overall transitSamples
+	byMarketplace := make(map[string]*transitSamples)
+	byCountry := make(map[string]*transitSamples)
+	byCarrier := make(map[string]*transitSamples)
+
+	for _, p := range transits {
+		overall.add(p)
+		groupSamples(byMarketplace, p.Marketplace).add(p)
+		groupSamples(byCountry, p.Country).add(p)
+		groupSamples(byCarrier, p.Carrier).add(p)
+	}
+
+	stats := &TransitStats{
+		From:          from,
+		To:            to,
+		Overall:       overall.stats("all"),
+		ByMarketplace: groupStats(byMarketplace),
+		ByCountry:     groupStats(byCountry),
+		ByCarrier:     groupStats(byCarrier),
+		Flagged:       []string{},
+	}
+
+	base := stats.Overall.CustomsDwell
+	for i := range stats.ByMarketplace {
+		g := &stats.ByMarketplace[i]
+		if g.CustomsDwell.Samples < transitFlagMinSamples || base.Samples == 0 {
+			continue
+		}
+		switch {
+		case g.CustomsDwell.P50Hours > transitFlagFactor*base.P50Hours:
+			g.FlagReason = fmt.Sprintf("customs dwell p50 %.1fh vs %.1fh overall", g.CustomsDwell.P50Hours, base.P50Hours)
+		case g.CustomsDwell.P90Hours > transitFlagFactor*base.P90Hours:
+			g.FlagReason = fmt.Sprintf("customs dwell p90 %.1fh vs %.1fh overall", g.CustomsDwell.P90Hours, base.P90Hours)
+		default:
+			continue
+		}
+		g.Flagged = true
+		stats.Flagged = append(stats.Flagged, g.Key)
+	}
+
+	return stats
+}
+
+func groupSamples(groups map[string]*transitSamples, key string) *transitSamples {
+	if key == "" {
+		key = "unknown"
+	}
+	g, ok := groups[key]
+	if !ok {
+		g = &transitSamples{}
+		groups[key] = g
+	}
+	return g
+}
+
+// groupStats returns group statistics ordered by parcel count (descending), then key.
+func groupStats(groups map[string]*transitSamples) []TransitGroupStats {
+	result := make([]TransitGroupStats, 0, len(groups))
+	for key, g := range groups {
+		result = append(result, g.stats(key))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Parcels != result[j].Parcels {
+			return result[i].Parcels > result[j].Parcels
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ats-verify/internal/models"
+)
+
+func transitFixture(marketplace string, dwell time.Duration, n int) []models.ParcelTransit {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var out []models.ParcelTransit
+	for i := 0; i < n; i++ {
+		first := base.Add(time.Duration(i) * time.Hour)
+		customsIn := first.Add(48 * time.Hour)
+		customsOut := customsIn.Add(dwell)
+		delivered := customsOut.Add(24 * time.Hour)
+		out = append(out, models.ParcelTransit{
+			Marketplace:  marketplace,
+			Country:      "CN",
+			Carrier:      "Kazpost",
+			FirstScanAt:  first,
+			CustomsInAt:  &customsIn,
+			CustomsOutAt: &customsOut,
+			DeliveredAt:  &delivered,
+		})
+	}
+	return out
+}
+
+func TestBuildTransitStats_Percentiles(t *testing.T) {
+	transits := transitFixture("Ozon", 10*time.Hour, 10)
+	// A parcel without customs milestones only counts towards parcels.
+	transits = append(transits, models.ParcelTransit{Marketplace: "Ozon", FirstScanAt: time.Now()})
+
+	stats := buildTransitStats(transits, time.Time{}, time.Now())
+	if stats.Overall.Parcels != 11 {
+		t.Fatalf("expected 11 parcels, got %d", stats.Overall.Parcels)
+	}
+	if d := stats.Overall.CustomsDwell; d.Samples != 10 || d.P50Hours != 10 || d.P90Hours != 10 {
+		t.Errorf("unexpected customs dwell stats: %+v", d)
+	}
+	if d := stats.Overall.FirstScanToCustoms; d.P50Hours != 48 {
+		t.Errorf("expected 48h to customs, got %+v", d)
+	}
+	if d := stats.Overall.FirstScanToHandover; d.P50Hours != 82 {
+		t.Errorf("expected 82h to handover, got %+v", d)
+	}
+	if len(stats.ByCountry) != 2 || stats.ByCountry[0].Key != "CN" || stats.ByCountry[1].Key != "unknown" {
+		t.Errorf("unexpected country groups: %+v", stats.ByCountry)
+	}
+}
+
+func TestBuildTransitStats_FlagsSlowCustomsDwell(t *testing.T) {
+	var transits []models.ParcelTransit
+	transits = append(transits, transitFixture("Wildberries", 12*time.Hour, 30)...)
+	transits = append(transits, transitFixture("Temu", 96*time.Hour, 10)...)
+	transits = append(transits, transitFixture("Kaspi", 200*time.Hour, 3)...) // too few samples
+
+	stats := buildTransitStats(transits, time.Time{}, time.Now())
+	if fmt.Sprint(stats.Flagged) != "[Temu]" {
+		t.Fatalf("expected only Temu to be flagged, got %v", stats.Flagged)
+	}
+	for _, g := range stats.ByMarketplace {
+		if g.Key == "Temu" && (!g.Flagged || g.FlagReason == "") {
+			t.Errorf("expected Temu group to carry a flag reason, got %+v", g)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+	"ats-verify/internal/repository"
+)
+
+var (
+	// ErrWatchlistLimit is returned when a user's watchlist is full for their role.
+	ErrWatchlistLimit = errors.New("watchlist limit reached")
+	// ErrInvalidWatch wraps validation errors of a new watchlist item.
+	ErrInvalidWatch = errors.New("invalid watchlist item")
+	// ErrAlreadyWatched is returned when the user already watches the track.
+	ErrAlreadyWatched = errors.New("track is already on the watchlist")
+)
+
+// UnlimitedWatchlist disables the watchlist limit for a role.
+const UnlimitedWatchlist = -1
+
+// watchlistRefreshBatch caps how many tracks one refresh pass queries.
+const watchlistRefreshBatch = 500
+
+// WatchlistService manages personal track watchlists and notifies subscribers
+// when a watched track gets a new event or its normalized status changes.
+type WatchlistService struct {
+	repo            *repository.WatchlistRepository
+	trackingService *TrackingService
+	notifications   *NotificationService
+	mailer          Mailer // nil when email is not configured
+	webhookClient   *http.Client
+	webhookSecret   string
+	limits          map[models.UserRole]int
+}
+
+// NewWatchlistService creates a new WatchlistService.
+// limits maps roles to their maximum watchlist size; roles without an entry cannot use watchlists.
+func NewWatchlistService(
+	repo *repository.WatchlistRepository,
+	trackingService *TrackingService,
+	notifications *NotificationService,
+	mailer Mailer,
+	webhookSecret string,
+	limits map[models.UserRole]int,
+) *WatchlistService {
+	return &WatchlistService{
+		repo:            repo,
+		trackingService: trackingService,
+		notifications:   notifications,
+		mailer:          mailer,
+		webhookClient:   newWebhookClient(),
+		webhookSecret:   webhookSecret,
+		limits:          limits,
+	}
+}
+
+// AddWatchInput is the payload for adding a track to the watchlist.
+type AddWatchInput struct {
+	TrackNumber string                `json:"track_number"`
+	Label       string                `json:"label"`
+	Channels    []models.WatchChannel `json:"channels"` // Defaults to in_app
+	Email       string                `json:"email"`    // Defaults to the username if it is an email address
+	WebhookURL  string                `json:"webhook_url"`
+}
+
+// WatchlistResponse is a user's watchlist together with their limit.
+type WatchlistResponse struct {
+	Items []models.WatchlistItem `json:"items"`
+	Used  int                    `json:"used"`
+	Limit int                    `json:"limit"` // -1 = unlimited
+}
+
+// Limit returns the watchlist size limit of a role (0 = not allowed).
+func (s *WatchlistService) Limit(role models.UserRole) int {
+	return s.limits[role]
+}
+
+// List returns the watchlist of a user.
+func (s *WatchlistService) List(ctx context.Context, userID uuid.UUID, role models.UserRole) (*WatchlistResponse, error) {
+	items, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.WatchlistItem{}
+	}
+	return &WatchlistResponse{Items: items, Used: len(items), Limit: s.Limit(role)}, nil
+}
+
+// Add validates the input, enforces the role limit and stores a new watchlist item.
+func (s *WatchlistService) Add(ctx context.Context, userID uuid.UUID, role models.UserRole, username string, input AddWatchInput) (*models.WatchlistItem, error) {
+	item, err := s.buildItem(userID, username, input)
+	if err != nil {
+		return nil, err
+	}
+	if item.WebhookURL != "" {
+		if err := checkWebhookTarget(ctx, item.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := s.Limit(role)
+	if limit == 0 {
+		return nil, ErrWatchlistLimit
+	}
+
+	// The repository checks the limit and inserts in one transaction.
+	if err := s.repo.Create(ctx, item, limit); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrWatchlistFull):
+			return nil, fmt.Errorf("%w (%d tracks)", ErrWatchlistLimit, limit)
+		case errors.Is(err, repository.ErrWatchlistDuplicate):
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyWatched, item.TrackNumber)
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// Remove deletes a watchlist item of the user.
+func (s *WatchlistService) Remove(ctx context.Context, id, userID uuid.UUID) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+// buildItem validates channels and their delivery targets.
+func (s *WatchlistService) buildItem(userID uuid.UUID, username string, input AddWatchInput) (*models.WatchlistItem, error) {
+	track := strings.TrimSpace(input.TrackNumber)
+	if track == "" {
+		return nil, fmt.Errorf("%w: track_number is required", ErrInvalidWatch)
+	}
+
+	channels := input.Channels
+	if len(channels) == 0 {
+		channels = []models.WatchChannel{models.WatchChannelInApp}
+	}
+
+	item := &models.WatchlistItem{
+		UserID:      userID,
+		TrackNumber: track,
+		Label:       strings.TrimSpace(input.Label),
+	}
+
+	seen := make(map[models.WatchChannel]bool)
+	for _, ch := range channels {
+		if seen[ch] {
+			continue
+		}
+		seen[ch] = true
+
+		switch ch {
+		case models.WatchChannelInApp:
+		case models.WatchChannelEmail:
+			if s.mailer == nil {
+				return nil, fmt.Errorf("%w: email notifications are not configured", ErrInvalidWatch)
+			}
+			email := strings.TrimSpace(input.Email)
+			if email == "" && strings.Contains(username, "@") {
+				email = username
+			}
+			if !strings.Contains(email, "@") {
+				return nil, fmt.Errorf("%w: a valid email is required for the email channel", ErrInvalidWatch)
+			}
+			item.Email = email
+		case models.WatchChannelWebhook:
+			u, err := url.Parse(strings.TrimSpace(input.WebhookURL))
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return nil, fmt.Errorf("%w: an http(s) webhook_url is required for the webhook channel", ErrInvalidWatch)
+			}
+			item.WebhookURL = u.String()
+		default:
+			return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidWatch, ch)
+		}
+		item.Channels = append(item.Channels, string(ch))
+	}
+
+	return item, nil
+}
+
+// cgnatRange is the carrier-grade NAT range (RFC 6598), internal like RFC 1918.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether webhooks may be delivered to ip. Loopback, private,
+// link-local (including the 169.254.169.254 metadata service), multicast and
+// unspecified addresses are internal to the cluster.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !cgnatRange.Contains(ip)
+}
+
+// checkWebhookTarget resolves the webhook host and rejects it unless every address is public.
+func checkWebhookTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid webhook_url", ErrInvalidWatch)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: webhook host %s cannot be resolved", ErrInvalidWatch, u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: webhook_url must point to a public address", ErrInvalidWatch)
+		}
+	}
+	return nil
+}
+
+// newWebhookClient creates the HTTP client for webhook delivery. The address is checked
+// again when connecting, because DNS may have changed since the watch was added and
+// redirects may lead elsewhere.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook to non-public address %s refused", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect on our behalf and bypass the check.
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// Run refreshes due watchlist items every interval until ctx is cancelled.
+func (s *WatchlistService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshDue(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDue queries providers for items not checked within the interval.
+// The cutoff is slightly shorter than the interval so ticker jitter does not skip a round.
+func (s *WatchlistService) refreshDue(ctx context.Context, interval time.Duration) {
+	items, err := s.repo.ListDue(ctx, time.Now().Add(-interval*9/10), watchlistRefreshBatch)
+	if err != nil {
+		log.Printf("watchlist: %v", err)
+		return
+	}
+	for i := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		s.refresh(ctx, &items[i])
+	}
+}
+
+// refresh updates one item and notifies its channels when something changed.
+func (s *WatchlistService) refresh(ctx context.Context, item *models.WatchlistItem) {
+	now := time.Now()
+	result, err := s.trackingService.Track(ctx, item.TrackNumber)
+
+	prevStatus := item.LastStatus
+	var change *watchChange
+	if err != nil {
+		item.LastError = err.Error()
+	} else {
+		item.LastError = ""
+		change = detectWatchChange(item, result)
+	}
+	item.LastCheckedAt = &now
+
+	if err := s.repo.UpdateState(ctx, item); err != nil {
+		log.Printf("watchlist: %v", err)
+		return
+	}
+	if change != nil {
+		s.dispatch(ctx, item, prevStatus, change)
+	}
+}
+
+// watchChange describes what changed for a watched track since the last check.
+type watchChange struct {
+	Status models.TrackingStatus
+	Event  *models.TrackingEvent
+}
+
+// detectWatchChange applies a tracking result to the item state and reports a change.
+// The first successful check only records a baseline and never notifies.
+func detectWatchChange(item *models.WatchlistItem, result *TrackingResult) *watchChange {
+	status := result.Status
+	if status == "" {
+		status = models.TrackingStatusUnknown
+	}
+
+	latest := latestEvent(result.Events)
+	key := ""
+	if latest != nil {
+		key = fmt.Sprintf("%s|%s|%d", latest.Source, latest.StatusCode, latest.EventTime.Unix())
+	}
+
+	baseline := item.LastCheckedAt == nil
+	changed := key != item.LastEventKey || status != item.LastStatus
+
+	item.LastStatus = status
+	item.LastEventKey = key
+	if latest != nil {
+		t := latest.EventTime
+		item.LastEventAt = &t
+	}
+
+	if baseline || !changed {
+		return nil
+	}
+	return &watchChange{Status: status, Event: latest}
+}
+
+// watchWebhookPayload is the JSON body posted to subscriber webhooks.
+type watchWebhookPayload struct {
+	Event          string                `json:"event"`
+	WatchID        uuid.UUID             `json:"watch_id"`
+	TrackNumber    string                `json:"track_number"`
+	Label          string                `json:"label,omitempty"`
+	Status         models.TrackingStatus `json:"status"`
+	PreviousStatus models.TrackingStatus `json:"previous_status"`
+	LatestEvent    *models.TrackingEvent `json:"latest_event,omitempty"`
+	SentAt         time.Time             `json:"sent_at"`
+}
+
+func (s *WatchlistService) dispatch(ctx context.Context, item *models.WatchlistItem, prevStatus models.TrackingStatus, change *watchChange) {
+	title, body := watchMessage(item, prevStatus, change)
+
+	for _, ch := range item.Channels {
+		var err error
+		switch models.WatchChannel(ch) {
+		case models.WatchChannelInApp:
+			err = s.notifications.NotifyUser(ctx, item.UserID, models.Notification{
+				Kind:        models.NotificationTrackingUpdate,
+				Title:       title,
+				Body:        body,
+				TrackNumber: item.TrackNumber,
+			})
+		case models.WatchChannelEmail:
+			if s.mailer != nil {
+				err = s.mailer.Send(item.Email, title, body)
+			}
+		case models.WatchChannelWebhook:
+			err = s.postWebhook(ctx, item.WebhookURL, watchWebhookPayload{
+				Event:          "tracking.updated",
+				WatchID:        item.ID,
+				TrackNumber:    item.TrackNumber,
+				Label:          item.Label,
+				Status:         change.Status,
+				PreviousStatus: prevStatus,
+				LatestEvent:    change.Event,
+				SentAt:         time.Now().UTC(),
+			})
+		}
+		if err != nil {
+			log.Printf("watchlist: %s notification for %s: %v", ch, item.TrackNumber, err)
+		}
+	}
+}
+
+// postWebhook posts the payload; with a secret configured the body is signed
+// in the X-Signature-256 header as "sha256=<hex hmac>".
+func (s *WatchlistService) postWebhook(ctx context.Context, target string, payload watchWebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.webhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func watchMessage(item *models.WatchlistItem, prevStatus models.TrackingStatus, change *watchChange) (string, string) {
+	name := item.TrackNumber
+	if item.Label != "" {
+		name += " (" + item.Label + ")"
+	}
+	title := "Обновление по треку " + name
+
+	lines := []string{"Трек: " + item.TrackNumber}
+	if change.Status != prevStatus {
+		lines = append(lines, fmt.Sprintf("Статус: %s → %s", prevStatus, change.Status))
+	} else {
+		lines = append(lines, "Статус: "+string(change.Status))
+	}
+	if e := change.Event; e != nil {
+		event := e.Description
+		if e.Location != "" {
+			event += ", " + e.Location
+		}
+		if !e.EventTime.IsZero() {
+			event += ", " + e.EventTime.Format("02.01.2006 15:04")
+		}
+		lines = append(lines, "Событие: "+event)
+	}
+	return title, strings.Join(lines, "\n")
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ats-verify/internal/models"
+)
+
+type nopMailer struct{}
+
+func (nopMailer) Send(to, subject, body string) error { return nil }
+
+func TestDetectWatchChange(t *testing.T) {
+	item := &models.WatchlistItem{LastStatus: models.TrackingStatusUnknown}
+	first := &TrackingResult{
+		Status: models.TrackingStatusInTransit,
+		Events: []models.TrackingEvent{{Source: "Kazpost", StatusCode: "TRANSIT", EventTime: time.Unix(100, 0)}},
+	}
+
+	if change := detectWatchChange(item, first); change != nil {
+		t.Fatalf("expected first check to only record a baseline, got %+v", change)
+	}
+	now := time.Now()
+	item.LastCheckedAt = &now
+
+	if change := detectWatchChange(item, first); change != nil {
+		t.Fatalf("expected no change for the same events, got %+v", change)
+	}
+
+	next := &TrackingResult{
+		Status: models.TrackingStatusCustomsHold,
+		Events: append(first.Events, models.TrackingEvent{Source: "Kazpost", StatusCode: "DetainedByCustom", EventTime: time.Unix(200, 0)}),
+	}
+	change := detectWatchChange(item, next)
+	if change == nil || change.Status != models.TrackingStatusCustomsHold || change.Event.StatusCode != "DetainedByCustom" {
+		t.Fatalf("expected customs hold change, got %+v", change)
+	}
+	if item.LastStatus != models.TrackingStatusCustomsHold || item.LastEventAt == nil || !item.LastEventAt.Equal(time.Unix(200, 0)) {
+		t.Errorf("expected item state to be updated, got %+v", item)
+	}
+}
+
+func TestWatchlistService_ValidatesChannels(t *testing.T) {
+	svc := NewWatchlistService(nil, nil, nil, nil, "", nil)
+
+	if _, err := svc.buildItem(uuid.New(), "user@example.kz", AddWatchInput{TrackNumber: "CN1", Channels: []models.WatchChannel{models.WatchChannelEmail}}); !errors.Is(err, ErrInvalidWatch) {
+		t.Errorf("expected email channel to be rejected without SMTP, got %v", err)
+	}
+	if _, err := svc.buildItem(uuid.New(), "u", AddWatchInput{TrackNumber: "CN1", Channels: []models.WatchChannel{models.WatchChannelWebhook}, WebhookURL: "ftp://x"}); !errors.Is(err, ErrInvalidWatch) {
+		t.Errorf("expected non-http webhook to be rejected, got %v", err)
+	}
+
+	svc.mailer = nopMailer{}
+	item, err := svc.buildItem(uuid.New(), "user@example.kz", AddWatchInput{
+		TrackNumber: " CN1 ",
+		Channels:    []models.WatchChannel{models.WatchChannelEmail, models.WatchChannelInApp, models.WatchChannelEmail},
+	})
+	if err != nil {
+		t.Fatalf("expected valid item, got %v", err)
+	}
+	if item.TrackNumber != "CN1" || item.Email != "user@example.kz" || len(item.Channels) != 2 {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
+
+func TestWatchlistService_LimitPerRole(t *testing.T) {
+	svc := NewWatchlistService(nil, nil, nil, nil, "", map[models.UserRole]int{models.RolePaidUser: 10})
+
+	if _, err := svc.Add(context.Background(), uuid.New(), models.RoleMarketplace, "", AddWatchInput{TrackNumber: "CN1"}); !errors.Is(err, ErrWatchlistLimit) {
+		t.Errorf("expected role without a limit entry to be rejected, got %v", err)
+	}
+}
+
+func TestWatchlistService_SignsWebhook(t *testing.T) {
+	var payload watchWebhookPayload
+	var signature string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature-256")
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := NewWatchlistService(nil, nil, nil, nil, "hook-secret", nil)
+	svc.webhookClient = srv.Client() // The test server listens on loopback.
+	err := svc.postWebhook(context.Background(), srv.URL, watchWebhookPayload{
+		Event:       "tracking.updated",
+		TrackNumber: "CN1",
+		Status:      models.TrackingStatusDelivered,
+	})
+	if err != nil {
+		t.Fatalf("expected webhook delivery, got %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("hook-secret"))
+	mac.Write(body)
+	if signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("unexpected signature %q", signature)
+	}
+	if payload.TrackNumber != "CN1" || payload.Status != models.TrackingStatusDelivered {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestWatchlistService_RejectsInternalWebhooks(t *testing.T) {
+	for _, target := range []string{"http://127.0.0.1:8080/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook"} {
+		if err := checkWebhookTarget(context.Background(), target); !errors.Is(err, ErrInvalidWatch) {
+			t.Errorf("%s: expected internal address to be rejected, got %v", target, err)
+		}
+	}
+	if err := checkWebhookTarget(context.Background(), "https://203.0.113.10/hook"); err != nil {
+		t.Errorf("expected public address to be accepted, got %v", err)
+	}
+
+	// The dial-time check also stops delivery to a host that resolves internally later.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to reach a loopback webhook")
+	}))
+	defer srv.Close()
+	svc := NewWatchlistService(nil, nil, nil, nil, "", nil)
+	if err := svc.postWebhook(context.Background(), srv.URL, watchWebhookPayload{}); err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Errorf("expected loopback delivery to be refused, got %v", err)
+	}
+}
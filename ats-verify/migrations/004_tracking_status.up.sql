@@ -0,0 +1,22 @@
+-- +goose Up
+-- Unified cross-carrier tracking status taxonomy.
+CREATE TYPE tracking_status AS ENUM (
+    'accepted', 'in_transit', 'arrived_country', 'customs_hold', 'customs_released',
+    'ready_for_pickup', 'delivered', 'returned', 'unknown'
+);
+
+ALTER TABLE tracking_events ADD COLUMN normalized_status tracking_status NOT NULL DEFAULT 'unknown';
+ALTER TABLE parcels ADD COLUMN tracking_status tracking_status NOT NULL DEFAULT 'unknown';
+
+-- Deduplicates repeated provider responses for the same parcel.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tracking_events_dedup ON tracking_events(parcel_id, source, status_code, event_time);
+CREATE INDEX IF NOT EXISTS idx_tracking_events_parcel_time ON tracking_events(parcel_id, event_time);
+CREATE INDEX IF NOT EXISTS idx_parcels_tracking_status ON parcels(tracking_status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_parcels_tracking_status;
+DROP INDEX IF EXISTS idx_tracking_events_parcel_time;
+DROP INDEX IF EXISTS idx_tracking_events_dedup;
+ALTER TABLE parcels DROP COLUMN tracking_status;
+ALTER TABLE tracking_events DROP COLUMN normalized_status;
+DROP TYPE tracking_status;
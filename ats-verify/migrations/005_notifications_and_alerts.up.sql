@@ -0,0 +1,33 @@
+-- +goose Up
+-- In-app notifications and deduplicated customs detention alerts.
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT DEFAULT '',
+    parcel_id UUID REFERENCES parcels(id) ON DELETE SET NULL,
+    track_number VARCHAR(100) DEFAULT '',
+    ticket_id UUID REFERENCES support_tickets(id) ON DELETE SET NULL,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS tracking_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcel_id UUID NOT NULL REFERENCES parcels(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    status_code VARCHAR(50) DEFAULT '',
+    ticket_id UUID REFERENCES support_tickets(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (parcel_id, kind)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tracking_alerts;
+DROP INDEX IF EXISTS idx_notifications_user_unread;
+DROP INDEX IF EXISTS idx_notifications_user_created;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Personal track watchlists with background refresh and notification channels.
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    track_number VARCHAR(100) NOT NULL,
+    label VARCHAR(255) DEFAULT '',
+    channels TEXT[] NOT NULL DEFAULT '{in_app}',
+    email VARCHAR(255) DEFAULT '',
+    webhook_url TEXT DEFAULT '',
+    last_status tracking_status NOT NULL DEFAULT 'unknown',
+    last_event_key TEXT DEFAULT '',
+    last_event_at TIMESTAMP WITH TIME ZONE,
+    last_checked_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, track_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_items_last_checked ON watchlist_items(last_checked_at NULLS FIRST);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_watchlist_items_last_checked;
+DROP TABLE IF EXISTS watchlist_items;
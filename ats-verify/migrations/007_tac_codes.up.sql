@@ -0,0 +1,11 @@
+-- +goose Up
+-- Local TAC table resolving the first 8 IMEI digits to manufacturer and model.
+CREATE TABLE IF NOT EXISTS tac_codes (
+    tac CHAR(8) PRIMARY KEY,
+    manufacturer VARCHAR(255) NOT NULL,
+    model VARCHAR(255) DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tac_codes;
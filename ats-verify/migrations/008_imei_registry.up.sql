@@ -0,0 +1,16 @@
+-- +goose Up
+-- Registry of devices found in verified declarations, linked to the stored verification.
+CREATE TABLE IF NOT EXISTS imei_registry (
+    imei14 CHAR(14) NOT NULL,
+    report_id UUID NOT NULL REFERENCES analysis_reports(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    declaration VARCHAR(255) DEFAULT '',
+    csv_line INT NOT NULL DEFAULT 0,
+    verified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (imei14, report_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_imei_registry_imei_verified ON imei_registry(imei14, verified_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS imei_registry;
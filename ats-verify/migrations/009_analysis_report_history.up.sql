@@ -0,0 +1,19 @@
+-- +goose Up
+-- Stores input files, their SHA-256 hashes and the full results of each IMEI verification.
+ALTER TABLE analysis_reports ADD COLUMN IF NOT EXISTS csv_file_name VARCHAR(255) DEFAULT '';
+ALTER TABLE analysis_reports ADD COLUMN IF NOT EXISTS csv_sha256 VARCHAR(64) DEFAULT '';
+ALTER TABLE analysis_reports ADD COLUMN IF NOT EXISTS pdf_file_names TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE analysis_reports ADD COLUMN IF NOT EXISTS pdf_sha256 TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE analysis_reports ADD COLUMN IF NOT EXISTS results JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_analysis_reports_type_created ON analysis_reports(report_type, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_analysis_reports_user_created ON analysis_reports(user_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_analysis_reports_user_created;
+DROP INDEX IF EXISTS idx_analysis_reports_type_created;
+ALTER TABLE analysis_reports DROP COLUMN IF EXISTS results;
+ALTER TABLE analysis_reports DROP COLUMN IF EXISTS pdf_sha256;
+ALTER TABLE analysis_reports DROP COLUMN IF EXISTS pdf_file_names;
+ALTER TABLE analysis_reports DROP COLUMN IF EXISTS csv_sha256;
+ALTER TABLE analysis_reports DROP COLUMN IF EXISTS csv_file_name;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Different TACs known to belong to one dual-SIM device (Imei1/Imei2). Stored with tac_a < tac_b.
+CREATE TABLE IF NOT EXISTS tac_pairs (
+    tac_a CHAR(8) NOT NULL,
+    tac_b CHAR(8) NOT NULL,
+    note VARCHAR(255) DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (tac_a, tac_b),
+    CHECK (tac_a < tac_b)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tac_pairs;
@@ -5,9 +5,19 @@ import api from '../lib/api';
 interface IMEIResult {
     csv_line: number;
     column: string;
+    raw_value: string;
     imei_14: string;
     found: boolean;
     matched_imei?: string;
+    normalized?: boolean;
+    pair_mismatch?: boolean;
+    pair_reason?: string;
+    normalized_from?: string;
+    source_file?: string;
+    source_page?: number;
+    goods_item?: number;
+    duplicate_of_line?: number;
+    previous_declaration?: { report_id?: string; declaration?: string; verified_at: string };
 }
 
 interface IMEIColumnStats {
@@ -17,13 +27,43 @@ interface IMEIColumnStats {
     missing: number;
 }
 
+interface IMEIFileStats {
+    file_name: string;
+    pages: number;
+    imei_sequences: number;
+    matched: number;
+}
+
+interface IMEIUnexpected {
+    imei: string;
+    source_file?: string;
+    source_page: number;
+    line: number;
+    context: string;
+}
+
+// Reasons a declaration PDF could not be read (error code of /imei/analyze).
+const PDF_ERRORS: Record<string, string> = {
+    encrypted: 'PDF защищён паролем — укажите пароль или проверьте его.',
+    no_text_layer: 'PDF не содержит текстового слоя (скан). Нужно распознать текст (OCR) или загрузить электронную декларацию.',
+    corrupt: 'PDF повреждён и не может быть прочитан.',
+    too_large: 'PDF слишком большой.',
+};
+
 interface IMEIReport {
+    mode?: 'imei' | 'serial';
+    match_rule?: string;
     total_imeis: number;
     total_found: number;
     total_missing: number;
+    total_unexpected?: number;
+    unexpected_in_pdf?: IMEIUnexpected[];
     column_stats: IMEIColumnStats[];
+    detected_columns?: { column: string; position: number; method: 'header' | 'content' | 'selected'; reason: string }[];
+    file_stats?: IMEIFileStats[];
     results: IMEIResult[];
     text_report?: string;
+    report_id?: string;
 }
 
 export default function IMEIPage() {
@@ -33,22 +73,35 @@ export default function IMEIPage() {
     const csvRef = useRef<HTMLInputElement>(null);
     const pdfRef = useRef<HTMLInputElement>(null);
     const [csvFile, setCsvFile] = useState<File | null>(null);
-    const [pdfFile, setPdfFile] = useState<File | null>(null);
+    const [pdfFiles, setPdfFiles] = useState<File[]>([]);
+    const [mode, setMode] = useState<'imei' | 'serial'>('imei');
+    const [serialColumns, setSerialColumns] = useState('');
+    const [matchRule, setMatchRule] = useState<'exact' | 'prefix' | 'alnum'>('exact');
+    const [prefixLength, setPrefixLength] = useState(8);
+    const [pdfPassword, setPdfPassword] = useState('');
 
     const handleAnalyze = async () => {
-        if (!csvFile || !pdfFile) return;
+        if (!csvFile || pdfFiles.length === 0) return;
         setLoading(true);
         setError('');
 
         try {
             const formData = new FormData();
             formData.append('csv_file', csvFile);
-            formData.append('pdf_file', pdfFile);
+            pdfFiles.forEach(f => formData.append('pdf_files', f));
+            if (pdfPassword) formData.append('pdf_password', pdfPassword);
+            if (mode === 'serial') {
+                formData.append('mode', 'serial');
+                formData.append('columns', serialColumns);
+                formData.append('match', matchRule);
+                if (matchRule === 'prefix') formData.append('prefix_length', String(prefixLength));
+            }
             const { data } = await api.post('/imei/analyze', formData);
             setReport(data);
         } catch (err: unknown) {
-            const msg = (err as { response?: { data?: { error?: string } } })?.response?.data?.error;
-            setError(msg || 'Ошибка анализа. Убедитесь что бэкенд запущен.');
+            const data = (err as { response?: { data?: { error?: string; code?: string; message?: string } } })?.response?.data;
+            const pdfError = data?.code ? PDF_ERRORS[data.code] : undefined;
+            setError(pdfError ? `${pdfError} ${data?.message ?? ''}` : data?.error || 'Ошибка анализа. Убедитесь что бэкенд запущен.');
         } finally {
             setLoading(false);
         }
@@ -59,14 +112,38 @@ export default function IMEIPage() {
 
     const handleExport = () => {
         if (results.length === 0) return;
-        const headers = ['Row', 'Column', 'IMEI Code (14-digit)', 'Status in PDF', 'Matched 15-digit Number'];
-        const rows = results.map(r => [r.csv_line, r.column, r.imei_14, r.found ? 'Found' : 'Missing', r.matched_imei || '']);
+        const headers = ['Row', 'Column', 'IMEI Code (14-digit)', 'Status in PDF', 'Matched 15-digit Number', 'File', 'Page'];
+        const rows = results.map(r => [r.csv_line, r.column, r.imei_14, r.found ? 'Found' : 'Missing', r.matched_imei || '', r.source_file || '', r.source_page || '']);
         const csv = [headers.join(','), ...rows.map(r => r.join(','))].join('\n');
         const blob = new Blob([csv], { type: 'text/csv' });
         const url = URL.createObjectURL(blob);
         const a = document.createElement('a'); a.href = url; a.download = 'imei_report.csv'; a.click();
     };
 
+    const handleServerExport = async (format: 'xlsx' | 'csv' | 'pdf') => {
+        if (!report?.report_id) return;
+        try {
+            const { data } = await api.get(`/imei/reports/${report.report_id}/export`, { params: { format }, responseType: 'blob' });
+            const url = URL.createObjectURL(data);
+            const a = document.createElement('a'); a.href = url; a.download = `imei-report-${report.report_id}.${format}`; a.click();
+            URL.revokeObjectURL(url);
+        } catch {
+            setError('Не удалось выгрузить отчёт.');
+        }
+    };
+
+    const handleCertificate = async () => {
+        if (!report?.report_id) return;
+        try {
+            const { data } = await api.get(`/imei/reports/${report.report_id}/certificate`, { params: { format: 'pdf' }, responseType: 'blob' });
+            const url = URL.createObjectURL(data);
+            const a = document.createElement('a'); a.href = url; a.download = `imei-certificate-${report.report_id}.pdf`; a.click();
+            URL.revokeObjectURL(url);
+        } catch {
+            setError('Не удалось получить сертификат.');
+        }
+    };
+
     return (
         <div>
             {/* Header */}
@@ -80,7 +157,21 @@ export default function IMEIPage() {
                     <h1 className="page-title">Анализ IMEI кодов</h1>
                     <p className="page-subtitle">Проверка 14-значных IMEI из CSV против 15-значных номеров в PDF-декларации</p>
                 </div>
-                {results.length > 0 && (
+                {results.length > 0 && report?.report_id && (
+                    <div className="flex gap-2">
+                        {(['xlsx', 'pdf', 'csv'] as const).map(format => (
+                            <button key={format} onClick={() => handleServerExport(format)} className="btn-primary">
+                                <Download size={16} />
+                                {format.toUpperCase()}
+                            </button>
+                        ))}
+                        <button onClick={handleCertificate} className="btn-primary" title="Подписанный сертификат проверки для третьих лиц">
+                            <Download size={16} />
+                            Сертификат
+                        </button>
+                    </div>
+                )}
+                {results.length > 0 && !report?.report_id && (
                     <button onClick={handleExport} className="btn-primary">
                         <Download size={16} />
                         Export Report
@@ -107,15 +198,36 @@ export default function IMEIPage() {
                         </div>
                         <div
                             onClick={() => pdfRef.current?.click()}
-                            className={`border-2 border-dashed rounded-xl p-6 text-center cursor-pointer transition-all ${pdfFile ? 'border-success bg-success-light' : 'border-border hover:border-primary/40'}`}
+                            className={`border-2 border-dashed rounded-xl p-6 text-center cursor-pointer transition-all ${pdfFiles.length > 0 ? 'border-success bg-success-light' : 'border-border hover:border-primary/40'}`}
                         >
-                            <input ref={pdfRef} type="file" accept=".pdf" onChange={(e) => e.target.files?.[0] && setPdfFile(e.target.files[0])} className="hidden" />
-                            <Upload size={24} className={`mx-auto mb-2 ${pdfFile ? 'text-green-600' : 'text-text-muted'}`} />
-                            <p className="text-sm font-medium">{pdfFile ? pdfFile.name : 'PDF Декларация'}</p>
-                            <p className="text-xs text-text-muted mt-1">Таможенный документ</p>
+                            <input ref={pdfRef} type="file" accept=".pdf" multiple onChange={(e) => e.target.files && setPdfFiles(Array.from(e.target.files))} className="hidden" />
+                            <Upload size={24} className={`mx-auto mb-2 ${pdfFiles.length > 0 ? 'text-green-600' : 'text-text-muted'}`} />
+                            <p className="text-sm font-medium">{pdfFiles.length > 0 ? pdfFiles.map(f => f.name).join(', ') : 'PDF Декларация'}</p>
+                            <p className="text-xs text-text-muted mt-1">Таможенный документ (можно несколько частей)</p>
                         </div>
                     </div>
 
+                    <div className="flex flex-wrap items-center gap-3 mb-4 text-sm">
+                        <select value={mode} onChange={(e) => setMode(e.target.value as 'imei' | 'serial')} className="input w-auto">
+                            <option value="imei">IMEI</option>
+                            <option value="serial">Серийные номера</option>
+                        </select>
+                        {mode === 'serial' && (
+                            <>
+                                <input value={serialColumns} onChange={(e) => setSerialColumns(e.target.value)} placeholder="Колонки через запятую, напр. Serial" className="input flex-1 min-w-48" />
+                                <select value={matchRule} onChange={(e) => setMatchRule(e.target.value as 'exact' | 'prefix' | 'alnum')} className="input w-auto">
+                                    <option value="exact">Точное совпадение</option>
+                                    <option value="prefix">Первые N символов</option>
+                                    <option value="alnum">Только буквы и цифры, без регистра</option>
+                                </select>
+                                {matchRule === 'prefix' && (
+                                    <input type="number" min={4} value={prefixLength} onChange={(e) => setPrefixLength(Number(e.target.value))} className="input w-20" />
+                                )}
+                            </>
+                        )}
+                        <input type="password" value={pdfPassword} onChange={(e) => setPdfPassword(e.target.value)} placeholder="Пароль PDF (если есть)" autoComplete="off" className="input w-56" />
+                    </div>
+
                     {error && (
                         <div className="mb-4 bg-danger-light border border-danger/20 text-danger text-sm px-4 py-3 rounded-lg">
                             {error}
@@ -123,7 +235,7 @@ export default function IMEIPage() {
                     )}
 
                     <div className="flex justify-end">
-                        <button onClick={handleAnalyze} disabled={!csvFile || !pdfFile || loading} className="btn-primary disabled:opacity-50 disabled:cursor-not-allowed">
+                        <button onClick={handleAnalyze} disabled={!csvFile || pdfFiles.length === 0 || (mode === 'serial' && !serialColumns.trim()) || loading} className="btn-primary disabled:opacity-50 disabled:cursor-not-allowed">
                             {loading ? 'Анализ...' : 'Запустить анализ'}
                         </button>
                     </div>
@@ -167,7 +279,7 @@ export default function IMEIPage() {
 
                     {/* New analysis button */}
                     <div className="mb-4">
-                        <button onClick={() => { setReport(null); setCsvFile(null); setPdfFile(null); }} className="btn-secondary text-sm">
+                        <button onClick={() => { setReport(null); setCsvFile(null); setPdfFiles([]); }} className="btn-secondary text-sm">
                             ← Новый анализ
                         </button>
                     </div>
@@ -180,14 +292,31 @@ export default function IMEIPage() {
 
                         {/* Per-column Stats */}
                         <div className="px-6 py-4 bg-bg-hover border-b border-border flex flex-wrap gap-4">
-                            {report.column_stats.map((colStat) => (
-                                <div key={colStat.column} className="bg-bg-white border border-border rounded-lg px-3 py-2 text-sm shadow-sm">
-                                    <span className="font-semibold text-text-primary">{colStat.column}:</span>{' '}
-                                    <span className="text-green-600">{colStat.found}</span> / <span className="text-text-primary">{colStat.total}</span>
-                                </div>
-                            ))}
+                            {report.column_stats.map((colStat) => {
+                                const detected = report.detected_columns?.find(d => d.column === colStat.column);
+                                return (
+                                    <div key={colStat.column} title={detected?.reason} className="bg-bg-white border border-border rounded-lg px-3 py-2 text-sm shadow-sm">
+                                        <span className="font-semibold text-text-primary">{colStat.column}:</span>{' '}
+                                        <span className="text-green-600">{colStat.found}</span> / <span className="text-text-primary">{colStat.total}</span>
+                                        {detected?.method === 'content' && <span className="text-text-muted"> (по содержимому)</span>}
+                                    </div>
+                                );
+                            })}
                         </div>
 
+                        {/* Per-file Stats */}
+                        {report.file_stats && report.file_stats.length > 1 && (
+                            <div className="px-6 py-4 bg-bg-hover border-b border-border flex flex-wrap gap-4">
+                                {report.file_stats.map((fileStat) => (
+                                    <div key={fileStat.file_name} className="bg-bg-white border border-border rounded-lg px-3 py-2 text-sm shadow-sm">
+                                        <span className="font-semibold text-text-primary">{fileStat.file_name}:</span>{' '}
+                                        <span className="text-green-600">{fileStat.matched}</span> / <span className="text-text-primary">{fileStat.imei_sequences}</span>
+                                        <span className="text-text-muted"> ({fileStat.pages} стр.)</span>
+                                    </div>
+                                ))}
+                            </div>
+                        )}
+
                         <table className="data-table">
                             <thead>
                                 <tr>
@@ -196,6 +325,7 @@ export default function IMEIPage() {
                                     <th>IMEI Code (14-digit)</th>
                                     <th>Status in PDF</th>
                                     <th>Matched 15-digit Number</th>
+                                    <th>Source</th>
                                 </tr>
                             </thead>
                             <tbody>
@@ -203,15 +333,30 @@ export default function IMEIPage() {
                                     <tr key={i}>
                                         <td className="text-text-muted">{String(r.csv_line).padStart(3, '0')}</td>
                                         <td className="font-medium text-text-secondary">{r.column}</td>
-                                        <td className="font-mono font-medium text-text-primary">{r.imei_14}</td>
+                                        <td className="font-mono font-medium text-text-primary">{r.imei_14 || r.raw_value}</td>
                                         <td>
                                             {r.found ? (
                                                 <span className="badge-success"><CheckCircle size={12} /> Found</span>
                                             ) : (
                                                 <span className="badge-danger"><XCircle size={12} /> Missing</span>
                                             )}
+                                            {r.pair_mismatch && (
+                                                <span className="badge-danger ml-1" title={r.pair_reason}>Разные устройства в строке</span>
+                                            )}
+                                            {r.normalized && (
+                                                <span className="badge-warning ml-1" title={`В PDF: ${r.normalized_from}`}>Разорванный номер — проверить</span>
+                                            )}
+                                            {r.duplicate_of_line && (
+                                                <span className="badge-warning ml-1">Дубль строки {r.duplicate_of_line}</span>
+                                            )}
+                                            {r.previous_declaration && (
+                                                <span className="badge-warning ml-1" title={r.previous_declaration.report_id ? `Отчёт ${r.previous_declaration.report_id}` : 'Проверка другого пользователя'}>
+                                                    Уже декларирован {new Date(r.previous_declaration.verified_at).toLocaleDateString('ru-RU')}
+                                                </span>
+                                            )}
                                         </td>
                                         <td className="font-mono text-text-secondary">{r.matched_imei || <span className="text-text-muted italic">-- Not found --</span>}</td>
+                                        <td className="text-text-secondary">{r.source_page ? `${r.source_file || 'PDF'}, p. ${r.source_page}${r.goods_item ? `, товар ${r.goods_item}` : ''}` : ''}</td>
                                     </tr>
                                 ))}
                             </tbody>
@@ -222,6 +367,32 @@ export default function IMEIPage() {
                         </div>
                     </div>
 
+                    {report.unexpected_in_pdf && report.unexpected_in_pdf.length > 0 && (
+                        <div className="mt-8 card overflow-hidden">
+                            <div className="px-6 py-4 border-b border-border">
+                                <h3 className="text-base font-semibold text-text-primary">Есть в PDF, нет в CSV ({report.total_unexpected})</h3>
+                            </div>
+                            <table className="data-table">
+                                <thead>
+                                    <tr>
+                                        <th>IMEI (15-digit)</th>
+                                        <th>Source</th>
+                                        <th>Context</th>
+                                    </tr>
+                                </thead>
+                                <tbody>
+                                    {report.unexpected_in_pdf.map((u) => (
+                                        <tr key={u.imei}>
+                                            <td className="font-mono font-medium text-text-primary">{u.imei}</td>
+                                            <td className="text-text-secondary">{`${u.source_file || 'PDF'}, p. ${u.source_page}, line ${u.line}`}</td>
+                                            <td className="font-mono text-xs text-text-muted">{u.context}</td>
+                                        </tr>
+                                    ))}
+                                </tbody>
+                            </table>
+                        </div>
+                    )}
+
                     {report.text_report && (
                         <div className="mt-8 card overflow-hidden">
                             <div className="px-6 py-4 border-b border-border">